@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/mongostore"
+	"mnezerka/geonet/s2store"
+	"mnezerka/geonet/sqlitestore"
+	"mnezerka/geonet/store"
+
+	"github.com/spf13/cobra"
+)
+
+// stores keeping the net
+const BACKEND_S2 = "s2"
+const BACKEND_MONGO = "mongo"
+const BACKEND_SQLITE = "sqlite"
+
+var flagBackend string
+var flagDbPath string
+
+func addBackendFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&flagBackend, "backend", BACKEND_S2, "store keeping the net (s2 = in memory, mongo = mongodb given by MONGODB_URI, sqlite = geopackage file)")
+	cmd.PersistentFlags().StringVar(&flagDbPath, "db", "geonet.gpkg", "path to geopackage file of sqlite backend")
+}
+
+func newStore() (store.Store, error) {
+	if err := checkBackendOptions(&config.Cfg); err != nil {
+		return nil, err
+	}
+
+	switch flagBackend {
+	case BACKEND_S2:
+		return s2store.NewS2Store(&config.Cfg), nil
+	case BACKEND_MONGO:
+		return mongostore.NewMongoStore(&config.Cfg), nil
+	case BACKEND_SQLITE:
+		return sqlitestore.NewSqliteStore(&config.Cfg, flagDbPath)
+	}
+	return nil, fmt.Errorf("unknown backend: %s", flagBackend)
+}
+
+// some operations need the whole net in memory
+func requireS2Store(st store.Store, operation string) (*s2store.S2Store, error) {
+	s, ok := st.(*s2store.S2Store)
+	if !ok {
+		return nil, fmt.Errorf("%s is supported only by %s backend", operation, BACKEND_S2)
+	}
+	return s, nil
+}
+
+// options of matching, simplification and smoothing implemented only by s2
+// backend, other backends would silently ignore them
+func checkBackendOptions(cfg *config.Configuration) error {
+	if flagBackend == BACKEND_S2 {
+		return nil
+	}
+
+	var option string
+	switch {
+	case cfg.MatchEdges:
+		option = "--match-edges"
+	case cfg.MatchMaxAngle > 0:
+		option = "--match-max-angle"
+	case cfg.SimplifyAlgorithm != s2store.SIMPLIFY_DOUGLAS_PEUCKER && cfg.SimplifyAlgorithm != "":
+		option = "--sim-algorithm " + cfg.SimplifyAlgorithm
+	case cfg.SimplifyPreserveTopology:
+		option = "--sim-preserve-topology"
+	case cfg.Smooth:
+		option = "--smooth"
+	default:
+		return nil
+	}
+
+	return fmt.Errorf("%s is supported only by %s backend", option, BACKEND_S2)
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/log"
+	"mnezerka/geonet/s2store"
+	"mnezerka/geonet/store"
+
+	"github.com/spf13/cobra"
+)
+
+var cmdDiffExportFormat string
+var cmdDiffUnchanged bool
+var cmdDiffMovedDist float64
+
+var cmdDiff = &cobra.Command{
+	Use:   "diff [flags] old.geonet new.geonet",
+	Short: "Compare two versions of geo network",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		oldNet := s2store.NewS2Store(&config.Cfg)
+		err := loadStore(oldNet, args[0])
+		if err != nil {
+			return err
+		}
+
+		newNet := s2store.NewS2Store(&config.Cfg)
+		err = loadStore(newNet, args[1])
+		if err != nil {
+			return err
+		}
+
+		diff := s2store.Compare(oldNet, newNet, cmdDiffMovedDist)
+
+		log.Infof("differences:")
+		diff.Print()
+
+		collection := diff.ToGeoJson(config.Cfg.ShowPoints, cmdDiffUnchanged)
+
+		switch cmdDiffExportFormat {
+		case "geojson":
+			bytesJson, err := json.MarshalIndent(collection, "", " ")
+			if err != nil {
+				return err
+			}
+			fmt.Print(string(bytesJson))
+		case "html":
+			renderHtml(collection, diffMeta(oldNet, newNet))
+		default:
+			return fmt.Errorf("unknown export format: %s", cmdDiffExportFormat)
+		}
+
+		return nil
+	},
+}
+
+// tracks of both nets (removed tracks are known only in the old one)
+func diffMeta(oldNet, newNet store.Store) store.Meta {
+	meta := newNet.GetMeta()
+
+	known := map[int64]bool{}
+	for _, t := range meta.Tracks {
+		known[t.Id] = true
+	}
+	for _, t := range oldNet.GetMeta().Tracks {
+		if !known[t.Id] {
+			meta.Tracks = append(meta.Tracks, t)
+		}
+	}
+
+	return meta
+}
+
+func init() {
+	cmdDiff.PersistentFlags().StringVar(&cmdDiffExportFormat, "export-format", "geojson", "export format (geojson, html)")
+	cmdDiff.PersistentFlags().BoolVar(&config.Cfg.ShowPoints, "points", config.Cfg.ShowPoints, "include changes of points in exported content")
+	cmdDiff.PersistentFlags().BoolVar(&cmdDiffUnchanged, "unchanged", true, "include unchanged edges and points in exported content")
+	cmdDiff.PersistentFlags().Float64Var(&cmdDiffMovedDist, "moved-dist", 1, "minimal distance in meters of moved point to be reported as changed")
+
+	rootCmd.AddCommand(cmdDiff)
+}
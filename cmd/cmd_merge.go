@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/log"
+	"mnezerka/geonet/s2store"
+
+	"github.com/spf13/cobra"
+)
+
+var cmdMergeSavePath string
+var cmdMergeFormat string
+var cmdMergeCompression string
+
+var cmdMerge = &cobra.Command{
+	Use:   "merge [flags] first.geonet second.geonet [more.geonet]",
+	Short: "Merge saved geo networks into one network",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		store := s2store.NewS2Store(&config.Cfg)
+
+		err := loadStore(store, args[0])
+		if err != nil {
+			return err
+		}
+
+		for _, path := range args[1:] {
+			other := s2store.NewS2Store(&config.Cfg)
+			err = loadStore(other, path)
+			if err != nil {
+				return err
+			}
+
+			store.Merge(other)
+		}
+
+		err = saveStore(store, cmdMergeSavePath, cmdMergeFormat, cmdMergeCompression)
+		if err != nil {
+			return err
+		}
+
+		log.Infof("statistics:")
+		store.GetStat().Print()
+
+		return nil
+	},
+}
+
+func init() {
+	cmdMerge.PersistentFlags().Int64Var(&config.Cfg.MatchMaxDistance, "match-max-dist", config.Cfg.MatchMaxDistance, "maximal distance in meters for matching points of merged networks")
+	cmdMerge.PersistentFlags().StringVar(&cmdMergeSavePath, "save", STDIO_PATH, "save merged geonet to file (- for stdout)")
+	cmdMerge.PersistentFlags().StringVar(&cmdMergeFormat, "format", "", "format of saved geonet (json, binary), default is given by file extension (.gnb = binary)")
+	cmdMerge.PersistentFlags().StringVar(&cmdMergeCompression, "compress", "", "compression of saved geonet (none, gzip, zstd), default is given by file extension (.gz, .zst)")
+
+	rootCmd.AddCommand(cmdMerge)
+}
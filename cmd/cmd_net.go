@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"fmt"
 	"mnezerka/geonet/config"
 	"mnezerka/geonet/log"
 	"mnezerka/geonet/s2store"
 	"mnezerka/geonet/tracks"
+	"slices"
+	"strconv"
 
 	"github.com/spf13/cobra"
 )
@@ -12,20 +15,75 @@ import (
 var cmdGenInterpolate bool
 var cmdGenLimit int
 var cmdGenLoadPath string
+var cmdGenRemoveTracks []int64
+var cmdGenReplaceTracks map[string]string
+var cmdGenComponents bool
 
 var cmdNet = &cobra.Command{
 	Use:   "net [flags] [gpx files]",
 	Short: "Geographic network toolset",
 	RunE: func(cmd *cobra.Command, args []string) error {
 
-		store := s2store.NewS2Store(&config.Cfg)
+		store, err := newStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
 
 		if len(cmdGenLoadPath) > 0 {
-			log.Infof("loading geonet from %s", cmdGenLoadPath)
-			store.Load(cmdGenLoadPath)
+			err := loadStore(store, cmdGenLoadPath)
+			if err != nil {
+				return err
+			}
 			log.Infof("loaded")
 		}
 
+		for _, id := range cmdGenRemoveTracks {
+			log.Infof("removing track %d", id)
+			s, err := requireS2Store(store, "removing of tracks")
+			if err != nil {
+				return err
+			}
+			err = s.RemoveTrack(id)
+			if err != nil {
+				return err
+			}
+		}
+
+		// tracks are replaced in order of ids, so the result doesn't depend
+		// on order of map iteration
+		replaceIds := make([]int64, 0, len(cmdGenReplaceTracks))
+		replaceFiles := make(map[int64]string, len(cmdGenReplaceTracks))
+		for idStr, filePath := range cmdGenReplaceTracks {
+			id, err := strconv.ParseInt(idStr, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid track id: %s", idStr)
+			}
+			replaceIds = append(replaceIds, id)
+			replaceFiles[id] = filePath
+		}
+		slices.Sort(replaceIds)
+
+		for _, id := range replaceIds {
+			filePath := replaceFiles[id]
+
+			t := tracks.NewTrack(filePath)
+			log.Infof("replacing track %d by %s (%d points)", id, filePath, len(t.Points))
+
+			if cmdGenInterpolate {
+				t.InterpolateDistance(config.Cfg.InterpolationDistance)
+			}
+
+			s, err := requireS2Store(store, "replacing of tracks")
+			if err != nil {
+				return err
+			}
+			err = s.ReplaceTrack(id, t)
+			if err != nil {
+				return err
+			}
+		}
+
 		if len(args) > 0 {
 
 			log.Infof("generating geonet from %d input files", len(args))
@@ -54,10 +112,22 @@ var cmdNet = &cobra.Command{
 			}
 		}
 
-		processing(store)
+		err = processing(store)
+		if err != nil {
+			return err
+		}
 
 		export(store)
 
+		if cmdGenComponents {
+			s, err := requireS2Store(store, "listing of components")
+			if err != nil {
+				return err
+			}
+			log.Infof("connected components:")
+			s2store.PrintComponents(s.Components())
+		}
+
 		log.Infof("statistics:")
 		store.GetStat().Print()
 
@@ -69,9 +139,17 @@ func init() {
 	cmdNet.PersistentFlags().BoolVarP(&cmdGenInterpolate, "interpolate", "i", false, "interpolate tracks before adding to geonet")
 	cmdNet.PersistentFlags().Int64Var(&config.Cfg.InterpolationDistance, "int-dist", config.Cfg.InterpolationDistance, "distance for interpolation in meters")
 	cmdNet.PersistentFlags().Int64Var(&config.Cfg.MatchMaxDistance, "match-max-dist", config.Cfg.MatchMaxDistance, "maximal distance in meters for matching new points against points in geonet")
+	cmdNet.PersistentFlags().BoolVar(&config.Cfg.MatchEdges, "match-edges", config.Cfg.MatchEdges, "project points to nearest edge (and split it) if there is no point to be reused")
+	cmdNet.PersistentFlags().Float64Var(&config.Cfg.MatchMaxAngle, "match-max-angle", config.Cfg.MatchMaxAngle, "maximal angle in degrees between track and edges of matched point (0 = heading not checked)")
 	cmdNet.PersistentFlags().IntVar(&cmdGenLimit, "limit", -1, "max number of tracks to be processed")
 
-	cmdNet.PersistentFlags().StringVar(&cmdGenLoadPath, "load", "", "load geo network from file before processing")
+	cmdNet.PersistentFlags().StringVar(&cmdGenLoadPath, "load", "", "load geo network from file before processing (- for stdin)")
+	cmdNet.PersistentFlags().Int64SliceVar(&cmdGenRemoveTracks, "remove-track", nil, "remove track (by id) from loaded geo network")
+	cmdNet.PersistentFlags().StringToStringVar(&cmdGenReplaceTracks, "replace-track", nil, "replace track in loaded geo network by content of gpx file (id=file.gpx)")
+
+	cmdNet.PersistentFlags().BoolVar(&cmdGenComponents, "components", false, "list connected components of geo network")
+
+	addBackendFlags(cmdNet)
 
 	addProcessingFlags(cmdNet)
 
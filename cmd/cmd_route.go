@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/log"
+	"mnezerka/geonet/s2store"
+	"strconv"
+	"strings"
+
+	"github.com/golang/geo/s2"
+	"github.com/spf13/cobra"
+	"github.com/tkrajina/gpxgo/gpx"
+)
+
+var cmdRouteLoadPath string
+var cmdRouteFrom string
+var cmdRouteTo string
+var cmdRouteExportFormat string
+var cmdRouteProfilesPath string
+var cmdRouteProfile string
+
+var cmdRoute = &cobra.Command{
+	Use:   "route",
+	Short: "Find the shortest route in geo network",
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		from, err := parseLatLng(cmdRouteFrom)
+		if err != nil {
+			return fmt.Errorf("invalid start position: %w", err)
+		}
+
+		to, err := parseLatLng(cmdRouteTo)
+		if err != nil {
+			return fmt.Errorf("invalid end position: %w", err)
+		}
+
+		var profile *config.RouteProfile
+		if cmdRouteProfile != "" {
+			profiles, err := config.LoadRouteProfiles(cmdRouteProfilesPath)
+			if err != nil {
+				return err
+			}
+			p, ok := profiles[cmdRouteProfile]
+			if !ok {
+				return fmt.Errorf("route profile %s not found in %s", cmdRouteProfile, cmdRouteProfilesPath)
+			}
+			profile = &p
+			log.Infof("using route profile %s", cmdRouteProfile)
+		}
+
+		store := s2store.NewS2Store(&config.Cfg)
+
+		err = loadStore(store, cmdRouteLoadPath)
+		if err != nil {
+			return err
+		}
+
+		route, err := store.ShortestPathWithProfile(from, to, profile)
+		if err != nil {
+			return err
+		}
+
+		log.Infof("route found: %.2f km, %d points", route.LengthMeters/1000, len(route.Coordinates))
+
+		switch cmdRouteExportFormat {
+		case "geojson":
+			bytesJson, err := json.MarshalIndent(route.ToGeoJson(), "", " ")
+			if err != nil {
+				return err
+			}
+			fmt.Print(string(bytesJson))
+		case "gpx":
+			xmlBytes, err := route.ToGpx().ToXml(gpx.ToXmlParams{Version: "1.1", Indent: true})
+			if err != nil {
+				return err
+			}
+			fmt.Print(string(xmlBytes))
+		default:
+			return fmt.Errorf("unknown export format: %s", cmdRouteExportFormat)
+		}
+
+		return nil
+	},
+}
+
+// parse position in format lat,lng
+func parseLatLng(value string) (s2.LatLng, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 {
+		return s2.LatLng{}, fmt.Errorf("expected lat,lng, got '%s'", value)
+	}
+
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return s2.LatLng{}, err
+	}
+
+	lng, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return s2.LatLng{}, err
+	}
+
+	return s2.LatLngFromDegrees(lat, lng), nil
+}
+
+func init() {
+	cmdRoute.PersistentFlags().StringVar(&cmdRouteLoadPath, "load", "", "load geo network from file (- for stdin)")
+	cmdRoute.PersistentFlags().StringVar(&cmdRouteFrom, "from", "", "start position (lat,lng)")
+	cmdRoute.PersistentFlags().StringVar(&cmdRouteTo, "to", "", "end position (lat,lng)")
+	cmdRoute.PersistentFlags().StringVar(&cmdRouteExportFormat, "export-format", "geojson", "export format (geojson, gpx)")
+	cmdRoute.PersistentFlags().StringVar(&cmdRouteProfilesPath, "profiles", "profiles.json", "file with route profiles")
+	cmdRoute.PersistentFlags().StringVar(&cmdRouteProfile, "profile", "", "name of route profile used for edge costs (default is the shortest route)")
+	cmdRoute.PersistentFlags().Int64Var(&config.Cfg.RouteSnapDistance, "snap-dist", config.Cfg.RouteSnapDistance, "maximal distance in meters of start and end positions from the net")
+
+	cmdRoute.MarkPersistentFlagRequired("load")
+	cmdRoute.MarkPersistentFlagRequired("from")
+	cmdRoute.MarkPersistentFlagRequired("to")
+
+	rootCmd.AddCommand(cmdRoute)
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/log"
+	"mnezerka/geonet/tiles"
+	"os"
+	"path/filepath"
+
+	"github.com/paulmach/orb/maptile"
+	"github.com/spf13/cobra"
+)
+
+var cmdTilesLoadPath string
+var cmdTilesOut string
+var cmdTilesMinZoom int
+var cmdTilesMaxZoom int
+
+var cmdTiles = &cobra.Command{
+	Use:   "tiles",
+	Short: "Cut geo network into vector tiles (directory of tiles or pmtiles archive)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		store, err := newStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		if len(cmdTilesLoadPath) > 0 {
+			err := loadStore(store, cmdTilesLoadPath)
+			if err != nil {
+				return err
+			}
+		}
+
+		collection := store.ToGeoJson(nil)
+
+		log.Infof("generating tiles for zoom levels %d-%d from %d features", cmdTilesMinZoom, cmdTilesMaxZoom, len(collection.Features))
+
+		count := 0
+		counted := func(write tiles.TileFunc) tiles.TileFunc {
+			return func(tile maptile.Tile, data []byte) error {
+				count++
+				return write(tile, data)
+			}
+		}
+
+		// html map (--tiles-url) loads only directory of tiles, archive is
+		// for pmtiles aware viewers and servers
+		if filepath.Ext(cmdTilesOut) == ".pmtiles" {
+			archive := tiles.NewPMTiles()
+			err = tiles.Generate(collection, cmdTilesMinZoom, cmdTilesMaxZoom, counted(archive.AddTile))
+			if err != nil {
+				return err
+			}
+
+			f, err := os.Create(cmdTilesOut)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			err = archive.Write(f, tiles.Bound(collection))
+			if err != nil {
+				return err
+			}
+		} else {
+			err = tiles.Generate(collection, cmdTilesMinZoom, cmdTilesMaxZoom, counted(tiles.DirWriter(cmdTilesOut)))
+			if err != nil {
+				return err
+			}
+		}
+
+		log.Infof("%d tiles written to %s", count, cmdTilesOut)
+
+		return nil
+	},
+}
+
+func init() {
+	cmdTiles.PersistentFlags().StringVar(&cmdTilesLoadPath, "load", "", "load geo network from file (- for stdin)")
+	cmdTiles.PersistentFlags().StringVar(&cmdTilesOut, "out", "tiles", "output directory (z/x/y.pbf) or file with .pmtiles extension (pmtiles archive can't be loaded by html map, see --tiles-url)")
+	cmdTiles.PersistentFlags().IntVar(&cmdTilesMinZoom, "min-zoom", 8, "minimal zoom level of generated tiles")
+	cmdTiles.PersistentFlags().IntVar(&cmdTilesMaxZoom, "max-zoom", 14, "maximal zoom level of generated tiles")
+	cmdTiles.PersistentFlags().BoolVar(&config.Cfg.ShowPoints, "points", config.Cfg.ShowPoints, "include points in generated tiles")
+
+	addBackendFlags(cmdTiles)
+
+	addExportSmoothFlags(cmdTiles)
+
+	rootCmd.AddCommand(cmdTiles)
+}
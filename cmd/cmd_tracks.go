@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"mnezerka/geonet/config"
 	"mnezerka/geonet/tracks"
+	"os"
 
 	"github.com/spf13/cobra"
 )
@@ -33,6 +34,15 @@ var cmdTracks = &cobra.Command{
 			case "svg":
 				fmt.Print(set.ExportSvg())
 				break
+			case "png":
+				os.Stdout.Write(set.ExportPng())
+				break
+			case "kml":
+				os.Stdout.Write(set.ExportKml())
+				break
+			case "kmz":
+				os.Stdout.Write(set.ExportKmz())
+				break
 			default:
 				return fmt.Errorf("unknown export format: %s", cmdTracksExportFormat)
 			}
@@ -45,10 +55,11 @@ var cmdTracks = &cobra.Command{
 func init() {
 	// export
 	cmdTracks.PersistentFlags().BoolVarP(&cmdTracksExport, "export", "e", false, "export tracks")
-	cmdTracks.PersistentFlags().StringVar(&cmdTracksExportFormat, "export-format", "json", "export format (json, geojson, svg)")
+	cmdTracks.PersistentFlags().StringVar(&cmdTracksExportFormat, "export-format", "json", "export format (json, geojson, svg, png, kml, kmz)")
 	cmdTracks.PersistentFlags().BoolVar(&config.Cfg.ShowPoints, "points", config.Cfg.ShowPoints, "render individual points")
 
 	addExportSvgFlags(cmdTracks)
+	addExportPngFlags(cmdTracks)
 
 	// interpolate
 	cmdTracks.PersistentFlags().BoolVarP(&cmdTracksInterpolate, "interpolate", "i", false, "interpolate tracks")
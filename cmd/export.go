@@ -6,11 +6,12 @@ import (
 	"fmt"
 	"mnezerka/geonet/config"
 	"mnezerka/geonet/log"
-	"mnezerka/geonet/s2store"
 	"mnezerka/geonet/store"
+	"mnezerka/geonet/tiles"
 	"os"
 	"text/template"
 
+	geojson "github.com/paulmach/go.geojson"
 	"github.com/spf13/cobra"
 )
 
@@ -19,11 +20,17 @@ type mapData struct {
 	Meta           string
 	GeoJson        string
 	UseTrackColors bool
+	TilesUrl       string
+	TilesMaxZoom   int
+	Bounds         string
+	LineStyle      string
 	Js             string
 }
 
 var flagExport bool
 var flagExportFormat string
+var flagExportTilesUrl string
+var flagExportTilesMaxZoom int
 
 var templatesContent *embed.FS
 
@@ -31,10 +38,21 @@ func addExportFlags(cmd *cobra.Command) {
 
 	// export
 	cmd.PersistentFlags().BoolVarP(&flagExport, "export", "e", false, "export content to various formats")
-	cmd.PersistentFlags().StringVar(&flagExportFormat, "export-format", "json", "export format (json, geojson, svg, txt, html, metadata)")
+	cmd.PersistentFlags().StringVar(&flagExportFormat, "export-format", "json", "export format (json, geojson, svg, png, kml, kmz, gpx, txt, html, metadata)")
 	cmd.PersistentFlags().BoolVar(&config.Cfg.ShowPoints, "points", config.Cfg.ShowPoints, "include points in exported content ")
 	cmd.PersistentFlags().BoolVar(&config.Cfg.ShowEdges, "edges", config.Cfg.ShowEdges, "include edges in exported content")
+	cmd.PersistentFlags().StringVar(&config.Cfg.Style, "style", config.Cfg.Style, "color and width of lines in svg, png and html by data (popularity, usage, recency, track:ID or path to json style)")
+	cmd.PersistentFlags().StringVar(&flagExportTilesUrl, "tiles-url", "", "html map loads vector tiles from url (e.g. tiles/{z}/{x}/{y}.pbf) instead of inline geojson")
+	cmd.PersistentFlags().IntVar(&flagExportTilesMaxZoom, "tiles-max-zoom", 14, "maximal zoom level of vector tiles loaded by html map")
+	addExportSmoothFlags(cmd)
 	addExportSvgFlags(cmd)
+	addExportPngFlags(cmd)
+}
+
+func addExportSmoothFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&config.Cfg.Smooth, "smooth", config.Cfg.Smooth, "smooth geometry of exported segments (crossings, begin and end points are kept)")
+	cmd.PersistentFlags().StringVar(&config.Cfg.SmoothAlgorithm, "smooth-algorithm", config.Cfg.SmoothAlgorithm, "smoothing algorithm (chaikin, catmull-rom)")
+	cmd.PersistentFlags().IntVar(&config.Cfg.SmoothStrength, "smooth-strength", config.Cfg.SmoothStrength, "strength of smoothing (number of chaikin iterations, spline pieces per line - 1)")
 }
 
 func addExportSvgFlags(cmd *cobra.Command) {
@@ -43,31 +61,58 @@ func addExportSvgFlags(cmd *cobra.Command) {
 	cmd.PersistentFlags().IntVar(&config.Cfg.SvgPadding, "svg-padding", config.Cfg.SvgPadding, "padding of generated svg in pixels")
 	cmd.PersistentFlags().BoolVar(&config.Cfg.SvgPointLabels, "svg-point-labels", config.Cfg.SvgPointLabels, "add label (e.g. id) to each point")
 	cmd.PersistentFlags().BoolVar(&config.Cfg.SvgEdgeLabels, "svg-edge-labels", config.Cfg.SvgEdgeLabels, "add label (e.g. tracks) to each edge")
+	cmd.PersistentFlags().StringVar(&config.Cfg.SvgProjection, "svg-projection", config.Cfg.SvgProjection, "projection of generated svg and png (none, mercator, equirectangular)")
+	cmd.PersistentFlags().BoolVar(&config.Cfg.SvgScaleBar, "svg-scale-bar", config.Cfg.SvgScaleBar, "add metric scale bar to generated svg")
+	cmd.PersistentFlags().BoolVar(&config.Cfg.SvgNorthArrow, "svg-north-arrow", config.Cfg.SvgNorthArrow, "add north arrow to generated svg")
+}
+
+func addExportPngFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().IntVar(&config.Cfg.PngWidth, "png-width", config.Cfg.PngWidth, "width of generated png in pixels (at 96 dpi)")
+	cmd.PersistentFlags().IntVar(&config.Cfg.PngHeight, "png-height", config.Cfg.PngHeight, "height of generated png in pixels (at 96 dpi)")
+	cmd.PersistentFlags().IntVar(&config.Cfg.PngPadding, "png-padding", config.Cfg.PngPadding, "padding of generated png in pixels (at 96 dpi)")
+	cmd.PersistentFlags().IntVar(&config.Cfg.PngDpi, "png-dpi", config.Cfg.PngDpi, "resolution of generated png (image is scaled by dpi / 96)")
+	cmd.PersistentFlags().Float64Var(&config.Cfg.PngLineWidth, "png-line-width", config.Cfg.PngLineWidth, "width of lines in generated png in pixels (at 96 dpi)")
 }
 
-func export(s2store *s2store.S2Store) {
+func export(st store.Store) {
 
 	if flagExport {
 		log.Infof("exporting network")
 
 		switch flagExportFormat {
 		case "svg":
-			fmt.Print(store.ExportSvg(s2store))
+			fmt.Print(store.ExportSvg(st))
 			break
 		case "geojson":
-			fmt.Print(string(store.ExportGeoJson(s2store)))
+			fmt.Print(string(store.ExportGeoJson(st)))
+			break
+		case "png":
+			os.Stdout.Write(store.ExportPng(st))
+			break
+		case "kml":
+			os.Stdout.Write(store.ExportKml(st))
+			break
+		case "kmz":
+			os.Stdout.Write(store.ExportKmz(st))
+			break
+		case "gpx":
+			s, err := requireS2Store(st, "gpx export")
+			if err != nil {
+				log.ExitWithError(err)
+			}
+			fmt.Print(string(s.ExportGpx()))
 			break
 		case "metadata":
-			fmt.Print(string(store.ExportMetadata(s2store)))
+			fmt.Print(string(store.ExportMetadata(st)))
 			break
 		case "txt":
-			fmt.Print(s2store.ToTxt())
+			fmt.Print(st.ToTxt())
 			break
 		case "html":
-			render(s2store)
+			render(st)
 			break
 		default:
-			fmt.Print(string(store.Export(s2store)))
+			fmt.Print(string(store.Export(st)))
 		}
 	}
 }
@@ -76,12 +121,14 @@ func SetTemplatesContent(content *embed.FS) {
 	templatesContent = content
 }
 
-func render(store *s2store.S2Store) {
+func render(store store.Store) {
 	log.Debug("------------ rendering geonet to html --------------")
 
-	collection := store.ToGeoJson(nil)
+	renderHtml(store.ToGeoJson(nil), store.GetMeta())
+}
 
-	meta := store.GetMeta()
+// render html page with map of features
+func renderHtml(collection *geojson.FeatureCollection, meta store.Meta) {
 
 	// meta - json
 	metaJson, err := json.Marshal(meta)
@@ -89,12 +136,29 @@ func render(store *s2store.S2Store) {
 		log.ExitWithError(err)
 	}
 
-	// collection -> json
-	rawJSON, err := collection.MarshalJSON()
+	// collection -> json, only bounds are needed if features are loaded
+	// from vector tiles
+	rawJSON := []byte("null")
+	boundsJson := []byte("null")
+	if len(flagExportTilesUrl) > 0 {
+		bound := tiles.Bound(collection)
+		boundsJson, err = json.Marshal([][]float64{{bound.Min[1], bound.Min[0]}, {bound.Max[1], bound.Max[0]}})
+	} else {
+		rawJSON, err = collection.MarshalJSON()
+	}
 	if err != nil {
 		log.ExitWithError(err)
 	}
 
+	// style of lines driven by data - json
+	lineStyleJson := []byte("null")
+	if lineStyle := store.LineStyle(); lineStyle != nil {
+		lineStyleJson, err = json.Marshal(lineStyle)
+		if err != nil {
+			log.ExitWithError(err)
+		}
+	}
+
 	// Load the template file
 	htmlContent, err := template.ParseFS(templatesContent, "templates/map.html")
 	if err != nil {
@@ -112,6 +176,10 @@ func render(store *s2store.S2Store) {
 		Meta:           string(metaJson),
 		GeoJson:        string(rawJSON),
 		UseTrackColors: config.Cfg.ShowTrackColors,
+		TilesUrl:       flagExportTilesUrl,
+		TilesMaxZoom:   flagExportTilesMaxZoom,
+		Bounds:         string(boundsJson),
+		LineStyle:      string(lineStyleJson),
 		Js:             string(jsContent),
 	}
 
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"mnezerka/geonet/log"
+	"mnezerka/geonet/s2store"
+	"mnezerka/geonet/store"
+	"os"
+)
+
+// path used for standard input or output
+const STDIO_PATH = "-"
+
+// open file for reading, "-" means standard input
+func openInput(path string) (io.ReadCloser, error) {
+	if path == STDIO_PATH {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// create file for writing, "-" means standard output
+func createOutput(path string) (io.WriteCloser, error) {
+	if path == STDIO_PATH {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func loadStore(st store.Store, path string) error {
+
+	log.Infof("loading geonet from %s", path)
+
+	r, err := openInput(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	// other stores read only plain json
+	if s, ok := st.(*s2store.S2Store); ok {
+		return s.LoadDetect(r)
+	}
+
+	return st.Load(r)
+}
+
+// save net, format and compression are given by file extension if not
+// set explicitly
+func saveStore(st store.Store, path, format, compression string) error {
+
+	pathFormat, pathCompression := s2store.FormatFromPath(path)
+	if len(format) == 0 {
+		format = pathFormat
+	}
+	if len(compression) == 0 {
+		compression = pathCompression
+	}
+	if compression == "none" {
+		compression = s2store.COMPRESSION_NONE
+	}
+
+	log.Infof("saving geonet to %s (format: %s, compression: %s)", path, format, compression)
+
+	s, ok := st.(*s2store.S2Store)
+	if !ok && (format != s2store.FORMAT_JSON || compression != s2store.COMPRESSION_NONE) {
+		return fmt.Errorf("%s backend saves only uncompressed json", flagBackend)
+	}
+
+	w, err := createOutput(path)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		err = s.SaveFormat(w, format, compression)
+	} else {
+		err = st.Save(w)
+	}
+	if err != nil {
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
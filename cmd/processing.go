@@ -3,32 +3,119 @@ package cmd
 import (
 	"mnezerka/geonet/config"
 	"mnezerka/geonet/log"
-	"mnezerka/geonet/s2store"
+	"mnezerka/geonet/store"
 
 	"github.com/spf13/cobra"
 )
 
+var processingPruneIslands float64
+var processingRefine bool
+var processingRemoveSpurs bool
+var processingCollapseJunctions bool
 var processingSimplify bool
-var processingSave bool
+var processingSmooth bool
+var processingSavePath string
+var processingSaveFormat string
+var processingSaveCompression string
 
 func addProcessingFlags(cmd *cobra.Command) {
 
+	// islands
+	cmd.PersistentFlags().Float64Var(&processingPruneIslands, "prune-islands-shorter-than", 0, "delete disconnected parts of geonet shorter than given length in meters")
+
+	// refinement
+	cmd.PersistentFlags().BoolVar(&processingRefine, "refine", false, "move points to centroids of matched gps fixes and merge points that get close")
+
+	// junctions
+	cmd.PersistentFlags().BoolVar(&processingCollapseJunctions, "collapse-junctions", false, "merge clusters of nearby crossings into single junction")
+	cmd.PersistentFlags().Int64Var(&config.Cfg.JunctionRadius, "junction-radius", config.Cfg.JunctionRadius, "radius in meters of crossing clusters to be collapsed")
+
+	// spurs
+	cmd.PersistentFlags().BoolVar(&processingRemoveSpurs, "remove-spurs", false, "remove short dead-end spurs (e.g. gps fixes wandering off the road)")
+	cmd.PersistentFlags().Int64Var(&config.Cfg.SpurMaxLength, "spur-max-len", config.Cfg.SpurMaxLength, "maximal length of spur in meters to be removed")
+
 	// simplification
 	cmd.PersistentFlags().BoolVar(&processingSimplify, "simplify", false, "simplify geonet")
 	cmd.PersistentFlags().Int64Var(&config.Cfg.SimplifyMinDistance, "sim-min-dist", config.Cfg.MatchMaxDistance, "minimal distance between points for simplification")
+	cmd.PersistentFlags().StringVar(&config.Cfg.SimplifyAlgorithm, "sim-algorithm", config.Cfg.SimplifyAlgorithm, "simplification algorithm (douglas-peucker, visvalingam-whyatt)")
+	cmd.PersistentFlags().BoolVar(&config.Cfg.SimplifyPreserveTopology, "sim-preserve-topology", config.Cfg.SimplifyPreserveTopology, "keep points needed to avoid crossing or touching of simplified segments")
+
+	// smoothing
+	cmd.PersistentFlags().BoolVar(&processingSmooth, "smooth-net", false, "replace geometry of segments by smoothed lines (see --smooth-algorithm, --smooth-strength)")
 
 	// save
-	cmd.PersistentFlags().BoolVar(&processingSave, "save", false, "save geonet (json format)")
+	cmd.PersistentFlags().StringVar(&processingSavePath, "save", "", "save geonet to file (- for stdout)")
+	cmd.PersistentFlags().StringVar(&processingSaveFormat, "format", "", "format of saved geonet (json, binary), default is given by file extension (.gnb = binary)")
+	cmd.PersistentFlags().StringVar(&processingSaveCompression, "compress", "", "compression of saved geonet (none, gzip, zstd), default is given by file extension (.gz, .zst)")
 }
 
-func processing(s2store *s2store.S2Store) {
+func processing(st store.Store) error {
+
+	if processingPruneIslands > 0 {
+		s, err := requireS2Store(st, "pruning islands")
+		if err != nil {
+			return err
+		}
+		log.Infof("pruning islands shorter than %.0fm", processingPruneIslands)
+		pruned := s.PruneIslands(processingPruneIslands)
+		log.Infof("pruned %d islands", pruned)
+	}
+
+	if processingRefine {
+		s, err := requireS2Store(st, "refinement")
+		if err != nil {
+			return err
+		}
+		log.Infof("refining network")
+		s.Refine()
+	}
+
+	if processingCollapseJunctions {
+		s, err := requireS2Store(st, "collapsing junctions")
+		if err != nil {
+			return err
+		}
+		log.Infof("collapsing junctions within %dm", config.Cfg.JunctionRadius)
+		collapsed := s.CollapseJunctions(float64(config.Cfg.JunctionRadius))
+		log.Infof("collapsed %d junctions", collapsed)
+	}
+
+	if processingRemoveSpurs {
+		s, err := requireS2Store(st, "removing spurs")
+		if err != nil {
+			return err
+		}
+		log.Infof("removing spurs shorter than %dm", config.Cfg.SpurMaxLength)
+		removed := s.RemoveSpurs(float64(config.Cfg.SpurMaxLength))
+		log.Infof("removed %d spurs", removed)
+	}
 
 	if processingSimplify {
 		log.Infof("simplifying network")
-		s2store.Simplify()
+		err := st.Simplify()
+		if err != nil {
+			return err
+		}
+	}
+
+	if processingSmooth {
+		s, err := requireS2Store(st, "smoothing of net")
+		if err != nil {
+			return err
+		}
+		log.Infof("smoothing network")
+		err = s.Smooth()
+		if err != nil {
+			return err
+		}
 	}
 
-	if processingSave {
-		s2store.Save()
+	if len(processingSavePath) > 0 {
+		err := saveStore(st, processingSavePath, processingSaveFormat, processingSaveCompression)
+		if err != nil {
+			return err
+		}
 	}
+
+	return nil
 }
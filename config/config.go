@@ -5,18 +5,37 @@ import (
 )
 
 type Configuration struct {
-	SimplifyMinDistance   int64 // in meters
-	MatchMaxDistance      int64 // in meters
-	InterpolationDistance int64 // in meters
-	ShowPoints            bool  // render points to map
-	ShowEdges             bool  // render edges to map
-	ShowTrackColors       bool  // render tracks with different colors
-	GeoJsonMergeEdges     bool  // export edge segments ans continuous line instead of individual lines
-	SvgWidth              int
-	SvgHeight             int
-	SvgPadding            int
-	SvgPointLabels        bool
-	SvgEdgeLabels         bool
+	SimplifyMinDistance      int64   // in meters
+	SimplifyAlgorithm        string  // douglas-peucker or visvalingam-whyatt
+	SimplifyPreserveTopology bool    // simplified segments must not cross or touch other segments
+	MatchMaxDistance         int64   // in meters
+	SpurMaxLength            int64   // in meters, max length of dead-end spur to be removed
+	JunctionRadius           int64   // in meters, radius of crossing clusters collapsed into single junction
+	MatchEdges               bool    // project points to nearest edge if there is no point to be reused
+	MatchMaxAngle            float64 // in degrees, 0 means heading is not checked
+	InterpolationDistance    int64   // in meters
+	RouteSnapDistance        int64   // in meters, max distance of route start and end from the net
+	ShowPoints               bool    // render points to map
+	ShowEdges                bool    // render edges to map
+	ShowTrackColors          bool    // render tracks with different colors
+	GeoJsonMergeEdges        bool    // export edge segments ans continuous line instead of individual lines
+	Smooth                   bool    // smooth geometry of exported segments
+	SmoothAlgorithm          string  // chaikin or catmull-rom
+	SmoothStrength           int     // number of chaikin iterations or spline pieces per line - 1
+	SvgWidth                 int
+	SvgHeight                int
+	SvgPadding               int
+	SvgPointLabels           bool
+	SvgEdgeLabels            bool
+	SvgProjection            string // none, mercator or equirectangular (used by png too)
+	SvgScaleBar              bool
+	SvgNorthArrow            bool
+	PngWidth                 int     // in css pixels (96 dpi)
+	PngHeight                int     // in css pixels (96 dpi)
+	PngPadding               int     // in css pixels (96 dpi)
+	PngDpi                   int     // resolution, image is scaled by dpi / 96
+	PngLineWidth             float64 // in css pixels
+	Style                    string  // style of lines in svg and html (popularity, usage, recency, track:ID or json file)
 }
 
 func (c *Configuration) ToString() string {
@@ -28,16 +47,35 @@ func (c *Configuration) ToString() string {
 }
 
 var Cfg = Configuration{
-	SimplifyMinDistance:   50,
-	MatchMaxDistance:      75,
-	InterpolationDistance: 30,
-	ShowPoints:            false,
-	ShowEdges:             true,
-	ShowTrackColors:       false,
-	GeoJsonMergeEdges:     true,
-	SvgWidth:              1000,
-	SvgHeight:             1000,
-	SvgPadding:            50,
-	SvgPointLabels:        false,
-	SvgEdgeLabels:         true,
+	SimplifyMinDistance:      50,
+	SimplifyAlgorithm:        "douglas-peucker",
+	SimplifyPreserveTopology: false,
+	MatchMaxDistance:         75,
+	SpurMaxLength:            50,
+	JunctionRadius:           20,
+	MatchEdges:               false,
+	MatchMaxAngle:            0,
+	InterpolationDistance:    30,
+	RouteSnapDistance:        500,
+	ShowPoints:               false,
+	ShowEdges:                true,
+	ShowTrackColors:          false,
+	GeoJsonMergeEdges:        true,
+	Smooth:                   false,
+	SmoothAlgorithm:          "chaikin",
+	SmoothStrength:           2,
+	SvgWidth:                 1000,
+	SvgHeight:                1000,
+	SvgPadding:               50,
+	SvgPointLabels:           false,
+	SvgEdgeLabels:            true,
+	SvgProjection:            "equirectangular",
+	SvgScaleBar:              false,
+	SvgNorthArrow:            false,
+	PngWidth:                 1000,
+	PngHeight:                1000,
+	PngPadding:               20,
+	PngDpi:                   96,
+	PngLineWidth:             2,
+	Style:                    "",
 }
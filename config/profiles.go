@@ -0,0 +1,42 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// RouteProfile defines cost of edges for routing. Cost of an edge is its
+// length multiplied by factors computed from edge attributes, increased
+// by penalty for climbing.
+type RouteProfile struct {
+	PopularityWeight float64 `json:"popularity_weight"` // > 0 prefers edges with many passes, < 0 avoids them
+	TracksWeight     float64 `json:"tracks_weight"`     // > 0 prefers edges used by many distinct tracks, < 0 avoids them
+	ClimbPenalty     float64 `json:"climb_penalty"`     // extra cost (in meters) for each meter of ascent
+	AgePenalty       float64 `json:"age_penalty"`       // cost increase (e.g. 0.5 = 50%) for each year since the last pass
+	MaxAgeDays       int     `json:"max_age_days"`      // edges not used for given number of days are avoided (0 = no limit)
+}
+
+// LoadRouteProfiles reads named routing profiles from json file, e.g.:
+//
+//	{
+//	  "popular": {"popularity_weight": 1, "tracks_weight": 0.5},
+//	  "recent": {"max_age_days": 730}
+//	}
+func LoadRouteProfiles(filePath string) (map[string]RouteProfile, error) {
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	profiles := make(map[string]RouteProfile)
+
+	err = json.NewDecoder(file).Decode(&profiles)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing route profiles %s: %w", filePath, err)
+	}
+
+	return profiles, nil
+}
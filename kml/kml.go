@@ -0,0 +1,203 @@
+// Package kml converts geojson features into KML document (Google Earth,
+// Garmin BaseCamp) optionally packed into KMZ archive.
+package kml
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+const KML_NAMESPACE = "http://www.opengis.net/kml/2.2"
+
+type kmlRoot struct {
+	XMLName  xml.Name    `xml:"kml"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	Document kmlDocument `xml:"Document"`
+}
+
+type kmlDocument struct {
+	Name       string         `xml:"name"`
+	Styles     []kmlStyle     `xml:"Style"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlStyle struct {
+	Id        string        `xml:"id,attr"`
+	LineStyle *kmlLineStyle `xml:"LineStyle,omitempty"`
+	IconStyle *kmlIconStyle `xml:"IconStyle,omitempty"`
+}
+
+type kmlLineStyle struct {
+	Color string  `xml:"color"`
+	Width float64 `xml:"width"`
+}
+
+type kmlIconStyle struct {
+	Color string  `xml:"color"`
+	Scale float64 `xml:"scale"`
+}
+
+type kmlPlacemark struct {
+	Name        string         `xml:"name"`
+	Description *kmlCData      `xml:"description,omitempty"`
+	StyleUrl    string         `xml:"styleUrl,omitempty"`
+	Point       *kmlGeometry   `xml:"Point,omitempty"`
+	LineString  *kmlGeometry   `xml:"LineString,omitempty"`
+	Extended    *kmlExtendData `xml:"ExtendedData,omitempty"`
+}
+
+type kmlCData struct {
+	Text string `xml:",cdata"`
+}
+
+type kmlGeometry struct {
+	Tessellate  int    `xml:"tessellate,omitempty"`
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlExtendData struct {
+	Data []kmlData `xml:"Data"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+// KML represents the document that should be created, features are added
+// as placemarks
+type KML struct {
+	root kmlRoot
+}
+
+// Placemark describes how a feature is presented
+type Placemark struct {
+	Name        string
+	Description string   // html
+	StyleId     string   // id of style added by AddLineStyle or AddIconStyle
+	Data        []string // properties of feature written as extended data
+}
+
+func NewKML(name string) *KML {
+	return &KML{
+		root: kmlRoot{
+			Xmlns:    KML_NAMESPACE,
+			Document: kmlDocument{Name: name},
+		},
+	}
+}
+
+// Color converts css color #rrggbb to kml color aabbggrr
+func Color(hex string, opacity float64) string {
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) != 6 {
+		hex = "000000"
+	}
+	return fmt.Sprintf("%02x%s%s%s", int(opacity*255), hex[4:6], hex[2:4], hex[0:2])
+}
+
+func (k *KML) AddLineStyle(id, color string, width float64) {
+	k.root.Document.Styles = append(k.root.Document.Styles, kmlStyle{
+		Id:        id,
+		LineStyle: &kmlLineStyle{Color: color, Width: width},
+	})
+}
+
+func (k *KML) AddIconStyle(id, color string, scale float64) {
+	k.root.Document.Styles = append(k.root.Document.Styles, kmlStyle{
+		Id:        id,
+		IconStyle: &kmlIconStyle{Color: color, Scale: scale},
+	})
+}
+
+// AddFeature adds point or line string feature, other geometries are ignored
+func (k *KML) AddFeature(feature *geojson.Feature, p Placemark) {
+
+	pm := kmlPlacemark{Name: p.Name}
+
+	if len(p.Description) > 0 {
+		pm.Description = &kmlCData{Text: p.Description}
+	}
+
+	if len(p.StyleId) > 0 {
+		pm.StyleUrl = "#" + p.StyleId
+	}
+
+	for _, name := range p.Data {
+		if value, ok := feature.Properties[name]; ok {
+			if pm.Extended == nil {
+				pm.Extended = &kmlExtendData{}
+			}
+			pm.Extended.Data = append(pm.Extended.Data, kmlData{Name: name, Value: fmt.Sprint(value)})
+		}
+	}
+
+	switch feature.Geometry.Type {
+	case geojson.GeometryPoint:
+		pm.Point = &kmlGeometry{Coordinates: coordinates([][]float64{feature.Geometry.Point})}
+	case geojson.GeometryLineString:
+		pm.LineString = &kmlGeometry{Tessellate: 1, Coordinates: coordinates(feature.Geometry.LineString)}
+	default:
+		return
+	}
+
+	k.root.Document.Placemarks = append(k.root.Document.Placemarks, pm)
+}
+
+// lng,lat[,ele] tuples separated by space
+func coordinates(points [][]float64) string {
+	var sb strings.Builder
+	for i, p := range points {
+		if i > 0 {
+			sb.WriteString(" ")
+		}
+		for j, c := range p {
+			if j > 0 {
+				sb.WriteString(",")
+			}
+			fmt.Fprintf(&sb, "%g", c)
+		}
+	}
+	return sb.String()
+}
+
+// Bytes renders the KML document
+func (k *KML) Bytes() ([]byte, error) {
+	content, err := xml.MarshalIndent(k.root, "", " ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), content...), nil
+}
+
+// Kmz renders the KML document packed into zip archive (doc.kml)
+func (k *KML) Kmz() ([]byte, error) {
+
+	content, err := k.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	w, err := zw.Create("doc.kml")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = w.Write(content); err != nil {
+		return nil, err
+	}
+
+	if err = zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -3,10 +3,11 @@ package mongostore
 import (
 	"context"
 	"os"
+	"time"
 
 	"mnezerka/geonet/config"
 	"mnezerka/geonet/log"
-	"mnezerka/geonet/tracks"
+	"mnezerka/geonet/store"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -15,51 +16,55 @@ import (
 
 const NIL_ID = -1
 
+// name of the database, tests use their own one
+var dbName = "geonet"
+
 type DbLogMsg struct {
 	Msg string `json:"msg" bson:"msg"`
 }
 
 type DbContent struct {
-	Points []DbPoint  `json:"points"`
-	Edges  []DbEdge   `json:"edges"`
-	Tracks []DbTrack  `json:"tracks"`
-	Log    []DbLogMsg `json:"log"`
-}
-
-type DbMeta struct {
-	Tracks []DbTrack `json:"tracks"`
+	Points []DbPoint     `json:"points"`
+	Edges  []DbEdge      `json:"edges"`
+	Tracks []store.Track `json:"tracks"`
+	Log    []DbLogMsg    `json:"log"`
 }
 
 type DbItem struct {
 	Id int64 `json:"id" bson:"id"`
 }
 
-type DbTrack struct {
-	Id   int64            `json:"id" bson:"id"`
-	Meta tracks.TrackMeta `json:"meta" bson:"meta"`
-}
-
 type GeoJsonGeometry struct {
 	Type        string    `json:"type" bson:"type"`
 	Coordinates []float64 `json:"coordinates" bson:"coordinates"`
 }
 
 type DbPoint struct {
-	Id        int64           `json:"id" bson:"id"`
-	Loc       GeoJsonGeometry `json:"loc" bson:"loc"`
-	Tracks    []int64         `bson:"tracks"`
-	Count     int             `bson:"count"`
-	Begin     bool            `bson:"begin"`
-	End       bool            `bson:"end"`
-	Crossing  bool            `bson:"crossing"`
-	Processed bool            `bson:"processed"`
+	Id          int64           `json:"id" bson:"id"`
+	Loc         GeoJsonGeometry `json:"loc" bson:"loc"`
+	CentroidLat float64         `bson:"centroid_lat"`
+	CentroidLng float64         `bson:"centroid_lng"`
+	Weight      int64           `bson:"weight"` // number of fixes in centroid
+	Tracks      []int64         `bson:"tracks"`
+	Count       int             `bson:"count"`
+	FirstTime   time.Time       `bson:"first_time"`
+	LastTime    time.Time       `bson:"last_time"`
+	Begin       bool            `bson:"begin"`
+	End         bool            `bson:"end"`
+	Crossing    bool            `bson:"crossing"`
+	Processed   bool            `bson:"processed"`
 }
 
+// edge points are sorted, forward passes go from the first to the second one
 type DbEdge struct {
-	Id     string  `json:"id" bson:"id"`
-	Points []int64 `json:"points" bson:"points"`
-	Tracks []int64 `bson:"tracks"`
-	Count  int     `bson:"count"`
+	Id        string    `json:"id" bson:"id"`
+	Points    []int64   `json:"points" bson:"points"`
+	Tracks    []int64   `bson:"tracks"`
+	Count     int       `bson:"count"`
+	Forward   int       `bson:"forward"`
+	Backward  int       `bson:"backward"`
+	FirstTime time.Time `bson:"first_time"`
+	LastTime  time.Time `bson:"last_time"`
 }
 
 type MongoStore struct {
@@ -74,6 +79,7 @@ type MongoStore struct {
 	log         *mongo.Collection
 	lastPointId int64
 	lastTrackId int64
+	stat        store.Stat
 	cfg         *config.Configuration
 }
 
@@ -107,7 +113,7 @@ func NewMongoStore(cfg *config.Configuration) *MongoStore {
 	}
 	log.Info("ping mongodb passed")
 
-	ms.db = ms.client.Database("geonet")
+	ms.db = ms.client.Database(dbName)
 
 	ms.tracks = createCollection(ms.db, "tracks")
 	ms.tracksTmp = createCollection(ms.db, "tracks_tmp")
@@ -161,11 +167,9 @@ func NewMongoStore(cfg *config.Configuration) *MongoStore {
 	return &ms
 }
 
-func (ms *MongoStore) Close() {
+func (ms *MongoStore) Close() error {
 	log.Info("diconnecting from mongodb")
-	if err := ms.client.Disconnect(context.TODO()); err != nil {
-		panic(err)
-	}
+	return ms.client.Disconnect(context.TODO())
 }
 
 func (ms *MongoStore) Log(msg string) {
@@ -214,29 +218,38 @@ func (ms *MongoStore) Reset() {
 	ms.lastTrackId = 0
 }
 
-func findPoint(id int64, points *[]DbPoint) *DbPoint {
-	for i := 0; i < len(*points); i++ {
-		if (*points)[i].Id == id {
-			return &(*points)[i]
-		}
+func (ms *MongoStore) GetMeta() store.Meta {
+
+	meta := store.Meta{Tracks: []*store.Track{}}
+
+	opts := options.Find().SetSort(bson.M{"id": 1})
+	cursor, err := ms.tracks.Find(context.TODO(), bson.D{}, opts)
+	if err != nil {
+		log.ExitWithError(err)
 	}
-	return nil
-}
 
-func (ms *MongoStore) GetMeta() (DbMeta, error) {
+	if err = cursor.All(context.TODO(), &meta.Tracks); err != nil {
+		log.ExitWithError(err)
+	}
 
-	var meta DbMeta
+	return meta
+}
+
+func (ms *MongoStore) GetStat() store.Stat {
 
-	cursor, err := ms.tracks.Find(context.TODO(), bson.D{})
+	points, err := ms.points.CountDocuments(context.TODO(), bson.M{})
 	if err != nil {
-		return meta, err
+		log.ExitWithError(err)
 	}
+	ms.stat.PointsFinal = points
 
-	if err = cursor.All(context.TODO(), &meta.Tracks); err != nil {
-		return meta, err
+	edges, err := ms.edges.CountDocuments(context.TODO(), bson.M{})
+	if err != nil {
+		log.ExitWithError(err)
 	}
+	ms.stat.EdgesFinal = edges
 
-	return meta, nil
+	return ms.stat
 }
 
 func (ms *MongoStore) GetLog() ([]DbLogMsg, error) {
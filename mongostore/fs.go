@@ -0,0 +1,136 @@
+package mongostore
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"mnezerka/geonet/store"
+	"mnezerka/geonet/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// iterate documents of collection sorted by id
+func eachDocument[T any](collection *mongo.Collection, fn func(doc *T) error) error {
+
+	cursor, err := collection.Find(context.TODO(), bson.M{}, options.Find().SetSort(bson.M{"id": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(context.TODO())
+
+	for cursor.Next(context.TODO()) {
+		var doc T
+		if err = cursor.Decode(&doc); err != nil {
+			return err
+		}
+		if err = fn(&doc); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// Save writes the net in json format shared by all stores, documents are
+// streamed from database
+func (ms *MongoStore) Save(w io.Writer) error {
+
+	nw := store.NetWriter{
+		LastPointId: ms.lastPointId,
+		LastTrackId: ms.lastTrackId,
+		Tracks: func(yield func(any) error) error {
+			return eachDocument(ms.tracks, func(t *store.Track) error {
+				return yield(t)
+			})
+		},
+		Locations: func(yield func(any) error) error {
+			return eachDocument(ms.points, func(p *DbPoint) error {
+				return yield(pointToNet(p))
+			})
+		},
+		Edges: func(yield func(any) error) error {
+			return eachDocument(ms.edges, func(e *DbEdge) error {
+				return yield(edgeToNet(e))
+			})
+		},
+	}
+
+	return nw.Write(w)
+}
+
+// Load imports the net saved by any store, the store has to be empty
+func (ms *MongoStore) Load(r io.Reader) error {
+
+	if ms.lastPointId > 0 || ms.lastTrackId > 0 {
+		return fmt.Errorf("cannot load net into non-empty mongo store")
+	}
+
+	nr := store.NetReader{}
+	nr.Track = func(t *store.Track) error {
+		ms.stat.TracksLoaded++
+		_, err := ms.tracks.InsertOne(context.TODO(), t)
+		return err
+	}
+	nr.Location = func(decode func(v any) error) error {
+		np := &store.NetPoint{}
+		if err := decode(np); err != nil {
+			return err
+		}
+		ms.stat.PointsLoaded++
+		_, err := ms.points.InsertOne(context.TODO(), DbPoint{
+			Id: np.Id,
+			Loc: GeoJsonGeometry{
+				Type:        "Point",
+				Coordinates: []float64{np.Lng, np.Lat},
+			},
+			CentroidLat: np.CentroidLat,
+			CentroidLng: np.CentroidLng,
+			Weight:      np.Weight,
+			Tracks:      utils.MapKeys(np.Tracks),
+			Count:       np.Count,
+			FirstTime:   np.FirstTime,
+			LastTime:    np.LastTime,
+			Begin:       np.Begin,
+			End:         np.End,
+			Crossing:    np.Crossing,
+		})
+		return err
+	}
+	nr.Edge = func(decode func(v any) error) error {
+		ne := &store.NetEdge{}
+		if err := decode(ne); err != nil {
+			return err
+		}
+		ms.stat.EdgesLoaded++
+		edge := DbEdge{
+			Id:        edgeIdFromPointIds(ne.Id.P1, ne.Id.P2),
+			Points:    []int64{ne.Id.P1, ne.Id.P2},
+			Tracks:    utils.MapKeys(ne.Tracks),
+			Count:     ne.Count,
+			Forward:   ne.Forward,
+			Backward:  ne.Backward,
+			FirstTime: ne.FirstTime,
+			LastTime:  ne.LastTime,
+		}
+		// points of edge are kept sorted
+		if ne.Id.P1 > ne.Id.P2 {
+			edge.Points = []int64{ne.Id.P2, ne.Id.P1}
+			edge.Forward, edge.Backward = edge.Backward, edge.Forward
+		}
+		_, err := ms.edges.InsertOne(context.TODO(), edge)
+		return err
+	}
+
+	if err := nr.Read(r); err != nil {
+		return err
+	}
+
+	ms.lastPointId = nr.LastPointId
+	ms.lastTrackId = nr.LastTrackId
+
+	return nil
+}
@@ -2,93 +2,103 @@ package mongostore
 
 import (
 	"context"
-	"fmt"
 
 	"mnezerka/geonet/log"
+	"mnezerka/geonet/store"
 
 	geojson "github.com/paulmach/go.geojson"
 
 	"go.mongodb.org/mongo-driver/bson"
 )
 
-func (ms *MongoStore) ToGeoJson() (*geojson.FeatureCollection, error) {
+func pointToNet(p *DbPoint) *store.NetPoint {
+	np := &store.NetPoint{
+		Id:          p.Id,
+		Lat:         p.Loc.Coordinates[1],
+		Lng:         p.Loc.Coordinates[0],
+		CentroidLat: p.CentroidLat,
+		CentroidLng: p.CentroidLng,
+		Weight:      p.Weight,
+		Tracks:      map[int64]bool{},
+		Count:       p.Count,
+		FirstTime:   p.FirstTime,
+		LastTime:    p.LastTime,
+		Begin:       p.Begin,
+		End:         p.End,
+		Crossing:    p.Crossing,
+	}
+	for _, id := range p.Tracks {
+		np.Tracks[id] = true
+	}
+	return np
+}
 
-	collection := geojson.NewFeatureCollection()
+func edgeToNet(e *DbEdge) *store.NetEdge {
+	ne := &store.NetEdge{
+		Id:        store.NetEdgeId{P1: e.Points[0], P2: e.Points[1]},
+		Tracks:    map[int64]bool{},
+		Count:     e.Count,
+		FirstTime: e.FirstTime,
+		LastTime:  e.LastTime,
+		Forward:   e.Forward,
+		Backward:  e.Backward,
+	}
+	for _, id := range e.Tracks {
+		ne.Tracks[id] = true
+	}
+	return ne
+}
 
-	// render points and build dict of points for searching when
-	// rendering edges
+func (ms *MongoStore) getNet() (map[int64]*store.NetPoint, []*store.NetEdge, error) {
 
 	cursor, err := ms.points.Find(context.TODO(), bson.M{})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	var points []DbPoint
-
-	err = cursor.All(context.TODO(), &points)
-	if err != nil {
-		return nil, err
+	var dbPoints []DbPoint
+	if err = cursor.All(context.TODO(), &dbPoints); err != nil {
+		return nil, nil, err
 	}
 
-	if ms.cfg.ShowPoints {
-
-		for i := 0; i < len(points); i++ {
-			point := points[i]
-
-			pnt := geojson.NewPointFeature(point.Loc.Coordinates)
-
-			pnt.SetProperty("id", point.Id)
-			pnt.SetProperty("tracks", point.Tracks)
-			pnt.SetProperty("begin", point.Begin)
-			pnt.SetProperty("end", point.End)
-			pnt.SetProperty("count", point.Count)
-			pnt.SetProperty("crossing", point.Crossing)
-
-			collection.AddFeature(pnt)
-		}
-
-		log.Infof("points rendered: %d", len(points))
+	points := map[int64]*store.NetPoint{}
+	for i := range dbPoints {
+		points[dbPoints[i].Id] = pointToNet(&dbPoints[i])
 	}
 
-	if ms.cfg.ShowEdges {
-
-		// render edges
-		cursor, err = ms.edges.Find(context.TODO(), bson.M{})
-		if err != nil {
-			return nil, err
-		}
-
-		var edges []DbEdge
-
-		err = cursor.All(context.TODO(), &edges)
-		if err != nil {
-			return nil, err
-		}
-
-		for i := 0; i < len(edges); i++ {
-			edge := edges[i]
-			p1 := findPoint(edge.Points[0], &points)
-			p2 := findPoint(edge.Points[1], &points)
+	cursor, err = ms.edges.Find(context.TODO(), bson.M{})
+	if err != nil {
+		return nil, nil, err
+	}
 
-			// of some of the points were not found -> database is inconsistent
-			if p1 == nil || p2 == nil {
-				return nil, fmt.Errorf("inconsistent database, some edge points where not found %d %d", edge.Points[0], edge.Points[1])
-			}
+	var dbEdges []DbEdge
+	if err = cursor.All(context.TODO(), &dbEdges); err != nil {
+		return nil, nil, err
+	}
 
-			log.Debugf("edge: %v, p1: %v p2: %v ", edge, p1, p2)
+	var edges []*store.NetEdge
+	for i := range dbEdges {
+		edges = append(edges, edgeToNet(&dbEdges[i]))
+	}
 
-			edgeCoordinates := [][]float64{p1.Loc.Coordinates, p2.Loc.Coordinates}
+	return points, edges, nil
+}
 
-			line := geojson.NewLineStringFeature(edgeCoordinates)
-			line.SetProperty("id", edge.Id)
-			line.SetProperty("tracks", edge.Tracks)
-			line.SetProperty("count", edge.Count)
+func (ms *MongoStore) ToGeoJson(each func(feature *geojson.Feature)) *geojson.FeatureCollection {
 
-			collection.AddFeature(line)
-		}
+	points, edges, err := ms.getNet()
+	if err != nil {
+		log.ExitWithError(err)
+	}
 
-		log.Infof("edges rendered: %d", len(edges))
+	collection, err := store.NetToGeoJson(ms.cfg, points, edges, nil, each)
+	if err != nil {
+		log.ExitWithError(err)
 	}
 
-	return collection, nil
+	log.Infof("points: %d, edges: %d", len(points), len(edges))
+	ms.stat.PointsRendered = int64(len(points))
+	ms.stat.EdgesRendered = int64(len(edges))
+
+	return collection
 }
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"mnezerka/geonet/log"
+	"mnezerka/geonet/store"
 	"mnezerka/geonet/tracks"
 
 	"github.com/tkrajina/gpxgo/gpx"
@@ -19,7 +20,7 @@ func (ms *MongoStore) AddGpx(track *tracks.Track) error {
 
 	var lastPointId int64 = NIL_ID
 
-	newTrack := DbTrack{
+	newTrack := store.Track{
 		Id:   ms.GenTrackId(),
 		Meta: track.Meta,
 	}
@@ -30,6 +31,7 @@ func (ms *MongoStore) AddGpx(track *tracks.Track) error {
 	}
 
 	log.Debugf("registered track %d", newTrack.Id)
+	ms.stat.TracksProcessed++
 
 	for i := 0; i < len(track.Points); i++ {
 		point := track.Points[i]
@@ -43,6 +45,18 @@ func (ms *MongoStore) AddGpx(track *tracks.Track) error {
 		if err != nil {
 			return err
 		}
+		if i == 0 {
+			newTrack.BeginPointId = lastPointId
+		}
+	}
+
+	if len(track.Points) > 0 {
+		newTrack.EndPointId = lastPointId
+		update := bson.M{"begin_point_id": newTrack.BeginPointId, "end_point_id": newTrack.EndPointId}
+		_, err = ms.tracks.UpdateOne(context.TODO(), bson.M{"id": newTrack.Id}, bson.M{"$set": update})
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -52,6 +66,8 @@ func (ms *MongoStore) AddGpxPoint(point *gpx.GPXPoint, trackId int64, lastPointI
 
 	var finalPointId int64
 
+	ms.stat.PointsGpx++
+
 	cntr := bson.M{
 		"type":        "Point",
 		"coordinates": []float64{point.Longitude, point.Latitude},
@@ -93,12 +109,21 @@ func (ms *MongoStore) AddGpxPoint(point *gpx.GPXPoint, trackId int64, lastPointI
 
 		log.Debugf("reusing point %v", n)
 
-		// increase counter
-		//update := bson.M{"$set": bson.M{"count": n.Count + 1}}
+		n.mergeCentroid(point.Latitude, point.Longitude, 1)
 		update := bson.M{
-			"count": n.Count + 1,
-			"begin": n.Begin || begin,
-			"end":   n.End || end,
+			"centroid_lat": n.CentroidLat,
+			"centroid_lng": n.CentroidLng,
+			"weight":       n.Weight,
+			"begin":        n.Begin || begin,
+			"end":          n.End || end,
+		}
+
+		// count pass through the point (repeated fixes in the same point are single pass)
+		if n.Id != lastPointId {
+			addTime(&n.FirstTime, &n.LastTime, point.Timestamp)
+			update["count"] = n.Count + 1
+			update["first_time"] = n.FirstTime
+			update["last_time"] = n.LastTime
 		}
 
 		// if current track is not associated with reused point, add it
@@ -114,6 +139,7 @@ func (ms *MongoStore) AddGpxPoint(point *gpx.GPXPoint, trackId int64, lastPointI
 		}
 
 		finalPointId = n.Id
+		ms.stat.PointsReused++
 	} else {
 		// no near point exists, register new one
 
@@ -124,11 +150,15 @@ func (ms *MongoStore) AddGpxPoint(point *gpx.GPXPoint, trackId int64, lastPointI
 				Type:        "Point",
 				Coordinates: []float64{point.Longitude, point.Latitude},
 			},
-			Tracks: []int64{trackId},
-			Count:  1,
-			Begin:  begin,
-			End:    end,
+			CentroidLat: point.Latitude,
+			CentroidLng: point.Longitude,
+			Weight:      1,
+			Tracks:      []int64{trackId},
+			Count:       1,
+			Begin:       begin,
+			End:         end,
 		}
+		addTime(&newPoint.FirstTime, &newPoint.LastTime, point.Timestamp)
 
 		log.Debugf("creating new point %v", newPoint)
 
@@ -138,6 +168,7 @@ func (ms *MongoStore) AddGpxPoint(point *gpx.GPXPoint, trackId int64, lastPointI
 		}
 
 		finalPointId = newPoint.Id
+		ms.stat.PointsCreated++
 	}
 
 	// --------------------  edge processing
@@ -155,8 +186,8 @@ func (ms *MongoStore) AddGpxPoint(point *gpx.GPXPoint, trackId int64, lastPointI
 				Id:     edgeId,
 				Points: edgePoints,
 				Tracks: []int64{trackId},
-				Count:  1,
 			}
+			newEdge.addPass(lastPointId, point.Timestamp)
 
 			// check if edge already exists
 			var existingEdges []DbEdge
@@ -173,8 +204,13 @@ func (ms *MongoStore) AddGpxPoint(point *gpx.GPXPoint, trackId int64, lastPointI
 
 				existingEdge := existingEdges[0]
 				log.Debugf("reusing existing edge: %s", existingEdge.Id)
+				existingEdge.addPass(lastPointId, point.Timestamp)
 				update := bson.M{
-					"count": existingEdge.Count + 1,
+					"count":      existingEdge.Count,
+					"forward":    existingEdge.Forward,
+					"backward":   existingEdge.Backward,
+					"first_time": existingEdge.FirstTime,
+					"last_time":  existingEdge.LastTime,
 				}
 
 				// if current track is not associated with reused edge, add it
@@ -188,12 +224,14 @@ func (ms *MongoStore) AddGpxPoint(point *gpx.GPXPoint, trackId int64, lastPointI
 				if err != nil {
 					return 0, err
 				}
+				ms.stat.EdgesReused++
 			} else {
 				log.Debugf("registering new edge: %s", newEdge.Id)
 				_, err = ms.edges.InsertOne(context.TODO(), newEdge)
 				if err != nil {
 					return NIL_ID, err
 				}
+				ms.stat.EdgesCreated++
 
 				// update crossings
 				err = ms.updateEdgeCrossings(&newEdge, newEdge.Points[0])
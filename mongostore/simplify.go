@@ -3,14 +3,12 @@ package mongostore
 import (
 	"context"
 	"fmt"
-	"strconv"
 
 	"mnezerka/geonet/log"
 
 	"mnezerka/geonet/config"
+	"mnezerka/geonet/utils"
 
-	"github.com/mnezerka/gpxcli/gpxutils"
-	"github.com/tkrajina/gpxgo/gpx"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
@@ -22,7 +20,7 @@ var freeNotProcessedPointsFilter bson.M = bson.M{
 	"processed": false, // point cannot be processed
 }
 
-func (ms *MongoStore) Simplify() {
+func (ms *MongoStore) Simplify() error {
 
 	ms.Log(fmt.Sprintf("simplify cfg=(%s)",
 		config.Cfg.ToString(),
@@ -31,12 +29,14 @@ func (ms *MongoStore) Simplify() {
 	// reset all points to not processed state to be sure we start with clean setup
 	_, err := ms.points.UpdateMany(context.TODO(), bson.M{}, bson.M{"$set": bson.M{"processed": false}})
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	// simplify segments until there is no more to simplify
 	for ms.SimplifySegment() {
 	}
+
+	return nil
 }
 
 func (ms *MongoStore) SimplifySegment() bool {
@@ -99,6 +99,7 @@ func (ms *MongoStore) SimplifySegment() bool {
 	}
 
 	// 3. simplify
+	ms.stat.SegmentsProcessed++
 	simplifiedPath := simplifyPath(path, ms.cfg.SimplifyMinDistance)
 	log.Debugf("simplified path: %v", simplifiedPath)
 
@@ -250,34 +251,18 @@ func pointsToIds(points []DbPoint) []int64 {
 
 func simplifyPath(path []DbPoint, minDistance int64) []int64 {
 
-	gpxFile := gpx.GPX{}
-
+	var coordinates [][]float64
 	for i := 0; i < len(path); i++ {
-		np := gpx.GPXPoint{
-			Point: gpx.Point{
-				Latitude:  path[i].Loc.Coordinates[0],
-				Longitude: path[i].Loc.Coordinates[1],
-			},
-			Name: strconv.FormatInt(path[i].Id, 10),
-		}
-
-		gpxFile.AppendPoint(&np)
+		coordinates = append(coordinates, path[i].Loc.Coordinates)
 	}
 
-	gpxFile.SimplifyTracks(float64(minDistance))
-
-	simplifiedPath, err := gpxutils.GpxFileToPoints(&gpxFile)
-	if err != nil {
-		panic(err)
-	}
+	keep := utils.DouglasPeucker(coordinates, float64(minDistance))
 
 	var result []int64
-	for i := 0; i < len(simplifiedPath); i++ {
-		id, err := strconv.ParseInt(simplifiedPath[i].Name, 10, 64)
-		if err != nil {
-			panic(err)
+	for i := 0; i < len(path); i++ {
+		if keep[i] {
+			result = append(result, path[i].Id)
 		}
-		result = append(result, id)
 	}
 
 	return result
@@ -337,7 +322,7 @@ func (ms *MongoStore) mergeEdges(beginId, endId int64, toRemoveIds []int64) {
 	if finalEdge == nil {
 		log.Debugf("creating new final edge, id: %v", finalEdgeId)
 
-		finalEdge := DbEdge{
+		finalEdge = &DbEdge{
 			Id:     finalEdgeId,
 			Points: finalEdgePoints,
 			Tracks: []int64{},
@@ -359,16 +344,10 @@ func (ms *MongoStore) mergeEdges(beginId, endId int64, toRemoveIds []int64) {
 		panic(fmt.Errorf("edge %s not found", firstEdgeId))
 	}
 
-	// update final edge with properties of the first edge
-	finalEdgeUpdate := bson.M{
-		"count":  firstEdge.Count,
-		"tracks": firstEdge.Tracks,
-	}
-
-	_, err := ms.edges.UpdateOne(context.TODO(), bson.M{"id": finalEdgeId}, bson.M{"$set": finalEdgeUpdate})
-	if err != nil {
-		panic(err)
-	}
+	// add passes of the first edge to the final edge, both edges are
+	// oriented by their sorted points, so directions are swapped when
+	// the path enters them from different ends
+	finalEdge.merge(firstEdge, (firstEdge.Points[0] == beginId) != (finalEdge.Points[0] == beginId))
 
 	// delete all edges
 	var allIds = append(append([]int64{beginId}, toRemoveIds...), endId)
@@ -377,8 +356,31 @@ func (ms *MongoStore) mergeEdges(beginId, endId int64, toRemoveIds []int64) {
 	for i := 1; i < len(allIds); i++ {
 		edgeId := edgeIdFromPointIds(allIds[i-1], allIds[i])
 		edgeIdsToRemove = append(edgeIdsToRemove, edgeId)
+
+		// passes could be recorded with different times along the path
+		if edge := ms.getSingleEdge(bson.M{"id": edgeId}); edge != nil {
+			addTime(&finalEdge.FirstTime, &finalEdge.LastTime, edge.FirstTime)
+			addTime(&finalEdge.FirstTime, &finalEdge.LastTime, edge.LastTime)
+		}
+	}
+
+	finalEdgeUpdate := bson.M{
+		"count":      finalEdge.Count,
+		"tracks":     finalEdge.Tracks,
+		"forward":    finalEdge.Forward,
+		"backward":   finalEdge.Backward,
+		"first_time": finalEdge.FirstTime,
+		"last_time":  finalEdge.LastTime,
+	}
+
+	_, err := ms.edges.UpdateOne(context.TODO(), bson.M{"id": finalEdgeId}, bson.M{"$set": finalEdgeUpdate})
+	if err != nil {
+		panic(err)
 	}
 
+	ms.stat.PointsSimplified += int64(len(toRemoveIds))
+	ms.stat.EdgesSimplified += int64(len(edgeIdsToRemove))
+
 	// delete redundant points
 	log.Debugf("points to be deleted %v", toRemoveIds)
 	ms.points.DeleteMany(context.TODO(), bson.M{"id": bson.M{"$in": toRemoveIds}})
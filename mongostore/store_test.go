@@ -0,0 +1,74 @@
+package mongostore
+
+import (
+	"context"
+	"fmt"
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/s2store"
+	"mnezerka/geonet/tracks"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestStore(t *testing.T, cfg *config.Configuration) *MongoStore {
+	if os.Getenv("MONGODB_URI") == "" {
+		t.Skip("MONGODB_URI is not set")
+	}
+
+	dbName = "geonet_test"
+	ms := NewMongoStore(cfg)
+	t.Cleanup(func() {
+		assert.Nil(t, ms.db.Drop(context.TODO()))
+		assert.Nil(t, ms.Close())
+	})
+	ms.Reset()
+
+	return ms
+}
+
+func TestGeoJsonParity(t *testing.T) {
+
+	t1 := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	// same path in both directions with times
+	timed := tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.002}, {50.0, 14.004}})
+	reversed := tracks.NewTrackFromPoints([][2]float64{{50.0, 14.004}, {50.0, 14.002}, {50.0, 14.0}})
+	for i := range timed.Points {
+		timed.Points[i].Timestamp = t1.Add(time.Duration(i) * time.Minute)
+		reversed.Points[i].Timestamp = t1.Add(time.Duration(i) * time.Hour)
+	}
+
+	// two consecutive fixes in the same point are single pass
+	repeated := tracks.NewTrackFromPoints([][2]float64{{50.2, 14.0}, {50.2, 14.0001}, {50.2, 14.002}})
+
+	// straight track with middle point removed by simplification and
+	// track going directly between its ends
+	bent := tracks.NewTrackFromPoints([][2]float64{{50.1, 14.0}, {50.1, 14.002}, {50.1, 14.004}})
+	direct := tracks.NewTrackFromPoints([][2]float64{{50.1, 14.0}, {50.1, 14.004}})
+
+	for _, merge := range []bool{true, false} {
+		t.Run(fmt.Sprintf("merge=%v", merge), func(t *testing.T) {
+			cfg := config.Cfg
+			cfg.ShowPoints = true
+			cfg.GeoJsonMergeEdges = merge
+
+			ms := newTestStore(t, &cfg)
+			s2 := s2store.NewS2Store(&cfg)
+
+			for _, track := range []*tracks.Track{tracks.NewTrack("../test_data/t1.gpx"), timed, reversed, repeated, bent, direct} {
+				assert.Nil(t, ms.AddGpx(track))
+				assert.Nil(t, s2.AddGpx(track))
+			}
+
+			assert.Equal(t, s2.ToGeoJson(nil), ms.ToGeoJson(nil))
+
+			assert.Nil(t, ms.Simplify())
+			assert.Nil(t, s2.Simplify())
+
+			assert.Equal(t, s2.ToGeoJson(nil), ms.ToGeoJson(nil))
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package mongostore
+
+import (
+	"context"
+	"mnezerka/geonet/log"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func boolToStr(x bool) string {
+	if x {
+		return "X"
+	}
+	return ""
+}
+
+func (ms *MongoStore) ToTxt() string {
+
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Point", "Tracks", "Neighbours", "Count", "Begin", "End", "Crossing"})
+
+	cursor, err := ms.points.Find(context.TODO(), bson.M{}, options.Find().SetSort(bson.M{"id": 1}))
+	if err != nil {
+		log.ExitWithError(err)
+	}
+
+	var points []DbPoint
+	if err = cursor.All(context.TODO(), &points); err != nil {
+		log.ExitWithError(err)
+	}
+
+	for _, point := range points {
+		t.AppendRow(table.Row{
+			point.Id,
+			point.Tracks,
+			ms.findNeighbours(point),
+			point.Count,
+			boolToStr(point.Begin),
+			boolToStr(point.End),
+			boolToStr(point.Crossing),
+		})
+	}
+
+	return t.Render()
+}
@@ -1,7 +1,72 @@
 package mongostore
 
-import "fmt"
+import (
+	"fmt"
+	"slices"
+	"time"
+)
 
 func edgeIdFromPointIds(from, to int64) string {
 	return fmt.Sprintf("%d-%d", min(from, to), max(from, to))
 }
+
+// extend time range of traversals by time t (zero is unknown time)
+func addTime(first, last *time.Time, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	if first.IsZero() || t.Before(*first) {
+		*first = t
+	}
+	if last.IsZero() || t.After(*last) {
+		*last = t
+	}
+}
+
+// move centroid of the point by a fix (or by other centroid of given weight)
+func (p *DbPoint) mergeCentroid(lat, lng float64, weight int64) {
+
+	// point without centroid (e.g. loaded from older file) is considered
+	// to be a single fix at its position
+	if p.Weight == 0 {
+		p.CentroidLat = p.Loc.Coordinates[1]
+		p.CentroidLng = p.Loc.Coordinates[0]
+		p.Weight = 1
+	}
+
+	total := p.Weight + weight
+	p.CentroidLat = (p.CentroidLat*float64(p.Weight) + lat*float64(weight)) / float64(total)
+	p.CentroidLng = (p.CentroidLng*float64(p.Weight) + lng*float64(weight)) / float64(total)
+	p.Weight = total
+}
+
+// count pass through the edge started in point fromId
+func (e *DbEdge) addPass(fromId int64, t time.Time) {
+	e.Count++
+	if fromId == e.Points[0] {
+		e.Forward++
+	} else {
+		e.Backward++
+	}
+	addTime(&e.FirstTime, &e.LastTime, t)
+}
+
+// add passes and tracks of other edge, reversed edge goes from the second
+// point to the first one
+func (e *DbEdge) merge(other *DbEdge, reversed bool) {
+	e.Count += other.Count
+	for _, id := range other.Tracks {
+		if !slices.Contains(e.Tracks, id) {
+			e.Tracks = append(e.Tracks, id)
+		}
+	}
+	addTime(&e.FirstTime, &e.LastTime, other.FirstTime)
+	addTime(&e.FirstTime, &e.LastTime, other.LastTime)
+	if reversed {
+		e.Forward += other.Backward
+		e.Backward += other.Forward
+	} else {
+		e.Forward += other.Forward
+		e.Backward += other.Backward
+	}
+}
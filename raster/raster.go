@@ -0,0 +1,348 @@
+// Package raster draws geojson features (points and lines) styled the same
+// way as in svg output to raster image, no map background is used
+package raster
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"mnezerka/geonet/svg"
+	"strconv"
+	"strings"
+
+	"github.com/fogleman/gg"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// resolution of css pixel, sizes of lines and points are given in css
+// pixels and scaled by dpi of the image
+const BASE_DPI = 96
+
+// Style of feature, colors are css colors (#rrggbb or name), empty or
+// "none" color means the part is not drawn
+type Style struct {
+	Stroke      string
+	StrokeWidth float64
+	Fill        string
+	Radius      float64
+}
+
+type StyleFunc func(feature *geojson.Feature) Style
+
+// Padding represents the padding of the image in css pixels
+type Padding struct{ Top, Right, Bottom, Left float64 }
+
+// Raster represents the image that should be created
+type Raster struct {
+	padding            Padding
+	dpi                float64
+	background         string
+	style              StyleFunc
+	projection         svg.Projection
+	featureCollections []*geojson.FeatureCollection
+}
+
+// An Option represents a single raster option
+type Option func(*Raster)
+
+func NewRaster() *Raster {
+	return &Raster{
+		dpi:        BASE_DPI,
+		background: "white",
+		projection: svg.PlateCarree,
+		style: func(feature *geojson.Feature) Style {
+			return ParseStyle(styleProperty(feature))
+		},
+	}
+}
+
+// AddFeatureCollection adds a geojson featurecollection to the image
+func (r *Raster) AddFeatureCollection(fc *geojson.FeatureCollection) {
+	r.featureCollections = append(r.featureCollections, fc)
+}
+
+// WithPadding configures the image to use the specified padding
+func WithPadding(p Padding) Option {
+	return func(r *Raster) {
+		r.padding = p
+	}
+}
+
+// WithDpi scales size of the image, lines and points (96 = 1:1)
+func WithDpi(dpi float64) Option {
+	return func(r *Raster) {
+		r.dpi = dpi
+	}
+}
+
+// WithBackground sets color of the image background ("none" = transparent)
+func WithBackground(c string) Option {
+	return func(r *Raster) {
+		r.background = c
+	}
+}
+
+// WithStyle sets function providing style of each feature, features are
+// styled by svg "style" property by default
+func WithStyle(f StyleFunc) Option {
+	return func(r *Raster) {
+		r.style = f
+	}
+}
+
+// WithProjection configures projection of lng, lat coordinates (the same
+// projections as in svg output are used)
+func WithProjection(p svg.Projection) Option {
+	return func(r *Raster) {
+		r.projection = p
+	}
+}
+
+// Draw renders features to image of given size (css pixels), features are
+// scaled to fit into the image and centered
+func (r *Raster) Draw(width, height float64, opts ...Option) image.Image {
+	for _, o := range opts {
+		o(r)
+	}
+
+	scale := r.dpi / BASE_DPI
+
+	dc := gg.NewContext(int(math.Round(width*scale)), int(math.Round(height*scale)))
+
+	if c, ok := ParseColor(r.background); ok {
+		dc.SetColor(c)
+		dc.Clear()
+	}
+
+	sf := r.makeScaleFunc(width, height)
+
+	for _, fc := range r.featureCollections {
+		for _, f := range fc.Features {
+			style := r.style(f)
+
+			switch {
+			case f.Geometry.IsLineString():
+				drawLineString(dc, sf, scale, f.Geometry.LineString, style)
+			case f.Geometry.IsMultiLineString():
+				for _, ls := range f.Geometry.MultiLineString {
+					drawLineString(dc, sf, scale, ls, style)
+				}
+			case f.Geometry.IsPoint():
+				drawPoint(dc, sf, scale, f.Geometry.Point, style)
+			case f.Geometry.IsMultiPoint():
+				for _, p := range f.Geometry.MultiPoint {
+					drawPoint(dc, sf, scale, p, style)
+				}
+			}
+		}
+	}
+
+	return dc.Image()
+}
+
+func drawLineString(dc *gg.Context, sf func(x, y float64) (float64, float64), scale float64, ps [][]float64, style Style) {
+	c, ok := ParseColor(style.Stroke)
+	if !ok || len(ps) < 2 {
+		return
+	}
+
+	for _, p := range ps {
+		x, y := sf(p[0], p[1])
+		dc.LineTo(x*scale, y*scale)
+	}
+
+	width := style.StrokeWidth
+	if width == 0 {
+		width = 1
+	}
+
+	dc.SetColor(c)
+	dc.SetLineWidth(width * scale)
+	dc.SetLineCap(gg.LineCapRound)
+	dc.SetLineJoin(gg.LineJoinRound)
+	dc.Stroke()
+}
+
+func drawPoint(dc *gg.Context, sf func(x, y float64) (float64, float64), scale float64, p []float64, style Style) {
+	c, ok := ParseColor(style.Fill)
+	if !ok {
+		return
+	}
+
+	radius := style.Radius
+	if radius == 0 {
+		radius = 1
+	}
+
+	x, y := sf(p[0], p[1])
+	dc.DrawCircle(x*scale, y*scale, radius*scale)
+	dc.SetColor(c)
+	dc.Fill()
+}
+
+// projection of lng, lat into the image (as in svg), projected content is
+// scaled linearly, y axis is reversed
+func (r *Raster) makeScaleFunc(width, height float64) func(lng, lat float64) (float64, float64) {
+	w := width - r.padding.Left - r.padding.Right
+	h := height - r.padding.Top - r.padding.Bottom
+
+	var points [][]float64
+	for _, fc := range r.featureCollections {
+		for _, f := range fc.Features {
+			points = append(points, collect(f.Geometry)...)
+		}
+	}
+
+	project := r.projection(points)
+
+	first := true
+	var minX, minY, maxX, maxY float64
+	for _, p := range points {
+		x, y := project(p[0], p[1])
+		if first {
+			minX, maxX, minY, maxY = x, x, y, y
+			first = false
+		}
+		minX = math.Min(minX, x)
+		maxX = math.Max(maxX, x)
+		minY = math.Min(minY, y)
+		maxY = math.Max(maxY, y)
+	}
+
+	res := math.Max((maxX-minX)/w, (maxY-minY)/h)
+	if res == 0 {
+		return func(lng, lat float64) (float64, float64) { return width / 2, height / 2 }
+	}
+
+	// center content in both directions
+	offsetX := r.padding.Left + (w-(maxX-minX)/res)/2
+	offsetY := r.padding.Top + (h-(maxY-minY)/res)/2
+
+	return func(lng, lat float64) (float64, float64) {
+		x, y := project(lng, lat)
+		return (x-minX)/res + offsetX, (maxY-y)/res + offsetY
+	}
+}
+
+func collect(g *geojson.Geometry) [][]float64 {
+	switch {
+	case g.IsPoint():
+		return [][]float64{g.Point}
+	case g.IsMultiPoint():
+		return g.MultiPoint
+	case g.IsLineString():
+		return g.LineString
+	case g.IsMultiLineString():
+		var ps [][]float64
+		for _, ls := range g.MultiLineString {
+			ps = append(ps, ls...)
+		}
+		return ps
+	}
+	return nil
+}
+
+func styleProperty(feature *geojson.Feature) string {
+	if style, ok := feature.Properties["style"].(string); ok {
+		return style
+	}
+	return ""
+}
+
+// ParseStyle reads svg style of feature, e.g.
+// "stroke: black; stroke-width: 2; fill: none" or "fill: #ff5c77; r: 8px"
+func ParseStyle(css string) Style {
+	var style Style
+
+	for _, declaration := range strings.Split(css, ";") {
+		name, value, found := strings.Cut(declaration, ":")
+		if !found {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+
+		switch name {
+		case "stroke":
+			style.Stroke = value
+		case "stroke-width":
+			style.StrokeWidth = parseSize(value)
+		case "fill":
+			style.Fill = value
+		case "r":
+			style.Radius = parseSize(value)
+		}
+	}
+
+	return style
+}
+
+func parseSize(value string) float64 {
+	size, err := strconv.ParseFloat(strings.TrimSuffix(value, "px"), 64)
+	if err != nil {
+		return 0
+	}
+	return size
+}
+
+var namedColors = map[string]color.RGBA{
+	"black": {0, 0, 0, 255},
+	"white": {255, 255, 255, 255},
+	"red":   {255, 0, 0, 255},
+	"green": {0, 128, 0, 255},
+	"blue":  {0, 0, 255, 255},
+	"gray":  {128, 128, 128, 255},
+}
+
+// ParseColor converts css color (#rrggbb or basic color name), false is
+// returned for "none" or unknown color
+func ParseColor(value string) (color.Color, bool) {
+	if c, exists := namedColors[value]; exists {
+		return c, true
+	}
+
+	if len(value) == 7 && value[0] == '#' {
+		rgb, err := strconv.ParseUint(value[1:], 16, 32)
+		if err == nil {
+			return color.RGBA{uint8(rgb >> 16), uint8(rgb >> 8), uint8(rgb), 255}, true
+		}
+	}
+
+	return nil, false
+}
+
+// EncodePng encodes image to png with physical resolution (pHYs chunk) set
+// to given dpi
+func EncodePng(img image.Image, dpi float64) ([]byte, error) {
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	// pixels per meter for both axes, unit = meter
+	data := make([]byte, 9)
+	ppm := uint32(math.Round(dpi / 0.0254))
+	binary.BigEndian.PutUint32(data[0:], ppm)
+	binary.BigEndian.PutUint32(data[4:], ppm)
+	data[8] = 1
+
+	chunk := make([]byte, 0, 12+len(data))
+	chunk = binary.BigEndian.AppendUint32(chunk, uint32(len(data)))
+	chunk = append(chunk, "pHYs"...)
+	chunk = append(chunk, data...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+
+	// chunk is placed right after signature (8 bytes) and IHDR (25 bytes)
+	content := buf.Bytes()
+	result := make([]byte, 0, len(content)+len(chunk))
+	result = append(result, content[:33]...)
+	result = append(result, chunk...)
+	result = append(result, content[33:]...)
+
+	return result, nil
+}
@@ -0,0 +1,619 @@
+package s2store
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mnezerka/geonet/store"
+	"mnezerka/geonet/utils"
+	"slices"
+	"time"
+)
+
+// magic bytes and version of binary file format
+const BINARY_MAGIC = "GNB"
+const BINARY_VERSION = 1
+
+// coordinates are stored as integers (1e-9 degree is ~0.1mm)
+const BINARY_COORD_SCALE = 1e9
+
+// elevation is stored in millimeters
+const BINARY_ELE_SCALE = 1e3
+
+// max length of byte strings (track meta, bitmaps), protects against huge
+// allocations when reading corrupted file
+const BINARY_MAX_BYTES = 16 << 20
+
+// flags of location
+const (
+	binFlagBegin = 1 << iota
+	binFlagEnd
+	binFlagCrossing
+	binFlagEle
+	binFlagFirstTime
+	binFlagLastTime
+	binFlagPasses
+)
+
+// encoding of track id sets
+const (
+	binSetList   = 0 // deltas of sorted indexes
+	binSetBitmap = 1 // bitmap of indexes starting at the lowest index
+)
+
+/*
+binary format (all integers are varints, signed ones zigzag encoded):
+
+	magic "GNB", version byte
+	last point id, last track id
+	dictionary of track ids (count, first id, deltas)
+	tracks (count, then id, begin point id, end point id, meta as json)
+	locations sorted by id (count, then id delta, lat/lng deltas to previous
+	  location, flags, centroid deltas to position, weight, elevation,
+	  usage, track set)
+	edges sorted by points (count, then p1 delta, p2 delta to p1, flags,
+	  forward and backward passes, usage, track sets of all, forward and
+	  backward tracks)
+
+usage is number of passes, first and last time (if flagged) and passes of
+tracks (if flagged, count, then track index delta, number of passes,
+forward passes, flags, first and last time)
+
+track sets refer to indexes in the dictionary, they are stored either as
+list of deltas or as bitmap (whichever is shorter)
+*/
+
+type binWriter struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (bw *binWriter) uvarint(v uint64) {
+	if bw.err == nil {
+		n := binary.PutUvarint(bw.buf[:], v)
+		_, bw.err = bw.w.Write(bw.buf[:n])
+	}
+}
+
+func (bw *binWriter) varint(v int64) {
+	if bw.err == nil {
+		n := binary.PutVarint(bw.buf[:], v)
+		_, bw.err = bw.w.Write(bw.buf[:n])
+	}
+}
+
+func (bw *binWriter) bytes(b []byte) {
+	bw.uvarint(uint64(len(b)))
+	if bw.err == nil {
+		_, bw.err = bw.w.Write(b)
+	}
+}
+
+func (bw *binWriter) time(t time.Time) {
+	bw.varint(t.Unix())
+	bw.uvarint(uint64(t.Nanosecond()))
+}
+
+type binReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (br *binReader) uvarint() uint64 {
+	if br.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(br.r)
+	br.err = err
+	return v
+}
+
+func (br *binReader) varint() int64 {
+	if br.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(br.r)
+	br.err = err
+	return v
+}
+
+func (br *binReader) bytes() []byte {
+	size := br.uvarint()
+	if br.err != nil {
+		return nil
+	}
+	if size > BINARY_MAX_BYTES {
+		br.err = fmt.Errorf("invalid length of data %d (max %d)", size, BINARY_MAX_BYTES)
+		return nil
+	}
+	b := make([]byte, size)
+	_, br.err = io.ReadFull(br.r, b)
+	return b
+}
+
+func (br *binReader) time() time.Time {
+	sec := br.varint()
+	nsec := br.uvarint()
+	return time.Unix(sec, int64(nsec)).UTC()
+}
+
+func coordToInt(v float64) int64 {
+	return int64(math.Round(v * BINARY_COORD_SCALE))
+}
+
+func intToCoord(v int64) float64 {
+	return float64(v) / BINARY_COORD_SCALE
+}
+
+// dictionary of all track ids used in the net
+type trackDict struct {
+	ids     []int64
+	indexes map[int64]int
+}
+
+func (s *S2Store) newTrackDict() *trackDict {
+
+	all := make(map[int64]bool)
+	for id := range s.tracks {
+		all[id] = true
+	}
+	for _, l := range s.index.flat {
+		utils.MapsMerge(all, l.Tracks)
+		for id := range l.Passes {
+			all[id] = true
+		}
+	}
+	for _, e := range s.edges {
+		utils.MapsMerge(all, e.Tracks)
+		utils.MapsMerge(all, e.ForwardTracks)
+		utils.MapsMerge(all, e.BackwardTracks)
+		for id := range e.Passes {
+			all[id] = true
+		}
+	}
+
+	d := &trackDict{ids: utils.MapKeys(all), indexes: make(map[int64]int)}
+	slices.Sort(d.ids)
+	for i, id := range d.ids {
+		d.indexes[id] = i
+	}
+
+	return d
+}
+
+func (bw *binWriter) trackSet(d *trackDict, set map[int64]bool) {
+
+	indexes := make([]int, 0, len(set))
+	for id := range set {
+		indexes = append(indexes, d.indexes[id])
+	}
+	slices.Sort(indexes)
+
+	bw.uvarint(uint64(len(indexes)))
+	if len(indexes) == 0 {
+		return
+	}
+
+	// size of both encodings
+	listSize := 0
+	for i, ix := range indexes {
+		if i > 0 {
+			ix -= indexes[i-1]
+		}
+		listSize += uvarintLen(uint64(ix))
+	}
+	first := indexes[0]
+	bitmapSize := uvarintLen(uint64(first)) + (indexes[len(indexes)-1]-first)/8 + 1
+
+	if listSize <= bitmapSize {
+		bw.uvarint(binSetList)
+		for i, ix := range indexes {
+			if i > 0 {
+				ix -= indexes[i-1]
+			}
+			bw.uvarint(uint64(ix))
+		}
+		return
+	}
+
+	bitmap := make([]byte, (indexes[len(indexes)-1]-first)/8+1)
+	for _, ix := range indexes {
+		bit := ix - first
+		bitmap[bit/8] |= 1 << (bit % 8)
+	}
+	bw.uvarint(binSetBitmap)
+	bw.uvarint(uint64(first))
+	bw.bytes(bitmap)
+}
+
+func (br *binReader) trackSet(d *trackDict) map[int64]bool {
+
+	set := make(map[int64]bool)
+
+	count := br.uvarint()
+	if count == 0 || br.err != nil {
+		return set
+	}
+
+	add := func(ix int) {
+		if ix < 0 || ix >= len(d.ids) {
+			br.err = fmt.Errorf("invalid track index %d", ix)
+			return
+		}
+		set[d.ids[ix]] = true
+	}
+
+	switch br.uvarint() {
+	case binSetList:
+		ix := 0
+		for i := uint64(0); i < count && br.err == nil; i++ {
+			ix += int(br.uvarint())
+			add(ix)
+		}
+	case binSetBitmap:
+		first := int(br.uvarint())
+		bitmap := br.bytes()
+		for bit := 0; bit < len(bitmap)*8 && br.err == nil; bit++ {
+			if bitmap[bit/8]&(1<<(bit%8)) != 0 {
+				add(first + bit)
+			}
+		}
+	default:
+		if br.err == nil {
+			br.err = errors.New("invalid encoding of track set")
+		}
+	}
+
+	if br.err == nil && uint64(len(set)) != count {
+		br.err = fmt.Errorf("track set size mismatch, expected %d, got %d", count, len(set))
+	}
+
+	return set
+}
+
+func uvarintLen(v uint64) int {
+	n := 1
+	for v >= 0x80 {
+		v >>= 7
+		n++
+	}
+	return n
+}
+
+func (bw *binWriter) usage(d *trackDict, u Usage) {
+	bw.uvarint(uint64(u.Count))
+	bw.times(u.FirstTime, u.LastTime)
+	if len(u.Passes) == 0 {
+		return
+	}
+
+	indexes := make([]int, 0, len(u.Passes))
+	for id := range u.Passes {
+		indexes = append(indexes, d.indexes[id])
+	}
+	slices.Sort(indexes)
+
+	bw.uvarint(uint64(len(indexes)))
+	for i, ix := range indexes {
+		p := u.Passes[d.ids[ix]]
+		if i > 0 {
+			bw.uvarint(uint64(ix - indexes[i-1]))
+		} else {
+			bw.uvarint(uint64(ix))
+		}
+		bw.uvarint(uint64(p.Count))
+		bw.uvarint(uint64(p.Forward))
+		bw.uvarint(timeFlags(p.FirstTime, p.LastTime))
+		bw.times(p.FirstTime, p.LastTime)
+	}
+}
+
+func (br *binReader) usage(d *trackDict, flags uint64) Usage {
+	u := Usage{Count: int(br.uvarint())}
+	u.FirstTime, u.LastTime = br.times(flags)
+	if flags&binFlagPasses == 0 {
+		return u
+	}
+
+	count := br.uvarint()
+	ix := 0
+	for i := uint64(0); i < count && br.err == nil; i++ {
+		ix += int(br.uvarint())
+		if ix < 0 || ix >= len(d.ids) {
+			br.err = fmt.Errorf("invalid track index %d", ix)
+			break
+		}
+		p := TrackPasses{Count: int(br.uvarint()), Forward: int(br.uvarint())}
+		p.FirstTime, p.LastTime = br.times(br.uvarint())
+		u.setPasses(d.ids[ix], p)
+	}
+
+	return u
+}
+
+// times which are not zero (flagged by timeFlags)
+func (bw *binWriter) times(first, last time.Time) {
+	if !first.IsZero() {
+		bw.time(first)
+	}
+	if !last.IsZero() {
+		bw.time(last)
+	}
+}
+
+func (br *binReader) times(flags uint64) (first, last time.Time) {
+	if flags&binFlagFirstTime != 0 {
+		first = br.time()
+	}
+	if flags&binFlagLastTime != 0 {
+		last = br.time()
+	}
+	return first, last
+}
+
+func timeFlags(first, last time.Time) uint64 {
+	var flags uint64
+	if !first.IsZero() {
+		flags |= binFlagFirstTime
+	}
+	if !last.IsZero() {
+		flags |= binFlagLastTime
+	}
+	return flags
+}
+
+func usageFlags(u Usage) uint64 {
+	flags := timeFlags(u.FirstTime, u.LastTime)
+	if len(u.Passes) > 0 {
+		flags |= binFlagPasses
+	}
+	return flags
+}
+
+// SaveBinary writes the net in compact binary format
+func (s *S2Store) SaveBinary(w io.Writer) error {
+
+	bw := &binWriter{w: bufio.NewWriter(w)}
+
+	bw.w.WriteString(BINARY_MAGIC)
+	bw.w.WriteByte(BINARY_VERSION)
+
+	bw.varint(s.lastPointId)
+	bw.varint(s.lastTrackId)
+
+	// dictionary of track ids
+	dict := s.newTrackDict()
+	bw.uvarint(uint64(len(dict.ids)))
+	for i, id := range dict.ids {
+		if i == 0 {
+			bw.varint(id)
+		} else {
+			bw.uvarint(uint64(id - dict.ids[i-1]))
+		}
+	}
+
+	// tracks
+	trackIds := utils.MapKeys(s.tracks)
+	slices.Sort(trackIds)
+	bw.uvarint(uint64(len(trackIds)))
+	for _, id := range trackIds {
+		t := s.tracks[id]
+		meta, err := json.Marshal(t.Meta)
+		if err != nil {
+			return err
+		}
+		bw.varint(t.Id)
+		bw.varint(t.BeginPointId)
+		bw.varint(t.EndPointId)
+		bw.bytes(meta)
+	}
+	s.stat.TracksRendered = int64(len(trackIds))
+
+	// locations
+	locationIds := utils.MapKeys(s.index.flat)
+	slices.Sort(locationIds)
+	bw.uvarint(uint64(len(locationIds)))
+
+	var prevId, prevLat, prevLng int64
+	for _, id := range locationIds {
+		l := s.index.flat[id]
+
+		lat := coordToInt(l.Lat)
+		lng := coordToInt(l.Lng)
+
+		flags := usageFlags(l.Usage)
+		if l.Begin {
+			flags |= binFlagBegin
+		}
+		if l.End {
+			flags |= binFlagEnd
+		}
+		if l.Crossing {
+			flags |= binFlagCrossing
+		}
+		if l.Ele != nil {
+			flags |= binFlagEle
+		}
+
+		bw.varint(l.Id - prevId)
+		bw.varint(lat - prevLat)
+		bw.varint(lng - prevLng)
+		bw.uvarint(flags)
+		bw.varint(coordToInt(l.CentroidLat) - lat)
+		bw.varint(coordToInt(l.CentroidLng) - lng)
+		bw.varint(l.Weight)
+		if l.Ele != nil {
+			bw.varint(int64(math.Round(*l.Ele * BINARY_ELE_SCALE)))
+		}
+		bw.usage(dict, l.Usage)
+		bw.trackSet(dict, l.Tracks)
+
+		prevId, prevLat, prevLng = l.Id, lat, lng
+	}
+	s.stat.PointsRendered = int64(len(locationIds))
+
+	// edges
+	edgeIds := utils.MapKeys(s.edges)
+	slices.SortFunc(edgeIds, func(a, b S2EdgeKey) int {
+		if a.P1 != b.P1 {
+			return cmp.Compare(a.P1, b.P1)
+		}
+		return cmp.Compare(a.P2, b.P2)
+	})
+	bw.uvarint(uint64(len(edgeIds)))
+
+	var prevP1 int64
+	for _, id := range edgeIds {
+		e := s.edges[id]
+
+		bw.varint(e.Id.P1 - prevP1)
+		bw.varint(e.Id.P2 - e.Id.P1)
+		bw.uvarint(usageFlags(e.Usage))
+		bw.uvarint(uint64(e.Forward))
+		bw.uvarint(uint64(e.Backward))
+		bw.usage(dict, e.Usage)
+		bw.trackSet(dict, e.Tracks)
+		bw.trackSet(dict, e.ForwardTracks)
+		bw.trackSet(dict, e.BackwardTracks)
+
+		prevP1 = e.Id.P1
+	}
+	s.stat.EdgesRendered = int64(len(edgeIds))
+
+	if bw.err != nil {
+		return bw.err
+	}
+
+	return bw.w.Flush()
+}
+
+// LoadBinary reads the net saved by SaveBinary
+func (s *S2Store) LoadBinary(r io.Reader) error {
+
+	br := &binReader{r: bufio.NewReader(r)}
+
+	header := make([]byte, len(BINARY_MAGIC)+1)
+	if _, err := io.ReadFull(br.r, header); err != nil {
+		return err
+	}
+	if string(header[:len(BINARY_MAGIC)]) != BINARY_MAGIC {
+		return errors.New("invalid binary geonet file")
+	}
+	if header[len(BINARY_MAGIC)] > BINARY_VERSION {
+		return fmt.Errorf("unsupported binary geonet file version %d (max %d)", header[len(BINARY_MAGIC)], BINARY_VERSION)
+	}
+
+	s.lastPointId = br.varint()
+	s.lastTrackId = br.varint()
+
+	// dictionary of track ids
+	dict := &trackDict{indexes: make(map[int64]int)}
+	count := br.uvarint()
+	for i := uint64(0); i < count && br.err == nil; i++ {
+		var id int64
+		if i == 0 {
+			id = br.varint()
+		} else {
+			id = dict.ids[i-1] + int64(br.uvarint())
+		}
+		dict.indexes[id] = len(dict.ids)
+		dict.ids = append(dict.ids, id)
+	}
+
+	// tracks
+	count = br.uvarint()
+	for i := uint64(0); i < count && br.err == nil; i++ {
+		t := &store.Track{}
+		t.Id = br.varint()
+		t.BeginPointId = br.varint()
+		t.EndPointId = br.varint()
+		meta := br.bytes()
+		if br.err != nil {
+			break
+		}
+		if err := json.Unmarshal(meta, &t.Meta); err != nil {
+			return fmt.Errorf("invalid meta of track %d: %w", t.Id, err)
+		}
+		s.tracks[t.Id] = t
+		s.stat.TracksLoaded++
+	}
+
+	// locations
+	count = br.uvarint()
+	var prevId, prevLat, prevLng int64
+	for i := uint64(0); i < count && br.err == nil; i++ {
+		l := NewLocation()
+
+		l.Id = prevId + br.varint()
+		lat := prevLat + br.varint()
+		lng := prevLng + br.varint()
+		l.Lat = intToCoord(lat)
+		l.Lng = intToCoord(lng)
+
+		flags := br.uvarint()
+		l.Begin = flags&binFlagBegin != 0
+		l.End = flags&binFlagEnd != 0
+		l.Crossing = flags&binFlagCrossing != 0
+
+		l.CentroidLat = intToCoord(lat + br.varint())
+		l.CentroidLng = intToCoord(lng + br.varint())
+		l.Weight = br.varint()
+		if flags&binFlagEle != 0 {
+			ele := float64(br.varint()) / BINARY_ELE_SCALE
+			l.Ele = &ele
+		}
+		l.Usage = br.usage(dict, flags)
+		l.Tracks = br.trackSet(dict)
+
+		if br.err != nil {
+			break
+		}
+
+		s.index.Add(l)
+		s.stat.PointsLoaded++
+
+		prevId, prevLat, prevLng = l.Id, lat, lng
+	}
+
+	// edges
+	count = br.uvarint()
+	var prevP1 int64
+	for i := uint64(0); i < count && br.err == nil; i++ {
+		e := NewS2Edge()
+
+		e.Id.P1 = prevP1 + br.varint()
+		e.Id.P2 = e.Id.P1 + br.varint()
+
+		flags := br.uvarint()
+		e.Forward = int(br.uvarint())
+		e.Backward = int(br.uvarint())
+		e.Usage = br.usage(dict, flags)
+		e.Tracks = br.trackSet(dict)
+		e.ForwardTracks = br.trackSet(dict)
+		e.BackwardTracks = br.trackSet(dict)
+
+		if br.err != nil {
+			break
+		}
+
+		if s.index.GetLocation(e.Id.P1) == nil || s.index.GetLocation(e.Id.P2) == nil {
+			return fmt.Errorf("invalid geonet file, missing points of edge %v", e.Id)
+		}
+		s.AddEdge(e)
+		s.stat.EdgesLoaded++
+
+		prevP1 = e.Id.P1
+	}
+
+	if br.err != nil {
+		return fmt.Errorf("error reading binary geonet file: %w", br.err)
+	}
+
+	return nil
+}
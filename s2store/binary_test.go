@@ -0,0 +1,167 @@
+package s2store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/tracks"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newBinaryTestStore(t *testing.T) *S2Store {
+
+	s := NewS2Store(&config.Cfg)
+	assert.Nil(t, s.AddGpx(tracks.NewTrack("../test_data/t1.gpx")))
+	assert.Nil(t, s.AddGpx(tracks.NewTrack("../test_data/t2.gpx")))
+
+	// attributes not present in test tracks
+	ele := 245.5
+	loc := s.index.GetLocation(1)
+	loc.Ele = &ele
+	loc.FirstTime = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	loc.LastTime = time.Date(2024, 6, 1, 10, 0, 0, 500, time.UTC)
+
+	return s
+}
+
+func assertStoresEqual(t *testing.T, expected, actual *S2Store) {
+
+	assert.Equal(t, expected.lastPointId, actual.lastPointId)
+	assert.Equal(t, expected.lastTrackId, actual.lastTrackId)
+	assert.Equal(t, expected.tracks, actual.tracks)
+	assert.Len(t, actual.index.GetLocations(), len(expected.index.GetLocations()))
+	assert.Len(t, actual.edges, len(expected.edges))
+
+	for id, l := range expected.index.GetLocations() {
+		a := actual.index.GetLocation(id)
+		assert.NotNil(t, a)
+		assert.InDelta(t, l.Lat, a.Lat, 1e-9)
+		assert.InDelta(t, l.Lng, a.Lng, 1e-9)
+		assert.InDelta(t, l.CentroidLat, a.CentroidLat, 1e-9)
+		assert.InDelta(t, l.CentroidLng, a.CentroidLng, 1e-9)
+		assert.Equal(t, l.Weight, a.Weight)
+		assert.Equal(t, l.Ele, a.Ele)
+		assert.Equal(t, l.Tracks, a.Tracks)
+		assert.Equal(t, l.Count, a.Count)
+		assert.True(t, l.FirstTime.Equal(a.FirstTime))
+		assert.True(t, l.LastTime.Equal(a.LastTime))
+		assert.Equal(t, l.Begin, a.Begin)
+		assert.Equal(t, l.End, a.End)
+		assert.Equal(t, l.Crossing, a.Crossing)
+		assert.Len(t, a.Edges, len(l.Edges))
+	}
+
+	for id, e := range expected.edges {
+		a := actual.edges[id]
+		assert.NotNil(t, a)
+		assert.Equal(t, e.Tracks, a.Tracks)
+		assert.Equal(t, e.ForwardTracks, a.ForwardTracks)
+		assert.Equal(t, e.BackwardTracks, a.BackwardTracks)
+		assert.Equal(t, e.Forward, a.Forward)
+		assert.Equal(t, e.Backward, a.Backward)
+		assert.Equal(t, e.Count, a.Count)
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+
+	s := newBinaryTestStore(t)
+
+	for _, compression := range []string{COMPRESSION_NONE, COMPRESSION_GZIP, COMPRESSION_ZSTD} {
+		var buf bytes.Buffer
+		assert.Nil(t, s.SaveFormat(&buf, FORMAT_BINARY, compression))
+
+		loaded := NewS2Store(&config.Cfg)
+		assert.Nil(t, loaded.LoadDetect(&buf))
+		assertStoresEqual(t, s, loaded)
+	}
+
+	// binary format is smaller than json
+	var jsonBuf, binBuf bytes.Buffer
+	assert.Nil(t, s.Save(&jsonBuf))
+	assert.Nil(t, s.SaveBinary(&binBuf))
+	assert.Less(t, binBuf.Len()*2, jsonBuf.Len())
+
+	// json is detected too
+	loaded := NewS2Store(&config.Cfg)
+	assert.Nil(t, loaded.LoadDetect(&jsonBuf))
+	assertStoresEqual(t, s, loaded)
+}
+
+func TestBinaryTrackSets(t *testing.T) {
+
+	dict := &trackDict{indexes: make(map[int64]int)}
+	for i := int64(0); i < 100; i++ {
+		dict.ids = append(dict.ids, i*3+1)
+		dict.indexes[i*3+1] = int(i)
+	}
+
+	// sparse (list) and dense (bitmap) sets
+	sets := []map[int64]bool{
+		{},
+		{1: true, 298: true},
+		{},
+	}
+	for i := int64(10); i < 60; i++ {
+		sets[2][i*3+1] = true
+	}
+
+	var buf bytes.Buffer
+	bw := &binWriter{w: bufio.NewWriter(&buf)}
+	for _, set := range sets {
+		bw.trackSet(dict, set)
+	}
+	assert.Nil(t, bw.err)
+	assert.Nil(t, bw.w.Flush())
+
+	// 50 tracks in bitmap of 7 bytes
+	assert.Less(t, buf.Len(), 20)
+
+	br := &binReader{r: bufio.NewReader(&buf)}
+	for _, set := range sets {
+		assert.Equal(t, set, br.trackSet(dict))
+	}
+	assert.Nil(t, br.err)
+
+}
+
+func TestFormatFromPath(t *testing.T) {
+
+	format, compression := FormatFromPath("net.gnb.zst")
+	assert.Equal(t, FORMAT_BINARY, format)
+	assert.Equal(t, COMPRESSION_ZSTD, compression)
+
+	format, compression = FormatFromPath("net.geonet.gz")
+	assert.Equal(t, FORMAT_JSON, format)
+	assert.Equal(t, COMPRESSION_GZIP, compression)
+
+	format, compression = FormatFromPath("-")
+	assert.Equal(t, FORMAT_JSON, format)
+	assert.Equal(t, COMPRESSION_NONE, compression)
+}
+
+func TestBinaryCorrupted(t *testing.T) {
+
+	// length of data far beyond the limit is rejected before allocation
+	var buf bytes.Buffer
+	buf.Write(binary.AppendUvarint(nil, 1<<62))
+	buf.WriteString("short")
+
+	br := &binReader{r: bufio.NewReader(&buf)}
+	assert.Nil(t, br.bytes())
+	assert.ErrorContains(t, br.err, "invalid length of data")
+
+	// length within limit, but data is missing
+	buf.Reset()
+	buf.Write(binary.AppendUvarint(nil, 100))
+	buf.WriteString("short")
+
+	br = &binReader{r: bufio.NewReader(&buf)}
+	br.bytes()
+	assert.ErrorIs(t, br.err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,161 @@
+package s2store
+
+import (
+	"fmt"
+	"mnezerka/geonet/log"
+	"mnezerka/geonet/utils"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// Component is a connected part of the net (locations reachable from each
+// other by edges)
+type Component struct {
+	Id           int
+	Locations    []int64
+	Edges        int
+	LengthMeters float64
+	Tracks       []int64
+}
+
+// Components finds all connected components of the net, components are
+// sorted by length (the longest first)
+func (s *S2Store) Components() []*Component {
+
+	var components []*Component
+
+	locationIds := utils.MapKeys(s.index.GetLocations())
+	slices.Sort(locationIds)
+
+	visited := make(map[int64]bool)
+
+	for _, id := range locationIds {
+		if visited[id] {
+			continue
+		}
+
+		component := &Component{}
+		edges := make(map[S2EdgeKey]bool)
+		trackIds := make(map[int64]bool)
+
+		// breadth first search from the first unvisited location
+		visited[id] = true
+		queue := []int64{id}
+		for len(queue) > 0 {
+			loc := s.index.GetLocation(queue[0])
+			queue = queue[1:]
+
+			component.Locations = append(component.Locations, loc.Id)
+			for trackId := range loc.Tracks {
+				trackIds[trackId] = true
+			}
+
+			for neighbourId, edge := range loc.Edges {
+				if !edges[edge.Id] {
+					edges[edge.Id] = true
+					neighbour := s.index.GetLocation(neighbourId)
+					component.LengthMeters += utils.HaversineDistance(loc.Lat, loc.Lng, neighbour.Lat, neighbour.Lng)
+					for trackId := range edge.Tracks {
+						trackIds[trackId] = true
+					}
+				}
+				if !visited[neighbourId] {
+					visited[neighbourId] = true
+					queue = append(queue, neighbourId)
+				}
+			}
+		}
+
+		slices.Sort(component.Locations)
+		component.Edges = len(edges)
+		component.Tracks = utils.MapKeys(trackIds)
+		slices.Sort(component.Tracks)
+
+		components = append(components, component)
+	}
+
+	sort.SliceStable(components, func(i, j int) bool {
+		return components[i].LengthMeters > components[j].LengthMeters
+	})
+
+	for i, c := range components {
+		c.Id = i + 1
+	}
+
+	return components
+}
+
+// PruneIslands deletes all components shorter than given length, tracks
+// which are not part of the net anymore are removed too, returns number
+// of deleted components
+func (s *S2Store) PruneIslands(minLengthMeters float64) int {
+
+	pruned := 0
+	removed := make(map[int64]bool)
+
+	for _, c := range s.Components() {
+		if c.LengthMeters >= minLengthMeters {
+			continue
+		}
+
+		log.Debugf("pruning component %d (%.1fm, points %v)", c.Id, c.LengthMeters, c.Locations)
+		s.removeLocationsByIds(c.Locations)
+		for _, id := range c.Locations {
+			removed[id] = true
+		}
+		pruned++
+	}
+
+	if pruned == 0 {
+		return 0
+	}
+
+	// tracks still present in the net
+	used := make(map[int64]bool)
+	for _, loc := range s.index.GetLocations() {
+		for trackId := range loc.Tracks {
+			used[trackId] = true
+		}
+	}
+
+	for id, t := range s.tracks {
+		if !used[id] {
+			log.Debugf("removing track %d, no points left", id)
+			delete(s.tracks, id)
+			continue
+		}
+
+		// boundaries of partially pruned tracks are not known anymore
+		if removed[t.BeginPointId] {
+			t.BeginPointId = 0
+		}
+		if removed[t.EndPointId] {
+			t.EndPointId = 0
+		}
+	}
+
+	return pruned
+}
+
+// PrintComponents writes table of components to stderr
+func PrintComponents(components []*Component) {
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stderr)
+	t.AppendHeader(table.Row{"Component", "Points", "Edges", "Length (km)", "Tracks"})
+
+	for _, c := range components {
+		t.AppendRow(table.Row{
+			c.Id,
+			len(c.Locations),
+			c.Edges,
+			fmt.Sprintf("%.2f", c.LengthMeters/1000),
+			c.Tracks,
+		})
+	}
+
+	t.Render()
+}
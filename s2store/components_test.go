@@ -0,0 +1,50 @@
+package s2store
+
+import (
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/tracks"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComponents(t *testing.T) {
+
+	s := NewS2Store(&config.Cfg)
+
+	// long track (~1.4km) crossed by shorter one, isolated short walk (~290m)
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.01}, {50.0, 14.02}})))
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.005, 14.01}, {50.0, 14.01}, {49.995, 14.01}})))
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.1, 14.0}, {50.1, 14.002}, {50.1, 14.004}})))
+
+	components := s.Components()
+	assert.Len(t, components, 2)
+
+	assert.Equal(t, 1, components[0].Id)
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, components[0].Locations)
+	assert.Equal(t, 4, components[0].Edges)
+	assert.Equal(t, []int64{1, 2}, components[0].Tracks)
+	assert.InDelta(t, 2543, components[0].LengthMeters, 10)
+
+	assert.Equal(t, 2, components[1].Id)
+	assert.Equal(t, []int64{6, 7, 8}, components[1].Locations)
+	assert.Equal(t, []int64{3}, components[1].Tracks)
+	assert.InDelta(t, 286, components[1].LengthMeters, 3)
+}
+
+func TestPruneIslands(t *testing.T) {
+
+	s := NewS2Store(&config.Cfg)
+
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.01}, {50.0, 14.02}})))
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.1, 14.0}, {50.1, 14.002}, {50.1, 14.004}})))
+
+	assert.Equal(t, 0, s.PruneIslands(100))
+	assert.Len(t, s.index.GetLocations(), 6)
+
+	assert.Equal(t, 1, s.PruneIslands(500))
+	assert.Len(t, s.index.GetLocations(), 3)
+	assert.Len(t, s.edges, 2)
+	assert.Len(t, s.GetMeta().Tracks, 1)
+	assert.Nil(t, s.index.GetLocation(4))
+}
@@ -0,0 +1,223 @@
+package s2store
+
+import (
+	"cmp"
+	"fmt"
+	"mnezerka/geonet/store"
+	"mnezerka/geonet/utils"
+	"os"
+	"slices"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// kinds of change between two versions of the net
+const CHANGE_ADDED = "added"
+const CHANGE_REMOVED = "removed"
+const CHANGE_CHANGED = "changed"
+const CHANGE_UNCHANGED = "unchanged"
+
+// Diff lists differences between two versions of the net, locations and
+// edges are identified by ids (the new net is expected to be built on top
+// of the old one), changed means different track set or moved position
+type Diff struct {
+	LocationsAdded   []int64
+	LocationsRemoved []int64
+	LocationsChanged []int64
+	EdgesAdded       []S2EdgeKey
+	EdgesRemoved     []S2EdgeKey
+	EdgesChanged     []S2EdgeKey
+	AddedMeters      float64 // length of added edges
+	RemovedMeters    float64 // length of removed edges
+	OldMeters        float64 // length of the old net
+	NewMeters        float64 // length of the new net
+	oldNet           *S2Store
+	newNet           *S2Store
+}
+
+func edgeLength(s *S2Store, id S2EdgeKey) float64 {
+	p1 := s.index.GetLocation(id.P1)
+	p2 := s.index.GetLocation(id.P2)
+	return utils.HaversineDistance(p1.Lat, p1.Lng, p2.Lat, p2.Lng)
+}
+
+func compareEdgeKeys(a, b S2EdgeKey) int {
+	if c := cmp.Compare(a.P1, b.P1); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.P2, b.P2)
+}
+
+// Compare finds differences between old and new version of the net,
+// locations moved by more than movedMeters (and edges of such locations)
+// are reported as changed
+func Compare(oldNet, newNet *S2Store, movedMeters float64) *Diff {
+
+	d := &Diff{oldNet: oldNet, newNet: newNet}
+
+	moved := func(id int64) bool {
+		loc := newNet.index.GetLocation(id)
+		oldLoc := oldNet.index.GetLocation(id)
+		return utils.HaversineDistance(loc.Lat, loc.Lng, oldLoc.Lat, oldLoc.Lng) > movedMeters
+	}
+
+	for id, loc := range newNet.index.GetLocations() {
+		oldLoc := oldNet.index.GetLocation(id)
+		if oldLoc == nil {
+			d.LocationsAdded = append(d.LocationsAdded, id)
+		} else if !utils.MapsEqual(loc.Tracks, oldLoc.Tracks) || moved(id) {
+			d.LocationsChanged = append(d.LocationsChanged, id)
+		}
+	}
+
+	for id := range oldNet.index.GetLocations() {
+		if newNet.index.GetLocation(id) == nil {
+			d.LocationsRemoved = append(d.LocationsRemoved, id)
+		}
+	}
+
+	for id, edge := range newNet.edges {
+		length := edgeLength(newNet, id)
+		d.NewMeters += length
+
+		oldEdge := oldNet.getEdgeById(id)
+		if oldEdge == nil {
+			d.EdgesAdded = append(d.EdgesAdded, id)
+			d.AddedMeters += length
+		} else if !utils.MapsEqual(edge.Tracks, oldEdge.Tracks) || moved(id.P1) || moved(id.P2) {
+			d.EdgesChanged = append(d.EdgesChanged, id)
+		}
+	}
+
+	for id := range oldNet.edges {
+		length := edgeLength(oldNet, id)
+		d.OldMeters += length
+
+		if newNet.getEdgeById(id) == nil {
+			d.EdgesRemoved = append(d.EdgesRemoved, id)
+			d.RemovedMeters += length
+		}
+	}
+
+	slices.Sort(d.LocationsAdded)
+	slices.Sort(d.LocationsRemoved)
+	slices.Sort(d.LocationsChanged)
+	slices.SortFunc(d.EdgesAdded, compareEdgeKeys)
+	slices.SortFunc(d.EdgesRemoved, compareEdgeKeys)
+	slices.SortFunc(d.EdgesChanged, compareEdgeKeys)
+
+	return d
+}
+
+// kind of change of each item of the new net
+func changes[K comparable](added, changed []K) map[K]string {
+	result := map[K]string{}
+	for _, id := range added {
+		result[id] = CHANGE_ADDED
+	}
+	for _, id := range changed {
+		result[id] = CHANGE_CHANGED
+	}
+	return result
+}
+
+// ToGeoJson renders edges (and locations if showPoints is set) with
+// `change` property, unchanged items are included only if requested
+func (d *Diff) ToGeoJson(showPoints, unchanged bool) *geojson.FeatureCollection {
+
+	collection := geojson.NewFeatureCollection()
+
+	addEdge := func(s *S2Store, id S2EdgeKey, change string) {
+		edge := s.getEdgeById(id)
+		p1 := s.index.GetLocation(id.P1)
+		p2 := s.index.GetLocation(id.P2)
+
+		line := geojson.NewLineStringFeature([][]float64{{p1.Lng, p1.Lat}, {p2.Lng, p2.Lat}})
+		line.SetProperty("id", edgeIdToString(id))
+		line.SetProperty("change", change)
+		line.SetProperty("tracks", sortedTrackIds(edge.Tracks))
+		if change == CHANGE_CHANGED {
+			line.SetProperty("old_tracks", sortedTrackIds(d.oldNet.getEdgeById(id).Tracks))
+		}
+		store.SetUsageProperties(line, edge.Count, len(edge.Tracks), edge.FirstTime, edge.LastTime)
+		collection.AddFeature(line)
+	}
+
+	edgeChanges := changes(d.EdgesAdded, d.EdgesChanged)
+	ids := utils.MapKeys(d.newNet.edges)
+	slices.SortFunc(ids, compareEdgeKeys)
+	for _, id := range ids {
+		change, ok := edgeChanges[id]
+		if !ok {
+			change = CHANGE_UNCHANGED
+		}
+		if change != CHANGE_UNCHANGED || unchanged {
+			addEdge(d.newNet, id, change)
+		}
+	}
+	for _, id := range d.EdgesRemoved {
+		addEdge(d.oldNet, id, CHANGE_REMOVED)
+	}
+
+	if !showPoints {
+		return collection
+	}
+
+	addLocation := func(loc *Location, change string) {
+		pnt := geojson.NewPointFeature([]float64{loc.Lng, loc.Lat})
+		pnt.SetProperty("id", loc.Id)
+		pnt.SetProperty("change", change)
+		pnt.SetProperty("tracks", sortedTrackIds(loc.Tracks))
+		pnt.SetProperty("begin", loc.Begin)
+		pnt.SetProperty("end", loc.End)
+		pnt.SetProperty("crossing", loc.Crossing)
+		if change == CHANGE_CHANGED {
+			oldLoc := d.oldNet.index.GetLocation(loc.Id)
+			pnt.SetProperty("old_tracks", sortedTrackIds(oldLoc.Tracks))
+			pnt.SetProperty("moved", utils.HaversineDistance(loc.Lat, loc.Lng, oldLoc.Lat, oldLoc.Lng))
+		}
+		store.SetUsageProperties(pnt, loc.Count, len(loc.Tracks), loc.FirstTime, loc.LastTime)
+		collection.AddFeature(pnt)
+	}
+
+	locationChanges := changes(d.LocationsAdded, d.LocationsChanged)
+	locIds := utils.MapKeys(d.newNet.index.GetLocations())
+	slices.Sort(locIds)
+	for _, id := range locIds {
+		change, ok := locationChanges[id]
+		if !ok {
+			change = CHANGE_UNCHANGED
+		}
+		if change != CHANGE_UNCHANGED || unchanged {
+			addLocation(d.newNet.index.GetLocation(id), change)
+		}
+	}
+	for _, id := range d.LocationsRemoved {
+		addLocation(d.oldNet.index.GetLocation(id), CHANGE_REMOVED)
+	}
+
+	return collection
+}
+
+// Print writes summary of differences to stderr
+func (d *Diff) Print() {
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stderr)
+	t.AppendHeader(table.Row{"Entity", "Added", "Removed", "Changed"})
+	t.AppendRows([]table.Row{
+		{"locations", len(d.LocationsAdded), len(d.LocationsRemoved), len(d.LocationsChanged)},
+		{"edges", len(d.EdgesAdded), len(d.EdgesRemoved), len(d.EdgesChanged)},
+		{"length (km)", fmt.Sprintf("%.2f", d.AddedMeters/1000), fmt.Sprintf("%.2f", d.RemovedMeters/1000), "-"},
+	})
+	t.AppendFooter(table.Row{"net (km)", fmt.Sprintf("%.2f -> %.2f", d.OldMeters/1000, d.NewMeters/1000), "", ""})
+
+	t.Render()
+}
+
+func sortedTrackIds(tracks map[int64]bool) []int64 {
+	ids := utils.MapKeys(tracks)
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,60 @@
+package s2store
+
+import (
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/tracks"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+
+	track1 := tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.01}, {50.0, 14.02}})
+	track2 := tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.01}, {50.01, 14.01}})
+
+	oldNet := NewS2Store(&config.Cfg)
+	assert.Nil(t, oldNet.AddGpx(track1))
+
+	// new version of the net has one more track
+	newNet := NewS2Store(&config.Cfg)
+	assert.Nil(t, newNet.AddGpx(track1))
+	assert.Nil(t, newNet.AddGpx(track2))
+
+	diff := Compare(oldNet, newNet, 1)
+
+	assert.Equal(t, []int64{4}, diff.LocationsAdded)
+	assert.Equal(t, []int64{1, 2}, diff.LocationsChanged)
+	assert.Empty(t, diff.LocationsRemoved)
+	assert.Equal(t, []S2EdgeKey{{2, 4}}, diff.EdgesAdded)
+	assert.Equal(t, []S2EdgeKey{{1, 2}}, diff.EdgesChanged)
+	assert.Empty(t, diff.EdgesRemoved)
+	assert.InDelta(t, 1112, diff.AddedMeters, 2)
+	assert.InDelta(t, 1430, diff.OldMeters, 2)
+
+	collection := diff.ToGeoJson(false, false)
+	assert.Len(t, collection.Features, 2)
+	assert.Equal(t, "1-2", collection.Features[0].Properties["id"])
+	assert.Equal(t, CHANGE_CHANGED, collection.Features[0].Properties["change"])
+	assert.Equal(t, []int64{1}, collection.Features[0].Properties["old_tracks"])
+	assert.Equal(t, CHANGE_ADDED, collection.Features[1].Properties["change"])
+
+	// reversed comparison reports removed items
+	diff = Compare(newNet, oldNet, 1)
+	assert.Equal(t, []int64{4}, diff.LocationsRemoved)
+	assert.Equal(t, []S2EdgeKey{{2, 4}}, diff.EdgesRemoved)
+
+	collection = diff.ToGeoJson(true, true)
+	assert.Len(t, collection.Features, 3+4)
+	assert.Equal(t, CHANGE_REMOVED, collection.Features[2].Properties["change"])
+
+	// edges of moved location are changed even if their tracks are the same
+	movedNet := NewS2Store(&config.Cfg)
+	assert.Nil(t, movedNet.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.01}, {50.0002, 14.02}})))
+
+	diff = Compare(oldNet, movedNet, 1)
+	assert.Equal(t, []int64{3}, diff.LocationsChanged)
+	assert.Equal(t, []S2EdgeKey{{2, 3}}, diff.EdgesChanged)
+	assert.Empty(t, diff.EdgesAdded)
+	assert.Empty(t, diff.EdgesRemoved)
+}
@@ -0,0 +1,171 @@
+package s2store
+
+import (
+	"mnezerka/geonet/utils"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+type NearestEdgeResult struct {
+	Edge           *S2Edge
+	Lat            float64 // position of the query point projected to the edge
+	Lng            float64
+	DistanceMeters float64
+}
+
+type indexedEdge struct {
+	edge  *S2Edge
+	a, b  s2.Point
+	cells []s2.CellID
+}
+
+// EdgeIndex is a spatial index of edges. Each edge is registered in all cells
+// (of given level) its geometry passes through, which allows searching for edges
+// whose vertices are far from the query point.
+type EdgeIndex struct {
+	data  map[s2.CellID]map[S2EdgeKey]*indexedEdge
+	level int
+	flat  map[S2EdgeKey]*indexedEdge
+}
+
+func NewEdgeIndex(level int) *EdgeIndex {
+	return &EdgeIndex{
+		data:  make(map[s2.CellID]map[S2EdgeKey]*indexedEdge),
+		level: level,
+		flat:  make(map[S2EdgeKey]*indexedEdge),
+	}
+}
+
+func (ei *EdgeIndex) Add(edge *S2Edge, l1, l2 *Location) {
+
+	// edge could be registered with old geometry (e.g. location was moved)
+	ei.Remove(edge.Id)
+
+	ll1 := s2.LatLngFromDegrees(l1.Lat, l1.Lng)
+	ll2 := s2.LatLngFromDegrees(l2.Lat, l2.Lng)
+
+	ie := &indexedEdge{
+		edge: edge,
+		a:    s2.PointFromLatLng(ll1),
+		b:    s2.PointFromLatLng(ll2),
+	}
+
+	ie.cells = ei.covering(ll1, ll2)
+
+	for _, cell := range ie.cells {
+		edges, ok := ei.data[cell]
+		if !ok {
+			edges = make(map[S2EdgeKey]*indexedEdge)
+			ei.data[cell] = edges
+		}
+		edges[edge.Id] = ie
+	}
+
+	ei.flat[edge.Id] = ie
+}
+
+func (ei *EdgeIndex) Remove(id S2EdgeKey) {
+	ie, ok := ei.flat[id]
+	if !ok {
+		return
+	}
+
+	for _, cell := range ie.cells {
+		delete(ei.data[cell], id)
+		if len(ei.data[cell]) == 0 {
+			delete(ei.data, cell)
+		}
+	}
+
+	delete(ei.flat, id)
+}
+
+// NearestOne returns edge closest to the given point (only edges accepted by
+// filter are considered) or nil if there is no edge within the radius
+func (ei *EdgeIndex) NearestOne(lat, lng float64, radiusMeters float64, filter func(e *S2Edge) bool) *NearestEdgeResult {
+	queryLatLng := s2.LatLngFromDegrees(lat, lng)
+	queryPoint := s2.PointFromLatLng(queryLatLng)
+
+	angle := s1.Angle(radiusMeters / utils.EARTH_RADIUS_METERS)
+	cap := s2.CapFromCenterAngle(queryPoint, angle)
+
+	rc := &s2.RegionCoverer{
+		MinLevel: ei.level,
+		MaxLevel: ei.level,
+		MaxCells: 20,
+	}
+	cellUnion := rc.Covering(cap)
+
+	var best *NearestEdgeResult
+	for _, cellID := range cellUnion {
+		edges, ok := ei.data[cellID]
+		if !ok {
+			continue
+		}
+		for _, ie := range edges {
+			if filter != nil && !filter(ie.edge) {
+				continue
+			}
+			projected := s2.LatLngFromPoint(s2.Project(queryPoint, ie.a, ie.b))
+			dist := utils.HaversineDistance(lat, lng, projected.Lat.Degrees(), projected.Lng.Degrees())
+			if dist <= radiusMeters && (best == nil || dist < best.DistanceMeters) {
+				best = &NearestEdgeResult{
+					Edge:           ie.edge,
+					Lat:            projected.Lat.Degrees(),
+					Lng:            projected.Lng.Degrees(),
+					DistanceMeters: dist,
+				}
+			}
+		}
+	}
+
+	return best
+}
+
+// cells of index level the line segment passes through
+func (ei *EdgeIndex) covering(ll1, ll2 s2.LatLng) []s2.CellID {
+
+	c1 := s2.CellIDFromLatLng(ll1).Parent(ei.level)
+	c2 := s2.CellIDFromLatLng(ll2).Parent(ei.level)
+
+	// most of the edges are short and fit into single cell
+	if c1 == c2 {
+		return []s2.CellID{c1}
+	}
+
+	rc := &s2.RegionCoverer{
+		MinLevel: ei.level,
+		MaxLevel: ei.level,
+		MaxCells: 20,
+	}
+	polyline := s2.Polyline{s2.PointFromLatLng(ll1), s2.PointFromLatLng(ll2)}
+	return rc.Covering(&polyline)
+}
+
+// Crossing returns edges which cross or touch the line segment between two
+// locations (only edges accepted by filter are considered)
+func (ei *EdgeIndex) Crossing(l1, l2 *Location, filter func(e *S2Edge) bool) []*S2Edge {
+
+	ll1 := s2.LatLngFromDegrees(l1.Lat, l1.Lng)
+	ll2 := s2.LatLngFromDegrees(l2.Lat, l2.Lng)
+	a := s2.PointFromLatLng(ll1)
+	b := s2.PointFromLatLng(ll2)
+
+	var result []*S2Edge
+	found := make(map[S2EdgeKey]bool)
+
+	for _, cellID := range ei.covering(ll1, ll2) {
+		for id, ie := range ei.data[cellID] {
+			if found[id] || (filter != nil && !filter(ie.edge)) {
+				continue
+			}
+			if s2.CrossingSign(a, b, ie.a, ie.b) != s2.DoNotCross {
+				found[id] = true
+				result = append(result, ie.edge)
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,121 @@
+package s2store
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// file formats of saved net
+const FORMAT_JSON = "json"
+const FORMAT_BINARY = "binary"
+
+// compression of saved net
+const COMPRESSION_NONE = ""
+const COMPRESSION_GZIP = "gzip"
+const COMPRESSION_ZSTD = "zstd"
+
+var gzipMagic = []byte{0x1f, 0x8b}
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// FormatFromPath guesses format and compression from file extension, e.g.
+// net.gnb.zst is binary format compressed by zstd
+func FormatFromPath(path string) (string, string) {
+
+	compression := COMPRESSION_NONE
+	switch filepath.Ext(path) {
+	case ".gz":
+		compression = COMPRESSION_GZIP
+		path = strings.TrimSuffix(path, ".gz")
+	case ".zst":
+		compression = COMPRESSION_ZSTD
+		path = strings.TrimSuffix(path, ".zst")
+	}
+
+	format := FORMAT_JSON
+	if filepath.Ext(path) == ".gnb" {
+		format = FORMAT_BINARY
+	}
+
+	return format, compression
+}
+
+// SaveFormat writes the net in given format and compression
+func (s *S2Store) SaveFormat(w io.Writer, format, compression string) error {
+
+	var cw io.WriteCloser
+	switch compression {
+	case COMPRESSION_NONE:
+	case COMPRESSION_GZIP:
+		cw = gzip.NewWriter(w)
+	case COMPRESSION_ZSTD:
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return err
+		}
+		cw = zw
+	default:
+		return fmt.Errorf("unknown compression: %s", compression)
+	}
+
+	if cw != nil {
+		w = cw
+	}
+
+	var err error
+	switch format {
+	case FORMAT_JSON:
+		err = s.Save(w)
+	case FORMAT_BINARY:
+		err = s.SaveBinary(w)
+	default:
+		err = fmt.Errorf("unknown format: %s", format)
+	}
+
+	if cw != nil {
+		if closeErr := cw.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+// LoadDetect reads the net in any supported format and compression,
+// both are detected from content
+func (s *S2Store) LoadDetect(r io.Reader) error {
+
+	br := bufio.NewReader(r)
+
+	head, err := br.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		return s.LoadDetect(gr)
+	case bytes.HasPrefix(head, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		return s.LoadDetect(zr)
+	case bytes.HasPrefix(head, []byte(BINARY_MAGIC)):
+		return s.LoadBinary(br)
+	}
+
+	return s.Load(br)
+}
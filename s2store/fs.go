@@ -1,89 +1,143 @@
 package s2store
 
 import (
-	"encoding/json"
 	"fmt"
-	"mnezerka/geonet/log"
+	"io"
 	"mnezerka/geonet/store"
-	"os"
 )
 
-type S2StoreJson struct {
-	Tracks      []*store.Track `json:"tracks"`
-	Edges       []*S2Edge      `json:"edges"`
-	Locations   []*Location    `json:"locations"`
-	LastPointId int64          `json:"last-point-id"`
-	LastTrackId int64          `json:"last-track-id"`
-}
+// version of the saved net (see store.NetWriter for the format)
+const FORMAT_VERSION = store.NET_FORMAT_VERSION
 
-func (s *S2Store) Save() {
+// Save writes the net in json format, items are encoded one by one, so the
+// net is not held in memory twice
+func (s *S2Store) Save(w io.Writer) error {
 
-	toJson := S2StoreJson{
+	nw := store.NetWriter{
 		LastPointId: s.lastPointId,
 		LastTrackId: s.lastTrackId,
-		Tracks:      make([]*store.Track, 0, len(s.tracks)),
-		Edges:       make([]*S2Edge, 0, len(s.edges)),
-		Locations:   make([]*Location, 0, len(s.index.flat)),
+		Tracks: func(yield func(any) error) error {
+			for _, t := range s.tracks {
+				if err := yield(t); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Locations: func(yield func(any) error) error {
+			for _, l := range s.index.flat {
+				if err := yield(l); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Edges: func(yield func(any) error) error {
+			for _, e := range s.edges {
+				if err := yield(e); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
 	}
 
-	for _, t := range s.tracks {
-		toJson.Tracks = append(toJson.Tracks, t)
+	if err := nw.Write(w); err != nil {
+		return err
 	}
-	s.stat.TracksRendered = int64(len(s.tracks))
 
-	for _, e := range s.edges {
-		toJson.Edges = append(toJson.Edges, e)
-	}
+	s.stat.TracksRendered = int64(len(s.tracks))
+	s.stat.PointsRendered = int64(len(s.index.flat))
 	s.stat.EdgesRendered = int64(len(s.edges))
 
-	for _, l := range s.index.flat {
-		toJson.Locations = append(toJson.Locations, l)
-	}
-	s.stat.PointsRendered = int64(len(s.index.flat))
+	return nil
+}
 
-	// meta - json
-	sJson, err := json.MarshalIndent(toJson, "", " ")
-	if err != nil {
-		log.ExitWithError(err)
-	}
+// Load reads the net saved by Save (any version), items are decoded one by
+// one
+func (s *S2Store) Load(r io.Reader) error {
 
-	fmt.Print(string(sJson))
-}
+	// older versions saved edges before locations
+	var pendingEdges []*S2Edge
 
-func (s *S2Store) Load(filePath string) {
+	nr := store.NetReader{}
+	nr.Track = func(t *store.Track) error {
+		s.tracks[t.Id] = t
+		s.stat.TracksLoaded++
+		return nil
+	}
+	nr.Location = func(decode func(v any) error) error {
+		l := NewLocation()
+		if err := decode(l); err != nil {
+			return err
+		}
+		migrateLocation(l, nr.Version)
+		s.index.Add(l)
+		s.stat.PointsLoaded++
+		return nil
+	}
+	nr.Edge = func(decode func(v any) error) error {
+		e := NewS2Edge()
+		if err := decode(e); err != nil {
+			return err
+		}
+		migrateEdge(e, nr.Version)
+		if s.index.GetLocation(e.Id.P1) == nil || s.index.GetLocation(e.Id.P2) == nil {
+			pendingEdges = append(pendingEdges, e)
+		} else {
+			s.AddEdge(e)
+		}
+		s.stat.EdgesLoaded++
+		return nil
+	}
 
-	// Read the JSON file
-	file, err := os.Open(filePath)
-	if err != nil {
-		log.ExitWithError(err)
+	if err := nr.Read(r); err != nil {
+		return err
 	}
-	defer file.Close()
 
-	fromJson := S2StoreJson{}
+	s.lastPointId = nr.LastPointId
+	s.lastTrackId = nr.LastTrackId
 
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&fromJson)
-	if err != nil {
-		log.ExitWithError(err)
+	for _, e := range pendingEdges {
+		if s.index.GetLocation(e.Id.P1) == nil || s.index.GetLocation(e.Id.P2) == nil {
+			return fmt.Errorf("invalid geonet file, missing points of edge %v", e.Id)
+		}
+		s.AddEdge(e)
 	}
 
-	for _, t := range fromJson.Tracks {
-		s.tracks[t.Id] = t
+	return nil
+}
+
+// upgrade location loaded from older version of the file
+func migrateLocation(l *Location, version int) {
+
+	if l.Tracks == nil {
+		l.Tracks = make(map[int64]bool)
 	}
-	s.stat.TracksLoaded = int64(len(fromJson.Tracks))
+	l.Edges = make(map[int64]*S2Edge)
 
-	for _, l := range fromJson.Locations {
-		s.index.Add(l)
-		l.Edges = make(map[int64]*S2Edge)
+	// version 1: centroid is not known, location is single fix at its position
+	if version < 2 && l.Weight == 0 {
+		l.CentroidLat = l.Lat
+		l.CentroidLng = l.Lng
+		l.Weight = 1
 	}
-	s.stat.PointsLoaded = int64(len(fromJson.Locations))
+}
 
-	for _, e := range fromJson.Edges {
+// upgrade edge loaded from older version of the file
+func migrateEdge(e *S2Edge, version int) {
 
-		s.AddEdge(e)
+	if e.Tracks == nil {
+		e.Tracks = make(map[int64]bool)
 	}
-	s.stat.EdgesLoaded = int64(len(fromJson.Edges))
 
-	s.lastPointId = fromJson.LastPointId
-	s.lastTrackId = fromJson.LastTrackId
+	// version 1: direction attributes could be missing or null
+	if version < 2 {
+		if e.ForwardTracks == nil {
+			e.ForwardTracks = make(map[int64]bool)
+		}
+		if e.BackwardTracks == nil {
+			e.BackwardTracks = make(map[int64]bool)
+		}
+	}
 }
@@ -0,0 +1,79 @@
+package s2store
+
+import (
+	"bytes"
+	"encoding/json"
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/tracks"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveLoad(t *testing.T) {
+
+	s := NewS2Store(&config.Cfg)
+	assert.Nil(t, s.AddGpx(tracks.NewTrack("../test_data/t1.gpx")))
+	assert.Nil(t, s.AddGpx(tracks.NewTrack("../test_data/t2.gpx")))
+
+	var buf bytes.Buffer
+	assert.Nil(t, s.Save(&buf))
+
+	// output is valid json with version
+	var raw map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &raw))
+	assert.Equal(t, float64(FORMAT_VERSION), raw["version"])
+
+	loaded := NewS2Store(&config.Cfg)
+	assert.Nil(t, loaded.Load(&buf))
+
+	assert.Equal(t, s.lastPointId, loaded.lastPointId)
+	assert.Equal(t, s.lastTrackId, loaded.lastTrackId)
+	assert.Len(t, loaded.tracks, 2)
+	assert.Len(t, loaded.index.GetLocations(), len(s.index.GetLocations()))
+	assert.Len(t, loaded.edges, len(s.edges))
+
+	for id, edge := range s.edges {
+		assert.Equal(t, edge.Tracks, loaded.edges[id].Tracks)
+		assert.Equal(t, edge.Forward, loaded.edges[id].Forward)
+	}
+
+	for id, loc := range s.index.GetLocations() {
+		assert.Equal(t, len(loc.Edges), len(loaded.index.GetLocation(id).Edges))
+	}
+}
+
+func TestLoadVersion1(t *testing.T) {
+
+	// edges before locations, no version, centroid and direction attributes
+	legacy := `{
+ "tracks": [{"id": 1, "meta": {}}],
+ "edges": [{"id": {"p1": 1, "p2": 2}, "tracks": {"1": true}}],
+ "locations": [
+  {"id": 1, "lat": 50.0, "lng": 14.0, "tracks": {"1": true}, "begin": true},
+  {"id": 2, "lat": 50.0, "lng": 14.01, "tracks": {"1": true}, "end": true}
+ ],
+ "last-point-id": 2,
+ "last-track-id": 1
+}`
+
+	s := NewS2Store(&config.Cfg)
+	assert.Nil(t, s.Load(strings.NewReader(legacy)))
+
+	assert.Len(t, s.edges, 1)
+	assert.Equal(t, int64(2), s.lastPointId)
+
+	loc := s.index.GetLocation(2)
+	assert.Equal(t, int64(1), loc.Weight)
+	assert.Equal(t, 14.01, loc.CentroidLng)
+	assert.Len(t, loc.Edges, 1)
+
+	edge := s.edges[edgeIdFromPointIds(1, 2)]
+	assert.NotNil(t, edge.ForwardTracks)
+	assert.NotNil(t, edge.BackwardTracks)
+
+	// unsupported version
+	assert.NotNil(t, s.Load(strings.NewReader(`{"version": 1000}`)))
+	assert.NotNil(t, NewS2Store(&config.Cfg).Load(strings.NewReader(`[]`)))
+}
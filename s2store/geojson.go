@@ -1,129 +1,88 @@
 package s2store
 
 import (
-	"fmt"
 	"mnezerka/geonet/log"
-	"mnezerka/geonet/utils"
-	"strings"
+	"mnezerka/geonet/store"
 
 	geojson "github.com/paulmach/go.geojson"
 )
 
-func (s *S2Store) ToGeoJson(each func(feature *geojson.Feature)) *geojson.FeatureCollection {
-
-	collection := geojson.NewFeatureCollection()
-
-	points := s.index.GetLocations()
-
-	s.stat.PointsFinal = 0
-	s.stat.EdgesFinal = 0
-
-	if s.cfg.ShowEdges {
-
-		if s.cfg.GeoJsonMergeEdges {
-
-			// reset all points to not processed state to be sure we start with clean setup
-			//s.index.SetLocationsNotProcessed()
-			s.setEdgesNotProcessed()
-
-			for {
-				path := s.getNextFreeSegment()
-				if len(path) < 2 {
-					break
-				}
-
-				log.Debugf("path for exporting: %v", pointsToIds(path))
-
-				pathCoordinates := [][]float64{}
-
-				for i := 0; i < len(path); i++ {
-					p1 := path[i]
-					pathCoordinates = append(pathCoordinates, []float64{p1.Lng, p1.Lat})
-				}
-
-				// line is complete, add metadata
-				line := geojson.NewLineStringFeature(pathCoordinates)
-				// ids of all points => 1-5-3-6-7
-
-				if len(path) < 10 {
-					line.SetProperty("id", strings.Trim(strings.Join(strings.Fields(fmt.Sprint(pointsToIds(path))), "-"), "[]"))
-				} else {
-					line.SetProperty("id", fmt.Sprintf("%d-%d", path[0].Id, path[len(path)-1].Id))
-				}
-
-				// take tracks from first edge of the path as:
-				// - bounding points (begin, end) could be part of more tracks => incorrect set of tracks for our case
-				// - path could be 2 points long - just one edge
-				edge := s.getEdgeById(edgeIdFromPointIds(path[0].Id, path[1].Id))
-				if edge == nil {
-					log.Exitf("cannot find first edge of path %v", pointsToIds(path))
-				}
-				line.SetProperty("tracks", utils.MapKeys(edge.Tracks))
-
-				// TODO: line.SetProperty("count", edge.Count)
-
-				if each != nil {
-					each(line)
-				}
-
-				collection.AddFeature(line)
-
-				s.stat.SegmentsRendered++
-			}
-		} else {
-			for _, edge := range s.edges {
+// net in the form shared by all stores
+func (s *S2Store) toNet() (map[int64]*store.NetPoint, []*store.NetEdge) {
+
+	points := map[int64]*store.NetPoint{}
+	for _, loc := range s.index.GetLocations() {
+		points[loc.Id] = &store.NetPoint{
+			Id:          loc.Id,
+			Lat:         loc.Lat,
+			Lng:         loc.Lng,
+			CentroidLat: loc.CentroidLat,
+			CentroidLng: loc.CentroidLng,
+			Weight:      loc.Weight,
+			Tracks:      loc.Tracks,
+			Count:       loc.Count,
+			FirstTime:   loc.FirstTime,
+			LastTime:    loc.LastTime,
+			Begin:       loc.Begin,
+			End:         loc.End,
+			Crossing:    loc.Crossing,
+		}
+	}
 
-				p1, p1ok := points[edge.Id.P1]
-				p2, p2ok := points[edge.Id.P2]
+	edges := make([]*store.NetEdge, 0, len(s.edges))
+	for _, edge := range s.edges {
+		edges = append(edges, &store.NetEdge{
+			Id:        store.NetEdgeId{P1: edge.Id.P1, P2: edge.Id.P2},
+			Tracks:    edge.Tracks,
+			Count:     edge.Count,
+			FirstTime: edge.FirstTime,
+			LastTime:  edge.LastTime,
+			Forward:   edge.Forward,
+			Backward:  edge.Backward,
+		})
+	}
 
-				// of some of the points were not found -> inconsistent data
-				if !p1ok || !p2ok {
-					log.Exitf("inconsistent data, some edge points where not found %d %d", edge.Id.P1, edge.Id.P2)
-				}
+	return points, edges
+}
 
-				edgeCoordinates := [][]float64{
-					{p1.Lng, p1.Lat},
-					{p2.Lng, p2.Lat},
-				}
+// smoothed geometry of exported segments
+func (s *S2Store) smoothGeometry(path []int64) ([][]float64, error) {
+	locations := make([]*Location, len(path))
+	for i, id := range path {
+		locations[i] = s.index.GetLocation(id)
+	}
+	return s.smoothPath(locations)
+}
 
-				line := geojson.NewLineStringFeature(edgeCoordinates)
-				line.SetProperty("id", edgeIdToString(edge.Id))
-				line.SetProperty("tracks", utils.MapKeys(edge.Tracks))
-				// TODO: line.SetProperty("count", edge.Count)
+func (s *S2Store) ToGeoJson(each func(feature *geojson.Feature)) *geojson.FeatureCollection {
 
-				if each != nil {
-					each(line)
-				}
+	points, edges := s.toNet()
 
-				collection.AddFeature(line)
-				s.stat.EdgesRendered++
-			}
+	var geometry store.NetGeometry
+	if s.cfg.Smooth {
+		if err := s.checkSmoothConfig(true); err != nil {
+			log.ExitWithError(err)
 		}
-
-		s.stat.EdgesFinal = int64(len(s.edges))
+		geometry = s.smoothGeometry
 	}
 
-	if s.cfg.ShowPoints {
-
-		for _, point := range points {
-
-			pnt := geojson.NewPointFeature([]float64{point.Lng, point.Lat})
-
-			pnt.SetProperty("id", point.Id)
-			pnt.SetProperty("tracks", utils.MapKeys(point.Tracks))
-			pnt.SetProperty("begin", point.Begin)
-			pnt.SetProperty("end", point.End)
-			pnt.SetProperty("crossing", point.Crossing)
-			// TODO: pnt.SetProperty("count", point.Count)
-
-			if each != nil {
-				each(pnt)
-			}
+	collection, err := store.NetToGeoJson(s.cfg, points, edges, geometry, each)
+	if err != nil {
+		log.ExitWithError(err)
+	}
 
-			collection.AddFeature(pnt)
+	s.stat.PointsFinal = int64(len(points))
+	s.stat.EdgesFinal = int64(len(edges))
+
+	for _, feature := range collection.Features {
+		switch {
+		case feature.Geometry.IsPoint():
+			s.stat.PointsRendered++
+		case s.cfg.GeoJsonMergeEdges:
+			s.stat.SegmentsRendered++
+		default:
+			s.stat.EdgesRendered++
 		}
-
-		s.stat.PointsRendered = int64(len(points))
 	}
 
 	return collection
@@ -0,0 +1,177 @@
+package s2store
+
+import (
+	"cmp"
+	"fmt"
+	"mnezerka/geonet/log"
+	"mnezerka/geonet/store"
+	"mnezerka/geonet/utils"
+	"slices"
+	"strings"
+
+	"github.com/tkrajina/gpxgo/gpx"
+)
+
+// max number of track titles in name of exported segment
+const GPX_MAX_TITLES = 3
+
+// ToGpx exports each free segment of the net as gpx track, name and
+// description of the segment are built from metadata of its tracks,
+// crossings and track begin and end points are added as waypoints if
+// points are shown
+func (s *S2Store) ToGpx() *gpx.GPX {
+
+	gpxFile := &gpx.GPX{Creator: "geonet", Version: "1.1"}
+
+	s.setEdgesNotProcessed()
+
+	for {
+		path := s.getNextFreeSegment()
+		if len(path) < 2 {
+			break
+		}
+
+		// tracks are taken from first edge of the path (see ToGeoJson)
+		edge := s.getEdgeById(edgeIdFromPointIds(path[0].Id, path[1].Id))
+		if edge == nil {
+			log.Exitf("cannot find first edge of path %v", pointsToIds(path))
+		}
+		segmentTracks := s.sortedTrackRefs(edge.Tracks)
+
+		segment := gpx.GPXTrackSegment{}
+
+		if s.cfg.Smooth {
+			coordinates, err := s.smoothPath(path)
+			if err != nil {
+				log.ExitWithError(err)
+			}
+			for _, c := range coordinates {
+				segment.Points = append(segment.Points, gpx.GPXPoint{Point: gpx.Point{Latitude: c[1], Longitude: c[0]}})
+			}
+		} else {
+			for _, l := range path {
+				segment.Points = append(segment.Points, locationToGpxPoint(l))
+			}
+		}
+
+		gpxFile.Tracks = append(gpxFile.Tracks, gpx.GPXTrack{
+			Name:        gpxSegmentName(segmentTracks),
+			Description: gpxTracksDescription(segmentTracks),
+			Segments:    []gpx.GPXTrackSegment{segment},
+		})
+
+		s.stat.SegmentsRendered++
+	}
+
+	if s.cfg.ShowPoints {
+
+		points := s.index.GetLocations()
+
+		ids := utils.MapKeys(points)
+		slices.Sort(ids)
+
+		for _, id := range ids {
+			l := points[id]
+
+			var kinds []string
+			symbol := ""
+			if l.Crossing {
+				kinds = append(kinds, "crossing")
+				symbol = "Crossing"
+			}
+			if l.End {
+				kinds = append(kinds, "end")
+				symbol = "Flag, Red"
+			}
+			if l.Begin {
+				kinds = append(kinds, "begin")
+				symbol = "Flag, Green"
+			}
+
+			if len(kinds) == 0 {
+				continue
+			}
+
+			wpt := locationToGpxPoint(l)
+			wpt.Name = fmt.Sprintf("%s %d", strings.Join(kinds, ", "), l.Id)
+			wpt.Type = kinds[0]
+			wpt.Symbol = symbol
+			wpt.Description = gpxTracksDescription(s.sortedTrackRefs(l.Tracks))
+
+			gpxFile.Waypoints = append(gpxFile.Waypoints, wpt)
+		}
+	}
+
+	return gpxFile
+}
+
+func locationToGpxPoint(l *Location) gpx.GPXPoint {
+	p := gpx.GPXPoint{Point: gpx.Point{Latitude: l.Lat, Longitude: l.Lng}}
+	if l.Ele != nil {
+		p.Elevation = *gpx.NewNullableFloat64(*l.Ele)
+	}
+	return p
+}
+
+// tracks of the net ordered by id, unknown ids are skipped
+func (s *S2Store) sortedTrackRefs(trackIds map[int64]bool) []*store.Track {
+	var result []*store.Track
+	for id := range trackIds {
+		if t, exists := s.tracks[id]; exists {
+			result = append(result, t)
+		}
+	}
+	slices.SortFunc(result, func(a, b *store.Track) int { return cmp.Compare(a.Id, b.Id) })
+	return result
+}
+
+func gpxTrackTitle(t *store.Track) string {
+	if len(t.Meta.TrackTitle) > 0 {
+		return t.Meta.TrackTitle
+	}
+	return fmt.Sprintf("Track %d", t.Id)
+}
+
+// titles of first tracks => "Morning ride, Lunch ride (+3)"
+func gpxSegmentName(segmentTracks []*store.Track) string {
+	var titles []string
+	for i, t := range segmentTracks {
+		if i == GPX_MAX_TITLES {
+			titles[len(titles)-1] += fmt.Sprintf(" (+%d)", len(segmentTracks)-GPX_MAX_TITLES)
+			break
+		}
+		titles = append(titles, gpxTrackTitle(t))
+	}
+	return strings.Join(titles, ", ")
+}
+
+// one line per track: title, date, post and link
+func gpxTracksDescription(segmentTracks []*store.Track) string {
+	var lines []string
+	for _, t := range segmentTracks {
+		parts := []string{gpxTrackTitle(t)}
+		if !t.Meta.TrackDate.IsZero() {
+			parts = append(parts, t.Meta.TrackDate.Format("2006-01-02"))
+		}
+		if len(t.Meta.PostTitle) > 0 {
+			parts = append(parts, t.Meta.PostTitle)
+		}
+		if len(t.Meta.TrackUrl) > 0 {
+			parts = append(parts, t.Meta.TrackUrl)
+		} else if len(t.Meta.PostUrl) > 0 {
+			parts = append(parts, t.Meta.PostUrl)
+		}
+		lines = append(lines, strings.Join(parts, ", "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (s *S2Store) ExportGpx() []byte {
+
+	xmlBytes, err := s.ToGpx().ToXml(gpx.ToXmlParams{Version: "1.1", Indent: true})
+	if err != nil {
+		log.ExitWithError(err)
+	}
+
+	return xmlBytes
+}
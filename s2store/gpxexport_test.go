@@ -0,0 +1,63 @@
+package s2store
+
+import (
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/tracks"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToGpx(t *testing.T) {
+
+	cfg := config.Cfg
+	cfg.ShowPoints = true
+
+	track1 := tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.01}, {50.0, 14.02}})
+	track1.Meta.TrackTitle = "Morning ride"
+	track1.Meta.TrackDate = time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	track1.Meta.TrackUrl = "https://example.com/1.gpx"
+	track2 := tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.01}, {50.01, 14.01}})
+
+	s := NewS2Store(&cfg)
+	assert.Nil(t, s.AddGpx(track1))
+	assert.Nil(t, s.AddGpx(track2))
+
+	g := s.ToGpx()
+
+	// shared segment and two branches behind the crossing
+	assert.Len(t, g.Tracks, 3)
+
+	names := map[string]string{}
+	for _, trk := range g.Tracks {
+		assert.Len(t, trk.Segments, 1)
+		assert.Len(t, trk.Segments[0].Points, 2)
+		names[trk.Name] = trk.Description
+	}
+	assert.Equal(t, "Morning ride, 2024-05-01, https://example.com/1.gpx\nTrack 2", names["Morning ride, Track 2"])
+	assert.Contains(t, names, "Morning ride")
+	assert.Contains(t, names, "Track 2")
+
+	// begin of both tracks, crossing and two ends
+	assert.Len(t, g.Waypoints, 4)
+	assert.Equal(t, "begin 1", g.Waypoints[0].Name)
+	assert.Equal(t, "Flag, Green", g.Waypoints[0].Symbol)
+	assert.Equal(t, "crossing 2", g.Waypoints[1].Name)
+	assert.Equal(t, "end 3", g.Waypoints[2].Name)
+
+	// waypoints are optional
+	cfg.ShowPoints = false
+	assert.Empty(t, s.ToGpx().Waypoints)
+}
+
+func TestGpxSegmentName(t *testing.T) {
+	s := NewS2Store(&config.Cfg)
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.01}})))
+	}
+
+	g := s.ToGpx()
+	assert.Len(t, g.Tracks, 1)
+	assert.Equal(t, "Track 1, Track 2, Track 3 (+2)", g.Tracks[0].Name)
+}
@@ -1,19 +1,25 @@
 package s2store
 
 import (
+	"mnezerka/geonet/utils"
 	"slices"
 	"sort"
 
 	"github.com/golang/geo/s1"
 	"github.com/golang/geo/s2"
+	"github.com/tkrajina/gpxgo/gpx"
 )
 
 type Location struct {
-	Id        int64             `json:"id"`
-	Lat       float64           `json:"lat"`
-	Lng       float64           `json:"lng"`
-	Tracks    map[int64]bool    `json:"tracks"`
-	Count     int               `json:"-"`
+	Id          int64          `json:"id"`
+	Lat         float64        `json:"lat"`
+	Lng         float64        `json:"lng"`
+	CentroidLat float64        `json:"centroid_lat"` // weighted centroid of all matched gps fixes
+	CentroidLng float64        `json:"centroid_lng"`
+	Weight      int64          `json:"weight"`        // number of gps fixes in centroid
+	Ele         *float64       `json:"ele,omitempty"` // elevation of the first fix with known elevation
+	Tracks      map[int64]bool `json:"tracks"`
+	Usage
 	Begin     bool              `json:"begin"`
 	End       bool              `json:"end"`
 	Crossing  bool              `json:"crossing"`
@@ -35,6 +41,35 @@ func NewLocation() *Location {
 	return l
 }
 
+// add gps fix to the centroid of the location
+func (l *Location) AddFix(lat, lng float64) {
+	l.mergeCentroid(lat, lng, 1)
+}
+
+// set elevation if not known yet
+func (l *Location) addElevation(ele gpx.NullableFloat64) {
+	if l.Ele == nil && ele.NotNull() {
+		value := ele.Value()
+		l.Ele = &value
+	}
+}
+
+func (l *Location) mergeCentroid(lat, lng float64, weight int64) {
+
+	// location without centroid (e.g. loaded from older file) is
+	// considered to be a single fix at its current position
+	if l.Weight == 0 {
+		l.CentroidLat = l.Lat
+		l.CentroidLng = l.Lng
+		l.Weight = 1
+	}
+
+	total := l.Weight + weight
+	l.CentroidLat = (l.CentroidLat*float64(l.Weight) + lat*float64(weight)) / float64(total)
+	l.CentroidLng = (l.CentroidLng*float64(l.Weight) + lng*float64(weight)) / float64(total)
+	l.Weight = total
+}
+
 type SpatialIndex struct {
 	data  map[s2.CellID][]*Location
 	level int // S2 cell level for indexing
@@ -60,7 +95,7 @@ func (si *SpatialIndex) Nearest(lat, lng float64, radiusMeters float64) []Neares
 	queryPoint := s2.PointFromLatLng(queryLatLng)
 
 	// Convert radius (meters) to angle (radians)
-	angle := s1.Angle(radiusMeters / 6371000) // Earth's radius in meters
+	angle := s1.Angle(radiusMeters / utils.EARTH_RADIUS_METERS)
 
 	// Create a spherical cap (disc on the globe)
 	cap := s2.CapFromCenterAngle(queryPoint, angle)
@@ -77,7 +112,7 @@ func (si *SpatialIndex) Nearest(lat, lng float64, radiusMeters float64) []Neares
 	for _, cellID := range cellUnion {
 		if locs, ok := si.data[cellID]; ok {
 			for _, loc := range locs {
-				dist := haversineDistance(lat, lng, loc.Lat, loc.Lng)
+				dist := utils.HaversineDistance(lat, lng, loc.Lat, loc.Lng)
 				if dist <= radiusMeters {
 					results = append(results, NearestResult{
 						Location:       loc,
@@ -99,7 +134,7 @@ func (si *SpatialIndex) NearestOne(lat, lng float64, radiusMeters float64) *Near
 	queryLatLng := s2.LatLngFromDegrees(lat, lng)
 	queryPoint := s2.PointFromLatLng(queryLatLng)
 
-	angle := s1.Angle(radiusMeters / 6371000)
+	angle := s1.Angle(radiusMeters / utils.EARTH_RADIUS_METERS)
 	cap := s2.CapFromCenterAngle(queryPoint, angle)
 
 	rc := &s2.RegionCoverer{
@@ -113,7 +148,7 @@ func (si *SpatialIndex) NearestOne(lat, lng float64, radiusMeters float64) *Near
 	for _, cellID := range cellUnion {
 		if locs, ok := si.data[cellID]; ok {
 			for _, loc := range locs {
-				dist := haversineDistance(lat, lng, loc.Lat, loc.Lng)
+				dist := utils.HaversineDistance(lat, lng, loc.Lat, loc.Lng)
 				if dist <= radiusMeters && (best == nil || dist < best.DistanceMeters) {
 					best = &NearestResult{
 						Location:       loc,
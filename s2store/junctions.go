@@ -0,0 +1,110 @@
+package s2store
+
+import (
+	"mnezerka/geonet/log"
+	"mnezerka/geonet/utils"
+	"sort"
+)
+
+// CollapseJunctions merges clusters of crossing locations into single
+// junction. Matching of tracks meeting at intersection often produces
+// several crossings joined by tiny edges:
+//
+//	before:        |                after:      |
+//	               2                            |
+//	 -------- 1 ---+                  ----------J----------
+//	               3 ---------                  |
+//	               |                            |
+//
+// Cluster is formed by locations reachable from the crossing with the
+// most edges without leaving given radius, it must contain at least two
+// crossings. Junction is placed to the weighted centre of the cluster.
+// Returns number of collapsed clusters.
+func (s *S2Store) CollapseJunctions(radiusMeters float64) int {
+
+	crossings := s.index.GetLocationsFiltered(func(l *Location) bool { return l.Crossing })
+
+	// the most important crossings absorb the others
+	sort.Slice(crossings, func(i, j int) bool {
+		if len(crossings[i].Edges) != len(crossings[j].Edges) {
+			return len(crossings[i].Edges) > len(crossings[j].Edges)
+		}
+		if crossings[i].Weight != crossings[j].Weight {
+			return crossings[i].Weight > crossings[j].Weight
+		}
+		return crossings[i].Id < crossings[j].Id
+	})
+
+	collapsed := 0
+
+	for _, seed := range crossings {
+
+		// crossing could be already merged into another one
+		if s.index.GetLocation(seed.Id) == nil || !seed.Crossing {
+			continue
+		}
+
+		cluster := s.junctionCluster(seed, radiusMeters)
+		if cluster == nil {
+			continue
+		}
+
+		log.Debugf("collapsing junction %v", pointsToIds(cluster))
+
+		var lat, lng, weight float64
+		for _, loc := range cluster {
+			w := float64(max(loc.Weight, 1))
+			lat += loc.Lat * w
+			lng += loc.Lng * w
+			weight += w
+		}
+
+		for _, loc := range cluster[1:] {
+			s.mergeLocations(seed, loc)
+		}
+
+		s.moveLocation(seed, lat/weight, lng/weight)
+
+		collapsed++
+	}
+
+	return collapsed
+}
+
+// locations reachable from seed by edges within radius, seed is the first
+// one, nil if there is no other crossing in the cluster
+func (s *S2Store) junctionCluster(seed *Location, radiusMeters float64) []*Location {
+
+	cluster := []*Location{seed}
+	visited := map[int64]bool{seed.Id: true}
+	crossings := 1
+
+	for i := 0; i < len(cluster); i++ {
+		for neighbourId := range cluster[i].Edges {
+			if visited[neighbourId] {
+				continue
+			}
+			visited[neighbourId] = true
+
+			neighbour := s.index.GetLocation(neighbourId)
+			if neighbour == nil {
+				log.Exitf("inconsistent data, location %d not found", neighbourId)
+			}
+
+			if utils.HaversineDistance(seed.Lat, seed.Lng, neighbour.Lat, neighbour.Lng) > radiusMeters {
+				continue
+			}
+
+			cluster = append(cluster, neighbour)
+			if neighbour.Crossing {
+				crossings++
+			}
+		}
+	}
+
+	if crossings < 2 {
+		return nil
+	}
+
+	return cluster
+}
@@ -0,0 +1,47 @@
+package s2store
+
+import (
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/tracks"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollapseJunctions(t *testing.T) {
+
+	cfg := config.Cfg
+	cfg.MatchMaxDistance = 5
+
+	s := NewS2Store(&cfg)
+
+	//              4
+	//              |
+	// 1 ---------- 2-5 ---------- 3
+	//                |
+	//                6
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.01}, {50.0, 14.02}})))
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.005, 14.0101}, {50.0, 14.0101}, {49.995, 14.0101}})))
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.01}, {50.0, 14.0101}, {49.995, 14.0101}})))
+
+	assert.Len(t, s.index.GetLocations(), 6)
+	assert.True(t, s.index.GetLocation(2).Crossing)
+	assert.True(t, s.index.GetLocation(5).Crossing)
+
+	// crossings are too far
+	assert.Equal(t, 0, s.CollapseJunctions(5))
+
+	assert.Equal(t, 1, s.CollapseJunctions(20))
+	assert.Len(t, s.index.GetLocations(), 5)
+	assert.Len(t, s.edges, 4)
+
+	junction := s.index.GetLocation(2)
+	assert.True(t, junction.Crossing)
+	assert.Len(t, junction.Edges, 4)
+	assert.Equal(t, map[int64]bool{1: true, 2: true, 3: true}, junction.Tracks)
+	assert.InDelta(t, 14.01005, junction.Lng, 0.00001)
+
+	// junction is boundary of segments
+	assert.Nil(t, s.Simplify())
+	assert.Len(t, s.edges, 4)
+}
@@ -0,0 +1,84 @@
+package s2store
+
+import (
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/tracks"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// create track from list of [lat, lng] pairs
+func TestMatchPointsOnly(t *testing.T) {
+
+	cfg := config.Cfg
+
+	s := NewS2Store(&cfg)
+
+	// long edge with distant vertices (~1km)
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.014}})))
+
+	// track following the edge 10m aside
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0001, 14.002}, {50.0001, 14.006}, {50.0001, 14.010}})))
+
+	// no match -> parallel line
+	assert.Len(t, s.index.GetLocations(), 5)
+	assert.Len(t, s.GetEdgesFiltered(nil), 3)
+}
+
+func TestMatchEdges(t *testing.T) {
+
+	cfg := config.Cfg
+	cfg.MatchEdges = true
+
+	s := NewS2Store(&cfg)
+
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.014}})))
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0001, 14.002}, {50.0001, 14.006}, {50.0001, 14.010}})))
+
+	// each point of second track splits original edge
+	assert.Len(t, s.index.GetLocations(), 5)
+	edges := s.GetEdgesFiltered(nil)
+	assert.Len(t, edges, 4)
+
+	assert.Equal(t, map[int64]bool{1: true}, s.edges[S2EdgeKey{1, 3}].Tracks)
+	assert.Equal(t, map[int64]bool{1: true, 2: true}, s.edges[S2EdgeKey{3, 4}].Tracks)
+	assert.Equal(t, map[int64]bool{1: true, 2: true}, s.edges[S2EdgeKey{4, 5}].Tracks)
+	assert.Equal(t, map[int64]bool{1: true}, s.edges[S2EdgeKey{2, 5}].Tracks)
+
+	// projected points lie on the original line
+	for _, id := range []int64{3, 4, 5} {
+		assert.InDelta(t, 50.0, s.index.GetLocation(id).Lat, 0.00001)
+	}
+}
+
+func TestMatchHeading(t *testing.T) {
+
+	trackWE := tracks.NewTrackFromPoints([][2]float64{{50.0, 14.000}, {50.0, 14.001}, {50.0, 14.002}, {50.0, 14.003}, {50.0, 14.004}})
+	trackSN := tracks.NewTrackFromPoints([][2]float64{{49.999, 14.003}, {49.9995, 14.003}, {50.00001, 14.003}, {50.0005, 14.003}, {50.001, 14.003}})
+	trackParallel := tracks.NewTrackFromPoints([][2]float64{{50.00004, 14.000}, {50.00004, 14.001}, {50.00004, 14.002}, {50.00004, 14.003}, {50.00004, 14.004}})
+
+	cfg := config.Cfg
+	cfg.MatchMaxDistance = 30
+
+	// crossing tracks share the point close to the intersection
+	s := NewS2Store(&cfg)
+	assert.Nil(t, s.AddGpx(trackWE))
+	assert.Nil(t, s.AddGpx(trackSN))
+	assert.Len(t, s.index.GetLocations(), 9)
+	assert.True(t, s.index.GetLocation(4).Crossing)
+
+	// heading check keeps crossing tracks apart
+	cfg.MatchMaxAngle = 30
+	s = NewS2Store(&cfg)
+	assert.Nil(t, s.AddGpx(trackWE))
+	assert.Nil(t, s.AddGpx(trackSN))
+	assert.Len(t, s.index.GetLocations(), 10)
+	assert.Len(t, s.index.GetLocationsFiltered(func(l *Location) bool { return l.Crossing }), 0)
+
+	// heading check still merges tracks moving in the same corridor
+	s = NewS2Store(&cfg)
+	assert.Nil(t, s.AddGpx(trackWE))
+	assert.Nil(t, s.AddGpx(trackParallel))
+	assert.Len(t, s.index.GetLocations(), 5)
+}
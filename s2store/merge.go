@@ -0,0 +1,116 @@
+package s2store
+
+import (
+	"mnezerka/geonet/log"
+	"mnezerka/geonet/utils"
+	"slices"
+)
+
+/*
+Merge adds other net into this one, locations of the other net are matched
+against locations of this net (within MatchMaxDistance), ids of points and
+tracks of the other net are remapped to avoid collisions:
+
+	this:   1---2---3          other: 1---2
+	                     =>               |
+	merged: 1---2---3                     3
+	            |
+	            5 (other 3 -> 5, other 1 and 2 matched to 2 and 3)
+*/
+func (s *S2Store) Merge(other *S2Store) {
+
+	lastPointId := s.lastPointId
+
+	// match before anything is added, so locations of other net are never
+	// matched against each other
+	otherIds := utils.MapKeys(other.index.GetLocations())
+	slices.Sort(otherIds)
+
+	matches := map[int64]*Location{}
+	for _, id := range otherIds {
+		loc := other.index.GetLocation(id)
+		for _, n := range s.index.Nearest(loc.Lat, loc.Lng, float64(s.cfg.MatchMaxDistance)) {
+			if n.Location.Id <= lastPointId {
+				matches[id] = n.Location
+				break
+			}
+		}
+	}
+
+	log.Infof("merging net with %d locations (%d matched) and %d tracks", len(otherIds), len(matches), len(other.tracks))
+
+	// tracks
+	trackIds := map[int64]int64{}
+	otherTrackIds := utils.MapKeys(other.tracks)
+	slices.Sort(otherTrackIds)
+	for _, id := range otherTrackIds {
+		trackIds[id] = s.GenTrackId()
+	}
+
+	remapTracks := func(tracks map[int64]bool) map[int64]bool {
+		result := make(map[int64]bool, len(tracks))
+		for id := range tracks {
+			if newId, ok := trackIds[id]; ok {
+				result[newId] = true
+			}
+		}
+		return result
+	}
+
+	remapPasses := func(passes map[int64]TrackPasses) map[int64]TrackPasses {
+		result := make(map[int64]TrackPasses, len(passes))
+		for id, p := range passes {
+			if newId, ok := trackIds[id]; ok {
+				result[newId] = p
+			}
+		}
+		return result
+	}
+
+	// locations, ids are assigned in ascending order, so order of edge
+	// points is kept
+	pointIds := map[int64]int64{}
+	for _, id := range otherIds {
+		loc := *other.index.GetLocation(id)
+		loc.Id = s.GenPointId()
+		loc.Tracks = remapTracks(loc.Tracks)
+		loc.Passes = remapPasses(loc.Passes)
+		loc.Edges = make(map[int64]*S2Edge)
+		pointIds[id] = loc.Id
+		s.index.Add(&loc)
+	}
+
+	for _, e := range other.edges {
+		edge := *e
+		edge.Id = S2EdgeKey{P1: pointIds[e.Id.P1], P2: pointIds[e.Id.P2]}
+		edge.Tracks = remapTracks(e.Tracks)
+		edge.ForwardTracks = remapTracks(e.ForwardTracks)
+		edge.BackwardTracks = remapTracks(e.BackwardTracks)
+		edge.Passes = remapPasses(e.Passes)
+		s.AddEdge(&edge)
+	}
+
+	for _, id := range otherTrackIds {
+		t := *other.tracks[id]
+		t.Id = trackIds[id]
+		t.BeginPointId = pointIds[t.BeginPointId]
+		t.EndPointId = pointIds[t.EndPointId]
+		s.tracks[t.Id] = &t
+	}
+
+	// union of both nets - matched locations are merged with their edges
+	for _, id := range otherIds {
+		if keep, ok := matches[id]; ok {
+			s.mergeLocations(keep, s.index.GetLocation(pointIds[id]))
+		}
+	}
+
+	// crossings and track boundaries
+	for _, loc := range s.index.GetLocations() {
+		s.updateLocationFlags(loc)
+	}
+
+	s.stat.TracksLoaded += int64(len(otherTrackIds))
+	s.stat.PointsLoaded += int64(len(otherIds))
+	s.stat.EdgesLoaded += int64(len(other.edges))
+}
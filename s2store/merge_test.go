@@ -0,0 +1,38 @@
+package s2store
+
+import (
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/tracks"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+
+	s := NewS2Store(&config.Cfg)
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.01}, {50.0, 14.02}})))
+
+	// follows first part of the track (~11m aside) and turns to north
+	other := NewS2Store(&config.Cfg)
+	assert.Nil(t, other.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0001, 14.0}, {50.0001, 14.01}, {50.01, 14.01}})))
+
+	s.Merge(other)
+
+	assert.Equal(t, int64(6), s.lastPointId)
+	assert.Equal(t, int64(2), s.lastTrackId)
+	assert.Len(t, s.index.GetLocations(), 4)
+	assert.Len(t, s.edges, 3)
+
+	assert.Equal(t, map[int64]bool{1: true, 2: true}, s.getEdgeById(S2EdgeKey{1, 2}).Tracks)
+	assert.Equal(t, map[int64]bool{1: true}, s.getEdgeById(S2EdgeKey{2, 3}).Tracks)
+	assert.Equal(t, map[int64]bool{2: true}, s.getEdgeById(S2EdgeKey{2, 6}).Tracks)
+
+	assert.True(t, s.index.GetLocation(2).Crossing)
+	assert.Equal(t, 2, s.index.GetLocation(2).Count)
+	assert.True(t, s.index.GetLocation(1).Begin)
+	assert.True(t, s.index.GetLocation(6).End)
+
+	assert.Equal(t, int64(1), s.tracks[2].BeginPointId)
+	assert.Equal(t, int64(6), s.tracks[2].EndPointId)
+}
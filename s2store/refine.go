@@ -0,0 +1,93 @@
+package s2store
+
+import (
+	"mnezerka/geonet/log"
+	"sort"
+)
+
+const REFINE_MAX_ITERATIONS = 10
+
+// Refine moves locations to centroids of all gps fixes matched to them and
+// merges locations which get closer than matching distance. Steps are repeated
+// until there is nothing to merge, so the net converges to the centre line of
+// frequently used paths.
+func (s *S2Store) Refine() {
+	log.Debug("======================== refine =========================")
+
+	for i := 0; i < REFINE_MAX_ITERATIONS; i++ {
+		moved := s.moveLocationsToCentroids()
+		merged := s.mergeCloseLocations()
+
+		log.Debugf("refine iteration %d: %d points moved, %d points merged", i+1, moved, merged)
+
+		if merged == 0 {
+			break
+		}
+	}
+}
+
+func (s *S2Store) moveLocationsToCentroids() int {
+
+	// locations are collected first since moving modifies the index
+	locations := s.index.GetLocationsFiltered(func(l *Location) bool {
+		return l.Weight > 0 && (l.Lat != l.CentroidLat || l.Lng != l.CentroidLng)
+	})
+
+	for _, loc := range locations {
+		s.moveLocation(loc, loc.CentroidLat, loc.CentroidLng)
+	}
+
+	return len(locations)
+}
+
+func (s *S2Store) mergeCloseLocations() int {
+
+	merged := 0
+
+	// locations with more fixes absorb the lighter ones
+	locations := s.index.GetLocationsFiltered(func(l *Location) bool { return true })
+	sort.Slice(locations, func(i, j int) bool {
+		if locations[i].Weight != locations[j].Weight {
+			return locations[i].Weight > locations[j].Weight
+		}
+		return locations[i].Id < locations[j].Id
+	})
+
+	for _, loc := range locations {
+
+		// location could be already merged into another one
+		if s.index.GetLocation(loc.Id) == nil {
+			continue
+		}
+
+		for _, candidate := range s.index.Nearest(loc.Lat, loc.Lng, float64(s.cfg.MatchMaxDistance)) {
+			if candidate.Location.Id == loc.Id || !s.locationsHeadingMatch(loc, candidate.Location) {
+				continue
+			}
+			s.mergeLocations(loc, candidate.Location)
+			merged++
+		}
+	}
+
+	return merged
+}
+
+// check if locations have some edges of similar direction (if heading check is enabled)
+func (s *S2Store) locationsHeadingMatch(l1, l2 *Location) bool {
+
+	if s.cfg.MatchMaxAngle <= 0 || len(l1.Edges) == 0 {
+		return true
+	}
+
+	for neighbourId := range l1.Edges {
+		neighbour := s.index.GetLocation(neighbourId)
+		if neighbour == nil {
+			log.Exitf("inconsistent data, location %d not found", neighbourId)
+		}
+		if s.locationHeadingMatches(l2, bearingBetween(l1.Lat, l1.Lng, neighbour.Lat, neighbour.Lng)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,67 @@
+package s2store
+
+import (
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/tracks"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCentroid(t *testing.T) {
+
+	s := NewS2Store(&config.Cfg)
+
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}})))
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0002, 14.0}})))
+
+	loc := s.index.GetLocation(1)
+
+	// location stays at position of the first fix
+	assert.Equal(t, 50.0, loc.Lat)
+
+	// centroid of both fixes
+	assert.Equal(t, int64(2), loc.Weight)
+	assert.InDelta(t, 50.0001, loc.CentroidLat, 0.0000001)
+	assert.InDelta(t, 14.0, loc.CentroidLng, 0.0000001)
+}
+
+func TestRefine(t *testing.T) {
+
+	cfg := config.Cfg
+	cfg.MatchMaxDistance = 20
+
+	s := NewS2Store(&cfg)
+
+	// first track is 12m north of the path, second one 12m south (~0.000108 deg),
+	// third one is 1m south of the path and matches points of second track
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.000108, 14.000}, {50.000108, 14.001}, {50.000108, 14.002}})))
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{49.999892, 14.000}, {49.999892, 14.001}, {49.999892, 14.002}})))
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{49.999991, 14.000}, {49.999991, 14.001}, {49.999991, 14.002}})))
+
+	// first and second track are too far to be matched
+	assert.Len(t, s.index.GetLocations(), 6)
+
+	s.Refine()
+
+	// points of the second track moved closer and were merged with first track
+	assert.Len(t, s.index.GetLocations(), 3)
+	assert.Len(t, s.GetEdgesFiltered(nil), 2)
+
+	for _, loc := range s.index.GetLocations() {
+		assert.Len(t, loc.Tracks, 3)
+		assert.Equal(t, int64(3), loc.Weight)
+		// average of all three fixes
+		assert.InDelta(t, 49.999997, loc.Lat, 0.000001)
+	}
+
+	for _, edge := range s.GetEdgesFiltered(nil) {
+		assert.Len(t, edge.Tracks, 3)
+	}
+
+	// track boundaries are remapped to kept locations
+	for _, track := range s.tracks {
+		assert.NotNil(t, s.index.GetLocation(track.BeginPointId))
+		assert.NotNil(t, s.index.GetLocation(track.EndPointId))
+	}
+}
@@ -0,0 +1,139 @@
+package s2store
+
+import (
+	"fmt"
+	"mnezerka/geonet/log"
+	"mnezerka/geonet/tracks"
+)
+
+// RemoveTrack removes track from the net, edges and locations not used
+// by any other track are deleted, passes of the track are subtracted from
+// usage of the remaining ones
+func (s *S2Store) RemoveTrack(id int64) error {
+
+	if _, ok := s.tracks[id]; !ok {
+		return fmt.Errorf("track %d not found", id)
+	}
+
+	log.Debugf("removing track %d", id)
+
+	delete(s.tracks, id)
+
+	// locations which flags (begin, end, crossing) have to be recomputed
+	touched := make(map[int64]bool)
+
+	var edgeIdsToRemove []S2EdgeKey
+	for _, edge := range s.edges {
+		if !edge.Tracks[id] {
+			continue
+		}
+		delete(edge.Tracks, id)
+		delete(edge.ForwardTracks, id)
+		delete(edge.BackwardTracks, id)
+		passes := edge.removeTrack(id)
+		edge.Forward -= passes.Forward
+		edge.Backward -= passes.Count - passes.Forward
+		if len(edge.Tracks) == 0 {
+			edgeIdsToRemove = append(edgeIdsToRemove, edge.Id)
+			touched[edge.Id.P1] = true
+			touched[edge.Id.P2] = true
+		}
+	}
+
+	log.Debugf("edges to be deleted %v", edgeIdsToRemove)
+	s.removeEdgesByIds(edgeIdsToRemove)
+
+	locations := s.index.GetLocationsFiltered(func(l *Location) bool {
+		return l.Tracks[id]
+	})
+
+	var pointIdsToRemove []int64
+	for _, loc := range locations {
+		delete(loc.Tracks, id)
+		loc.removeTrack(id)
+		if len(loc.Tracks) == 0 {
+			pointIdsToRemove = append(pointIdsToRemove, loc.Id)
+		} else {
+			touched[loc.Id] = true
+		}
+	}
+
+	log.Debugf("points to be deleted %v", pointIdsToRemove)
+	s.removeLocationsByIds(pointIdsToRemove)
+
+	for locId := range touched {
+		if loc := s.index.GetLocation(locId); loc != nil {
+			s.updateLocationFlags(loc)
+		}
+	}
+
+	return nil
+}
+
+// ReplaceTrack replaces content of existing track, id of the track is kept,
+// metadata of the existing track are kept if the replacement has none (e.g.
+// plain gpx file), only length and date are taken from the new content
+func (s *S2Store) ReplaceTrack(id int64, track *tracks.Track) error {
+
+	existing, ok := s.tracks[id]
+	if !ok {
+		return fmt.Errorf("track %d not found", id)
+	}
+
+	replacement := *track
+	if !hasSourceMeta(track.Meta) {
+		replacement.Meta = existing.Meta
+		replacement.Meta.LengthKm = track.Meta.LengthKm
+		replacement.Meta.TrackDate = track.Meta.TrackDate
+	}
+
+	if err := s.RemoveTrack(id); err != nil {
+		return err
+	}
+
+	return s.addGpx(&replacement, id)
+}
+
+// check if metadata describe source of the track (title alone could be
+// derived from name of the file)
+func hasSourceMeta(meta tracks.TrackMeta) bool {
+	return meta.TrackId != "" || meta.TrackUrl != "" || meta.SourceUrl != "" ||
+		meta.SourceType != "" || meta.PostTitle != "" || meta.PostUrl != ""
+}
+
+// delete locations including all their edges
+func (s *S2Store) removeLocationsByIds(ids []int64) {
+	for _, loc := range s.index.GetLocationsByIds(ids) {
+		for neighbourId := range loc.Edges {
+			edgeId := edgeIdFromPointIds(loc.Id, neighbourId)
+			s.removeEdgeById(edgeId)
+			if neighbour := s.index.GetLocation(neighbourId); neighbour != nil {
+				s.updateLocationFlags(neighbour)
+			}
+		}
+		s.index.Remove(loc)
+	}
+}
+
+// recompute begin, end and crossing flags from current state of the net,
+// begin and end flags are kept if some track doesn't know its boundaries
+// (e.g. net saved by older version)
+func (s *S2Store) updateLocationFlags(loc *Location) {
+
+	loc.Crossing = len(loc.Edges) > 2
+
+	begin := false
+	end := false
+
+	for trackId := range loc.Tracks {
+		t, ok := s.tracks[trackId]
+		if !ok || t.BeginPointId == 0 || t.EndPointId == 0 {
+			return
+		}
+		begin = begin || t.BeginPointId == loc.Id
+		end = end || t.EndPointId == loc.Id
+	}
+
+	loc.Begin = begin
+	loc.End = end
+}
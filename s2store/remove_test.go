@@ -0,0 +1,135 @@
+package s2store
+
+import (
+	"bytes"
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/tracks"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoveTrack(t *testing.T) {
+
+	s := NewS2Store(&config.Cfg)
+
+	assert.Nil(t, s.AddGpx(tracks.NewTrack("../test_data/t1.gpx")))
+	assert.Nil(t, s.AddGpx(tracks.NewTrack("../test_data/t2.gpx")))
+
+	assert.True(t, s.index.GetLocation(4).Crossing)
+
+	assert.Nil(t, s.RemoveTrack(2))
+
+	// net is the same as built from first track only
+	assert.Len(t, s.index.GetLocations(), 7)
+	assert.Len(t, s.GetEdgesFiltered(nil), 6)
+	assert.Len(t, s.GetMeta().Tracks, 1)
+
+	for _, loc := range s.index.GetLocations() {
+		assert.Equal(t, map[int64]bool{1: true}, loc.Tracks)
+		assert.False(t, loc.Crossing)
+		assert.Equal(t, loc.Id == 1, loc.Begin)
+		assert.Equal(t, loc.Id == 7, loc.End)
+	}
+
+	// unknown track
+	assert.NotNil(t, s.RemoveTrack(2))
+}
+
+func TestReplaceTrack(t *testing.T) {
+
+	s := NewS2Store(&config.Cfg)
+
+	assert.Nil(t, s.AddGpx(tracks.NewTrack("../test_data/t1.gpx")))
+	assert.Nil(t, s.AddGpx(tracks.NewTrack("../test_data/t3.gpx")))
+
+	assert.Nil(t, s.ReplaceTrack(2, tracks.NewTrack("../test_data/t2.gpx")))
+
+	// track id is kept
+	assert.Contains(t, s.tracks, int64(2))
+	assert.Len(t, s.tracks, 2)
+
+	// structure is the same as for net built from t1 and t2
+	s2 := NewS2Store(&config.Cfg)
+	assert.Nil(t, s2.AddGpx(tracks.NewTrack("../test_data/t1.gpx")))
+	assert.Nil(t, s2.AddGpx(tracks.NewTrack("../test_data/t2.gpx")))
+
+	assert.Len(t, s.GetEdgesFiltered(nil), len(s2.GetEdgesFiltered(nil)))
+	assert.Len(t, s.index.GetLocations(), len(s2.index.GetLocations()))
+	assert.Len(t, s.index.GetLocationsFiltered(func(l *Location) bool { return l.Crossing }), 2)
+
+	// metadata are kept if replacement has none
+	s.tracks[1].Meta.PostTitle = "Morning ride"
+	plain := tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.01}})
+	plain.Meta.LengthKm = 0.7
+	assert.Nil(t, s.ReplaceTrack(1, plain))
+	assert.Equal(t, "Morning ride", s.tracks[1].Meta.PostTitle)
+	assert.Equal(t, 0.7, s.tracks[1].Meta.LengthKm)
+
+	// unknown track
+	assert.NotNil(t, s.ReplaceTrack(3, plain))
+}
+
+func TestRemoveTrackWithoutBoundaries(t *testing.T) {
+
+	s := NewS2Store(&config.Cfg)
+
+	assert.Nil(t, s.AddGpx(tracks.NewTrack("../test_data/t1.gpx")))
+	assert.Nil(t, s.AddGpx(tracks.NewTrack("../test_data/t2.gpx")))
+
+	// simulate net saved by older version
+	for _, track := range s.tracks {
+		track.BeginPointId = 0
+		track.EndPointId = 0
+	}
+
+	assert.Nil(t, s.RemoveTrack(2))
+
+	// begin and end flags are kept
+	assert.True(t, s.index.GetLocation(1).Begin)
+	assert.True(t, s.index.GetLocation(7).End)
+}
+
+func TestRemoveTrackUsage(t *testing.T) {
+
+	t1 := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	// same path twice, the second time in opposite direction
+	there := tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.002}, {50.0, 14.004}})
+	back := tracks.NewTrackFromPoints([][2]float64{{50.0, 14.004}, {50.0, 14.002}, {50.0, 14.0}})
+	for i := range there.Points {
+		there.Points[i].Timestamp = t1.Add(time.Duration(i) * time.Minute)
+		back.Points[i].Timestamp = t1.Add(time.Duration(i) * time.Hour)
+	}
+
+	s := NewS2Store(&config.Cfg)
+	assert.Nil(t, s.AddGpx(there))
+	assert.Nil(t, s.AddGpx(back))
+
+	assert.Equal(t, 2, s.index.GetLocation(1).Count)
+
+	// passes of tracks survive saving in both formats
+	var buf bytes.Buffer
+	assert.Nil(t, s.SaveBinary(&buf))
+	s = NewS2Store(&config.Cfg)
+	assert.Nil(t, s.LoadBinary(&buf))
+	assert.Nil(t, s.Save(&buf))
+	s = NewS2Store(&config.Cfg)
+	assert.Nil(t, s.Load(&buf))
+
+	assert.Nil(t, s.RemoveTrack(2))
+
+	for _, loc := range s.index.GetLocations() {
+		assert.Equal(t, 1, loc.Count)
+		assert.Equal(t, loc.FirstTime, loc.LastTime)
+		assert.Equal(t, t1.Add(time.Duration(loc.Id-1)*time.Minute), loc.FirstTime)
+	}
+
+	edge := s.getEdgeById(S2EdgeKey{1, 2})
+	assert.Equal(t, 1, edge.Count)
+	assert.Equal(t, 1, edge.Forward)
+	assert.Equal(t, 0, edge.Backward)
+	assert.Equal(t, t1.Add(time.Minute), edge.FirstTime)
+	assert.Equal(t, t1.Add(time.Minute), edge.LastTime)
+}
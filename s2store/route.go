@@ -0,0 +1,410 @@
+package s2store
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/log"
+	"mnezerka/geonet/utils"
+	"time"
+
+	"github.com/golang/geo/s2"
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/tkrajina/gpxgo/gpx"
+)
+
+// ids of virtual route nodes (start or end snapped to the middle of an edge)
+const ROUTE_START_ID = NIL_ID - 1
+const ROUTE_END_ID = NIL_ID - 2
+
+var ErrNoRoute = errors.New("no route found")
+
+// Route is result of path search in the net
+type Route struct {
+	Locations    []*Location // locations of the net traversed by the route
+	Coordinates  [][]float64 // geometry of the route ([lng, lat]) including snapped start and end
+	LengthMeters float64
+}
+
+// position snapped to the net, it is either existing location or point
+// projected to the edge
+type routeSnap struct {
+	id       int64
+	location *Location
+	edge     *S2Edge
+	lat      float64
+	lng      float64
+}
+
+// connection between route nodes, edge could be traversed only partially
+// (connection to virtual node)
+type routeLink struct {
+	to     int64
+	edge   *S2Edge
+	length float64
+	cost   float64 // length adjusted by routing profile
+}
+
+type routeItem struct {
+	id    int64
+	score float64
+}
+
+type routeQueue []routeItem
+
+func (q routeQueue) Len() int            { return len(q) }
+func (q routeQueue) Less(i, j int) bool  { return q[i].score < q[j].score }
+func (q routeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *routeQueue) Push(x interface{}) { *q = append(*q, x.(routeItem)) }
+func (q *routeQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// state of a single route search
+type router struct {
+	s         *S2Store
+	start     *routeSnap
+	end       *routeSnap
+	extra     map[int64][]routeLink // connections of virtual nodes
+	profile   *config.RouteProfile  // nil = plain distance
+	now       time.Time
+	minFactor float64 // the lowest cost factor of all edges, keeps heuristic admissible
+}
+
+// ShortestPath finds the shortest route between two positions, both positions
+// are snapped to the nearest location or edge of the net (A* algorithm)
+func (s *S2Store) ShortestPath(from, to s2.LatLng) (*Route, error) {
+	return s.ShortestPathWithProfile(from, to, nil)
+}
+
+// ShortestPathWithProfile finds the cheapest route between two positions,
+// cost of edges is computed from their length and attributes (popularity,
+// elevation, age) according to given profile
+func (s *S2Store) ShortestPathWithProfile(from, to s2.LatLng, profile *config.RouteProfile) (*Route, error) {
+
+	start, err := s.snap(from, ROUTE_START_ID)
+	if err != nil {
+		return nil, fmt.Errorf("start: %w", err)
+	}
+
+	end, err := s.snap(to, ROUTE_END_ID)
+	if err != nil {
+		return nil, fmt.Errorf("end: %w", err)
+	}
+
+	log.Debugf("routing from %d to %d", start.id, end.id)
+
+	r := router{
+		s:         s,
+		start:     start,
+		end:       end,
+		extra:     routeExtraLinks(start, end),
+		profile:   profile,
+		now:       time.Now(),
+		minFactor: 1,
+	}
+
+	if profile != nil {
+		r.minFactor = math.Inf(1)
+		for _, edge := range s.edges {
+			if factor, ok := r.factor(edge); ok {
+				r.minFactor = math.Min(r.minFactor, factor)
+			}
+		}
+		if math.IsInf(r.minFactor, 1) {
+			return nil, ErrNoRoute
+		}
+	}
+
+	return r.search()
+}
+
+func (r *router) search() (*Route, error) {
+
+	dist := map[int64]float64{r.start.id: 0}
+	prev := make(map[int64]int64)
+	done := make(map[int64]bool)
+
+	queue := &routeQueue{{id: r.start.id, score: r.heuristic(r.start.id)}}
+
+	for queue.Len() > 0 {
+		current := heap.Pop(queue).(routeItem)
+
+		if done[current.id] {
+			continue
+		}
+		done[current.id] = true
+
+		if current.id == r.end.id {
+			break
+		}
+
+		for _, link := range r.links(current.id) {
+			if done[link.to] {
+				continue
+			}
+			d := dist[current.id] + link.cost
+			if known, ok := dist[link.to]; !ok || d < known {
+				dist[link.to] = d
+				prev[link.to] = current.id
+				heap.Push(queue, routeItem{id: link.to, score: d + r.heuristic(link.to)})
+			}
+		}
+	}
+
+	if !done[r.end.id] {
+		return nil, ErrNoRoute
+	}
+
+	// reconstruct path from the end
+	ids := []int64{r.end.id}
+	for id := r.end.id; id != r.start.id; {
+		id = prev[id]
+		ids = append([]int64{id}, ids...)
+	}
+
+	route := &Route{}
+
+	for i, id := range ids {
+		lat, lng := r.position(id)
+		route.Coordinates = append(route.Coordinates, []float64{lng, lat})
+		if id >= 0 {
+			route.Locations = append(route.Locations, r.s.index.GetLocation(id))
+		}
+		if i > 0 {
+			prevLat, prevLng := r.position(ids[i-1])
+			route.LengthMeters += utils.HaversineDistance(prevLat, prevLng, lat, lng)
+		}
+	}
+
+	return route, nil
+}
+
+// position of the route node (location or virtual node)
+func (r *router) position(id int64) (float64, float64) {
+	switch id {
+	case r.start.id:
+		return r.start.lat, r.start.lng
+	case r.end.id:
+		return r.end.lat, r.end.lng
+	}
+	loc := r.s.index.GetLocation(id)
+	if loc == nil {
+		log.Exitf("inconsistent data, location %d not found", id)
+	}
+	return loc.Lat, loc.Lng
+}
+
+func (r *router) heuristic(id int64) float64 {
+	lat, lng := r.position(id)
+	return utils.HaversineDistance(lat, lng, r.end.lat, r.end.lng) * r.minFactor
+}
+
+// elevation of the route node, elevation of virtual node is interpolated
+// from corners of its edge
+func (r *router) elevation(id int64) *float64 {
+
+	var snap *routeSnap
+	switch id {
+	case r.start.id:
+		snap = r.start
+	case r.end.id:
+		snap = r.end
+	default:
+		if loc := r.s.index.GetLocation(id); loc != nil {
+			return loc.Ele
+		}
+		return nil
+	}
+
+	if snap.location != nil {
+		return snap.location.Ele
+	}
+
+	l1 := r.s.index.GetLocation(snap.edge.Id.P1)
+	l2 := r.s.index.GetLocation(snap.edge.Id.P2)
+	if l1 == nil || l2 == nil || l1.Ele == nil || l2.Ele == nil {
+		return nil
+	}
+
+	d1 := utils.HaversineDistance(l1.Lat, l1.Lng, snap.lat, snap.lng)
+	d2 := utils.HaversineDistance(l2.Lat, l2.Lng, snap.lat, snap.lng)
+	if d1+d2 == 0 {
+		return l1.Ele
+	}
+
+	ele := *l1.Ele + (*l2.Ele-*l1.Ele)*d1/(d1+d2)
+	return &ele
+}
+
+// cost factor of the edge given by profile, false if edge is excluded
+func (r *router) factor(edge *S2Edge) (float64, bool) {
+
+	if r.profile == nil {
+		return 1, true
+	}
+
+	factor := 1.0
+
+	if r.profile.PopularityWeight != 0 {
+		factor /= math.Max(1+r.profile.PopularityWeight*math.Log1p(float64(edge.Count)), 0.1)
+	}
+
+	if r.profile.TracksWeight != 0 {
+		factor /= math.Max(1+r.profile.TracksWeight*math.Log1p(float64(len(edge.Tracks))), 0.1)
+	}
+
+	// edges with unknown date of the last pass are not penalized
+	if last := r.lastRidden(edge); !last.IsZero() {
+		age := r.now.Sub(last)
+		if r.profile.MaxAgeDays > 0 && age > time.Duration(r.profile.MaxAgeDays)*24*time.Hour {
+			return 0, false
+		}
+		if r.profile.AgePenalty != 0 && age > 0 {
+			factor *= math.Max(1+r.profile.AgePenalty*age.Hours()/(24*365), 0.1)
+		}
+	}
+
+	return factor, true
+}
+
+// time of the last pass, date of the newest track is used for nets
+// without usage statistics
+func (r *router) lastRidden(edge *S2Edge) time.Time {
+
+	if !edge.LastTime.IsZero() {
+		return edge.LastTime
+	}
+
+	var last time.Time
+	for trackId := range edge.Tracks {
+		if t, ok := r.s.tracks[trackId]; ok && t.Meta.TrackDate.After(last) {
+			last = t.Meta.TrackDate
+		}
+	}
+
+	return last
+}
+
+func (r *router) links(id int64) []routeLink {
+
+	var links []routeLink
+
+	if loc := r.s.index.GetLocation(id); loc != nil {
+		for neighbourId, edge := range loc.Edges {
+			links = append(links, routeLink{to: neighbourId, edge: edge})
+		}
+	}
+
+	links = append(links, r.extra[id]...)
+
+	lat1, lng1 := r.position(id)
+	ele1 := r.elevation(id)
+
+	result := links[:0]
+	for _, link := range links {
+		factor, ok := r.factor(link.edge)
+		if !ok {
+			continue
+		}
+
+		lat2, lng2 := r.position(link.to)
+		link.length = utils.HaversineDistance(lat1, lng1, lat2, lng2)
+		link.cost = link.length * factor
+
+		if r.profile != nil && r.profile.ClimbPenalty > 0 {
+			if ele2 := r.elevation(link.to); ele1 != nil && ele2 != nil && *ele2 > *ele1 {
+				link.cost += r.profile.ClimbPenalty * (*ele2 - *ele1)
+			}
+		}
+
+		result = append(result, link)
+	}
+
+	return result
+}
+
+func (s *S2Store) snap(ll s2.LatLng, virtualId int64) (*routeSnap, error) {
+
+	lat := ll.Lat.Degrees()
+	lng := ll.Lng.Degrees()
+	radius := float64(s.cfg.RouteSnapDistance)
+
+	nearestLoc := s.index.NearestOne(lat, lng, radius)
+	nearestEdge := s.edgeIndex.NearestOne(lat, lng, radius, nil)
+
+	// prefer location if edge projection is not closer (e.g. projected to edge corner)
+	if nearestLoc != nil && (nearestEdge == nil || nearestLoc.DistanceMeters <= nearestEdge.DistanceMeters) {
+		return &routeSnap{
+			id:       nearestLoc.Location.Id,
+			location: nearestLoc.Location,
+			lat:      nearestLoc.Location.Lat,
+			lng:      nearestLoc.Location.Lng,
+		}, nil
+	}
+
+	if nearestEdge != nil {
+		return &routeSnap{
+			id:   virtualId,
+			edge: nearestEdge.Edge,
+			lat:  nearestEdge.Lat,
+			lng:  nearestEdge.Lng,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no point or edge within %.0fm from %f,%f", radius, lat, lng)
+}
+
+// connections of virtual nodes (start or end snapped to edge) to the net
+func routeExtraLinks(start, end *routeSnap) map[int64][]routeLink {
+
+	extra := make(map[int64][]routeLink)
+
+	if start.edge != nil {
+		for _, cornerId := range []int64{start.edge.Id.P1, start.edge.Id.P2} {
+			extra[start.id] = append(extra[start.id], routeLink{to: cornerId, edge: start.edge})
+		}
+	}
+
+	if end.edge != nil {
+		for _, cornerId := range []int64{end.edge.Id.P1, end.edge.Id.P2} {
+			extra[cornerId] = append(extra[cornerId], routeLink{to: end.id, edge: end.edge})
+		}
+	}
+
+	// start and end on the same edge
+	if start.edge != nil && start.edge == end.edge {
+		extra[start.id] = append(extra[start.id], routeLink{to: end.id, edge: start.edge})
+	}
+
+	return extra
+}
+
+// ToGeoJson converts route to line feature
+func (r *Route) ToGeoJson() *geojson.FeatureCollection {
+	collection := geojson.NewFeatureCollection()
+	line := geojson.NewLineStringFeature(r.Coordinates)
+	line.SetProperty("length_km", r.LengthMeters/1000)
+	line.SetProperty("points", pointsToIds(r.Locations))
+	collection.AddFeature(line)
+	return collection
+}
+
+// ToGpx converts route to single gpx track
+func (r *Route) ToGpx() *gpx.GPX {
+	gpxFile := gpx.GPX{}
+	for _, c := range r.Coordinates {
+		gpxFile.AppendPoint(&gpx.GPXPoint{
+			Point: gpx.Point{
+				Latitude:  c[1],
+				Longitude: c[0],
+			},
+		})
+	}
+	return &gpxFile
+}
@@ -0,0 +1,58 @@
+package s2store
+
+import (
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/tracks"
+	"testing"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShortestPathWithProfile(t *testing.T) {
+
+	s := NewS2Store(&config.Cfg)
+
+	//       4
+	//     /   \
+	//   1 ----- 3     direct way (~1.4km) ridden once, detour (~1.6km) three times
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.01}, {50.0, 14.02}})))
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.003, 14.01}, {50.0, 14.02}})))
+	}
+
+	from := s2.LatLngFromDegrees(50.0, 14.0)
+	to := s2.LatLngFromDegrees(50.0, 14.02)
+
+	route, err := s.ShortestPathWithProfile(from, to, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, pointsToIds(route.Locations))
+
+	// popular edges are preferred
+	popular := &config.RouteProfile{PopularityWeight: 1}
+	route, err = s.ShortestPathWithProfile(from, to, popular)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1, 4, 3}, pointsToIds(route.Locations))
+
+	// climbing to the top of the detour is penalized
+	zero := 0.0
+	top := 100.0
+	for _, id := range []int64{1, 2, 3} {
+		s.index.GetLocation(id).Ele = &zero
+	}
+	s.index.GetLocation(4).Ele = &top
+	route, err = s.ShortestPathWithProfile(from, to, &config.RouteProfile{PopularityWeight: 1, ClimbPenalty: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, pointsToIds(route.Locations))
+
+	// old edges are excluded
+	s.edges[edgeIdFromPointIds(1, 4)].LastTime = time.Now().AddDate(-5, 0, 0)
+	route, err = s.ShortestPathWithProfile(from, to, &config.RouteProfile{PopularityWeight: 1, MaxAgeDays: 365})
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, pointsToIds(route.Locations))
+
+	s.edges[edgeIdFromPointIds(1, 2)].LastTime = time.Now().AddDate(-5, 0, 0)
+	_, err = s.ShortestPathWithProfile(from, to, &config.RouteProfile{MaxAgeDays: 365})
+	assert.ErrorIs(t, err, ErrNoRoute)
+}
@@ -0,0 +1,65 @@
+package s2store
+
+import (
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/tracks"
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShortestPath(t *testing.T) {
+
+	s := NewS2Store(&config.Cfg)
+
+	assert.Nil(t, s.AddGpx(tracks.NewTrack("../test_data/t1.gpx")))
+	assert.Nil(t, s.AddGpx(tracks.NewTrack("../test_data/t2.gpx")))
+
+	from := s.index.GetLocation(1)
+	to := s.index.GetLocation(8)
+
+	route, err := s.ShortestPath(s2.LatLngFromDegrees(from.Lat, from.Lng), s2.LatLngFromDegrees(to.Lat, to.Lng))
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1, 2, 3, 4, 5, 9, 8}, pointsToIds(route.Locations))
+	assert.Len(t, route.Coordinates, 7)
+	assert.Greater(t, route.LengthMeters, 0.0)
+}
+
+func TestShortestPathSnapToEdge(t *testing.T) {
+
+	s := NewS2Store(&config.Cfg)
+
+	// 1 ---------- 2 ---------- 3 (~1.4km)
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.01}, {50.0, 14.02}})))
+
+	// start and end in the middle of edges, 50m aside
+	route, err := s.ShortestPath(s2.LatLngFromDegrees(50.00045, 14.005), s2.LatLngFromDegrees(49.99955, 14.015))
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{2}, pointsToIds(route.Locations))
+	assert.Len(t, route.Coordinates, 3)
+	assert.InDelta(t, 14.005, route.Coordinates[0][0], 0.00001)
+	assert.InDelta(t, 50.0, route.Coordinates[0][1], 0.00001)
+	assert.InDelta(t, 715, route.LengthMeters, 5)
+
+	// start and end on the same edge
+	route, err = s.ShortestPath(s2.LatLngFromDegrees(50.0, 14.002), s2.LatLngFromDegrees(50.0, 14.004))
+	assert.Nil(t, err)
+	assert.Len(t, route.Locations, 0)
+	assert.Len(t, route.Coordinates, 2)
+}
+
+func TestShortestPathNoRoute(t *testing.T) {
+
+	s := NewS2Store(&config.Cfg)
+
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.01}})))
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.1, 14.0}, {50.1, 14.01}})))
+
+	_, err := s.ShortestPath(s2.LatLngFromDegrees(50.0, 14.0), s2.LatLngFromDegrees(50.1, 14.01))
+	assert.ErrorIs(t, err, ErrNoRoute)
+
+	// too far from the net
+	_, err = s.ShortestPath(s2.LatLngFromDegrees(51.0, 14.0), s2.LatLngFromDegrees(50.1, 14.01))
+	assert.NotNil(t, err)
+}
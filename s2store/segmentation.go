@@ -3,6 +3,7 @@ package s2store
 import (
 	"mnezerka/geonet/log"
 	"mnezerka/geonet/utils"
+	"slices"
 )
 
 func (s *S2Store) getNextFreeSegment() []*Location {
@@ -11,10 +12,8 @@ func (s *S2Store) getNextFreeSegment() []*Location {
 
 	path := []*Location{}
 
-	// get random free edge (free = not processed yet)
-	freeEdge := s.GetFirstEdgeFiltered(func(edge *S2Edge) bool {
-		return !edge.Processed
-	})
+	// get free edge (free = not processed yet)
+	freeEdge := s.getNextFreeEdge()
 
 	if freeEdge == nil {
 		log.Debugf("no free edges found")
@@ -137,6 +136,7 @@ func (s *S2Store) findNeighbours(p Location, tracks map[int64]bool) []int64 {
 		}
 		result = append(result, neighbourId)
 	}
+	slices.Sort(result)
 
 	return result
 }
@@ -1,17 +1,17 @@
 package s2store
 
 import (
+	"fmt"
 	"mnezerka/geonet/log"
 	"mnezerka/geonet/utils"
-	"strconv"
 
-	"github.com/mnezerka/gpxcli/gpxutils"
-	"github.com/tkrajina/gpxgo/gpx"
+	"github.com/golang/geo/s2"
 )
 
-func (s *S2Store) Simplify() {
+func (s *S2Store) Simplify() error {
 	log.Debug("======================== simplify =========================")
-	log.Debugf("minimal simplify distance: %d", s.cfg.SimplifyMinDistance)
+	log.Debugf("minimal simplify distance: %d, algorithm: %s, preserve topology: %t",
+		s.cfg.SimplifyMinDistance, s.cfg.SimplifyAlgorithm, s.cfg.SimplifyPreserveTopology)
 
 	// reset all points to not processed state to be sure we start with clean setup
 	//s.index.SetLocationsNotProcessed()
@@ -25,16 +25,24 @@ func (s *S2Store) Simplify() {
 		}
 
 		//  simplify
-		simplifiedPath := simplifyPath(path, s.cfg.SimplifyMinDistance)
-		log.Debugf("simplified path: %v", simplifiedPath)
+		simplifiedPath, err := s.simplifyPath(path)
+		if err != nil {
+			return err
+		}
+		log.Debugf("simplified path: %v", pointsToIds(simplifiedPath))
 
 		// adapt edges
-		s.adaptEdges(path, simplifiedPath)
+		err = s.adaptEdges(path, pointsToIds(simplifiedPath))
+		if err != nil {
+			return err
+		}
 
 		// adapt statistics
 		s.stat.SegmentsProcessed++
 		s.stat.SegmentsSimplified++
 	}
+
+	return nil
 }
 
 func (s *S2Store) getEdgeById(id S2EdgeKey) *S2Edge {
@@ -53,46 +61,132 @@ func pointsToIds(points []*Location) []int64 {
 	return ids
 }
 
-func simplifyPath(path []*Location, minDistance int64) []int64 {
+// simplify path by configured algorithm, first and last locations are
+// always kept
+func (s *S2Store) simplifyPath(path []*Location) ([]*Location, error) {
+
+	if len(path) < 2 {
+		return nil, fmt.Errorf("path %v is too short to be simplified", pointsToIds(path))
+	}
+
+	minDistance := float64(s.cfg.SimplifyMinDistance)
+
+	var keep []bool
+	switch s.cfg.SimplifyAlgorithm {
+	case SIMPLIFY_DOUGLAS_PEUCKER, "":
+		keep = utils.DouglasPeucker(locationsToCoordinates(path), minDistance)
+	case SIMPLIFY_VISVALINGAM_WHYATT:
+		// area of right triangle with both legs of minimal distance
+		keep = visvalingamWhyatt(path, minDistance*minDistance/2)
+	default:
+		return nil, fmt.Errorf("unknown simplification algorithm: %s", s.cfg.SimplifyAlgorithm)
+	}
 
-	gpxFile := gpx.GPX{}
+	if s.cfg.SimplifyPreserveTopology {
+		s.preserveTopology(path, keep)
+	}
 
-	for i := 0; i < len(path); i++ {
-		np := gpx.GPXPoint{
-			Point: gpx.Point{
-				Latitude:  path[i].Lat,
-				Longitude: path[i].Lng,
-			},
-			Name: strconv.FormatInt(path[i].Id, 10),
+	var result []*Location
+	for i, loc := range path {
+		if keep[i] {
+			result = append(result, loc)
 		}
+	}
 
-		gpxFile.AppendPoint(&np)
+	return result, nil
+}
+
+/*
+topology preserving mode - simplified line must not cross or touch any other
+edge of the net or another part of simplified path, otherwise the location
+most distant from the conflicting line is returned back:
+
+	  2              x (other edge)          2
+	 / \             |                      / \
+	1   3 --- 4      |    ->    1 ---------/   3 --- 4
+	          \______|____ 5              (not simplified)
+*/
+func (s *S2Store) preserveTopology(path []*Location, keep []bool) {
+
+	coordinates := locationsToCoordinates(path)
+
+	// edges of the path are replaced by the simplified line
+	pathEdges := make(map[S2EdgeKey]bool)
+	for i := 1; i < len(path); i++ {
+		pathEdges[edgeIdFromPointIds(path[i-1].Id, path[i].Id)] = true
 	}
 
-	gpxFile.SimplifyTracks(float64(minDistance))
+	for {
+		var kept []int
+		for i := range keep {
+			if keep[i] {
+				kept = append(kept, i)
+			}
+		}
+
+		fixed := false
+		for k := 1; k < len(kept); k++ {
+			i, j := kept[k-1], kept[k]
+
+			// original edge is not changed
+			if j == i+1 {
+				continue
+			}
 
-	simplifiedPath, err := gpxutils.GpxFileToPoints(&gpxFile)
-	if err != nil {
-		panic(err)
+			if s.simplifiedLineConflicts(path, kept, k, pathEdges) {
+				farthest, _ := utils.FarthestFromSegment(coordinates, i, j)
+				log.Debugf("line %d-%d changes topology, keeping %d", path[i].Id, path[j].Id, path[farthest].Id)
+				keep[farthest] = true
+				fixed = true
+			}
+		}
+
+		if !fixed {
+			return
+		}
 	}
+}
 
-	var result []int64
-	for i := 0; i < len(simplifiedPath); i++ {
-		id, err := strconv.ParseInt(simplifiedPath[i].Name, 10, 64)
-		if err != nil {
-			panic(err)
+// check if k-th line of simplified path crosses or touches other edges of
+// the net or other lines of the simplified path
+func (s *S2Store) simplifiedLineConflicts(path []*Location, kept []int, k int, pathEdges map[S2EdgeKey]bool) bool {
+
+	a := path[kept[k-1]]
+	b := path[kept[k]]
+
+	// edges sharing a vertex with the line touch it by definition
+	shares := func(p1, p2 int64) bool {
+		return p1 == a.Id || p1 == b.Id || p2 == a.Id || p2 == b.Id
+	}
+
+	crossing := s.edgeIndex.Crossing(a, b, func(e *S2Edge) bool {
+		return !pathEdges[e.Id] && !shares(e.Id.P1, e.Id.P2)
+	})
+	if len(crossing) > 0 {
+		return true
+	}
+
+	a2 := locationToPoint(a)
+	b2 := locationToPoint(b)
+	for m := 1; m < len(kept); m++ {
+		c := path[kept[m-1]]
+		d := path[kept[m]]
+		if m == k || shares(c.Id, d.Id) {
+			continue
+		}
+		if s2.CrossingSign(a2, b2, locationToPoint(c), locationToPoint(d)) != s2.DoNotCross {
+			return true
 		}
-		result = append(result, id)
 	}
 
-	return result
+	return false
 }
 
 /*
 path:              1 --------2 -------- 3 -- 4 --------- 5 ----6
 simplifiedPathIds: 1 ------------------------------------------6
 */
-func (s *S2Store) adaptEdges(path []*Location, simplifiedPathIds []int64) {
+func (s *S2Store) adaptEdges(path []*Location, simplifiedPathIds []int64) error {
 	var beginId int64 = NIL_ID
 	var lastIx = 0
 
@@ -107,7 +201,7 @@ func (s *S2Store) adaptEdges(path []*Location, simplifiedPathIds []int64) {
 		log.Debugf("updating edge of simplified path %d -> %d", beginId, endId)
 
 		if path[lastIx].Id != beginId {
-			panic("path point doesn't match starting point of the edge")
+			return fmt.Errorf("path point %d doesn't match starting point %d of the edge", path[lastIx].Id, beginId)
 		}
 		var pointsToRemove []int64
 
@@ -117,7 +211,10 @@ func (s *S2Store) adaptEdges(path []*Location, simplifiedPathIds []int64) {
 
 			if path[j].Id == endId {
 				log.Debugf("match found, to be removed: %v", pointsToRemove)
-				s.adaptNetToEdge(beginId, endId, pointsToRemove)
+				err := s.adaptNetToEdge(beginId, endId, pointsToRemove)
+				if err != nil {
+					return err
+				}
 				pointsToRemove = nil // make slice empty
 				beginId = endId
 				lastIx = j
@@ -127,15 +224,27 @@ func (s *S2Store) adaptEdges(path []*Location, simplifiedPathIds []int64) {
 			}
 		}
 	}
+
+	return nil
 }
 
-func (s *S2Store) adaptNetToEdge(beginId, endId int64, toRemoveIds []int64) {
+func (s *S2Store) adaptNetToEdge(beginId, endId int64, toRemoveIds []int64) error {
 
 	log.Debugf("---------- adapt net to edge %d-%d + remove points %v -----------", beginId, endId, toRemoveIds)
 
 	if len(toRemoveIds) == 0 {
 		log.Debugf("noting to remove for begin: %d and end: %d", beginId, endId)
-		return
+		return nil
+	}
+
+	// get first edge which is planned to be removed to have source
+	// of attributes (e.g. list of tracks) to be assigned to final edge
+	firstEdgeId := edgeIdFromPointIds(beginId, toRemoveIds[0])
+	log.Debugf("first edge (to be removed) id:: %v", firstEdgeId)
+	firstEdge := s.getEdgeById(firstEdgeId)
+
+	if firstEdge == nil {
+		return fmt.Errorf("edge %v not found", firstEdgeId)
 	}
 
 	// prepare final edge (create new or reuse existing)
@@ -156,19 +265,9 @@ func (s *S2Store) adaptNetToEdge(beginId, endId int64, toRemoveIds []int64) {
 		log.Debugf("reusing existing edge, id: %v", finalEdgeId)
 	}
 
-	// get first edge which is planned to be removed to have source
-	// of attributes (e.g. list of tracks) to be assigned to final edge
-	firstEdgeId := edgeIdFromPointIds(beginId, toRemoveIds[0])
-	log.Debugf("first edge (to be removed) id:: %v", firstEdgeId)
-	firstEdge := s.getEdgeById(firstEdgeId)
-
-	if firstEdge == nil {
-		log.Exitf("edge %v not found", firstEdgeId)
-	}
-
 	// update final edge with properties of the first edge
 	log.Debugf("tracks before merge: %v %v", finalEdge.Tracks, firstEdge.Tracks)
-	utils.MapsMerge(finalEdge.Tracks, firstEdge.Tracks)
+	finalEdge.merge(firstEdge, firstEdge.isForward(beginId) != finalEdge.isForward(beginId))
 	log.Debugf("merged tracks: %v", finalEdge.Tracks)
 
 	// delete all edges one by one
@@ -179,6 +278,11 @@ func (s *S2Store) adaptNetToEdge(beginId, endId int64, toRemoveIds []int64) {
 	for i := 1; i < len(allIds); i++ {
 		edgeId := edgeIdFromPointIds(allIds[i-1], allIds[i])
 		edgeIdsToRemove = append(edgeIdsToRemove, edgeId)
+
+		// passes could be recorded with different times along the path
+		if edge := s.getEdgeById(edgeId); edge != nil {
+			finalEdge.mergeTimes(edge.Usage)
+		}
 	}
 
 	// delete redundant edges
@@ -191,4 +295,5 @@ func (s *S2Store) adaptNetToEdge(beginId, endId int64, toRemoveIds []int64) {
 	s.index.RemoveByIds(toRemoveIds)
 	s.stat.PointsSimplified += int64(len(toRemoveIds))
 
+	return nil
 }
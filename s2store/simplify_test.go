@@ -21,7 +21,7 @@ func TestSimplifySingleTrack(t *testing.T) {
 	track := tracks.NewTrack("../test_data/t1.gpx")
 	assert.Nil(t, s.AddGpx(track))
 
-	s.Simplify()
+	assert.Nil(t, s.Simplify())
 
 	locIds := utils.MapKeys(s.index.GetLocations())
 	assert.Len(t, locIds, 2)
@@ -37,3 +37,54 @@ func TestSimplifySingleTrack(t *testing.T) {
 	edges := s.GetEdgesFiltered(nil)
 	assert.Len(t, edges, 1)
 }
+
+func TestSimplifyAlgorithms(t *testing.T) {
+
+	// bump ~220m high on ~1.4km long line
+	path := []*Location{
+		{Id: 1, Lat: 50.0, Lng: 14.0},
+		{Id: 2, Lat: 50.0011, Lng: 14.003},
+		{Id: 3, Lat: 50.002, Lng: 14.005},
+		{Id: 4, Lat: 50.0, Lng: 14.01},
+	}
+
+	assert.Equal(t, []bool{true, false, true, true}, utils.DouglasPeucker(locationsToCoordinates(path), 100))
+	assert.Equal(t, []bool{true, false, false, true}, utils.DouglasPeucker(locationsToCoordinates(path), 300))
+
+	assert.Equal(t, []bool{true, false, true, true}, visvalingamWhyatt(path, 300*300/2))
+	assert.Equal(t, []bool{true, false, false, true}, visvalingamWhyatt(path, 500*500/2))
+
+	cfg := config.Cfg
+	cfg.SimplifyAlgorithm = "unknown"
+	s := NewS2Store(&cfg)
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0001, 14.003}, {50.0, 14.01}})))
+	assert.NotNil(t, s.Simplify())
+}
+
+func TestSimplifyPreserveTopology(t *testing.T) {
+
+	//         2
+	//       /   \
+	//      /  4  \
+	//     1 --|-- 3   line 1-3 would cross edge 4-5
+	//         5
+	newStore := func(preserve bool) *S2Store {
+		cfg := config.Cfg
+		cfg.SimplifyMinDistance = 300
+		cfg.SimplifyPreserveTopology = preserve
+		s := NewS2Store(&cfg)
+		assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.002, 14.005}, {50.0, 14.01}})))
+		assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.001, 14.005}, {49.999, 14.005}})))
+		return s
+	}
+
+	s := newStore(false)
+	assert.Nil(t, s.Simplify())
+	assert.Len(t, s.index.GetLocations(), 4)
+	assert.Nil(t, s.index.GetLocation(2))
+
+	s = newStore(true)
+	assert.Nil(t, s.Simplify())
+	assert.Len(t, s.index.GetLocations(), 5)
+	assert.NotNil(t, s.index.GetLocation(2))
+}
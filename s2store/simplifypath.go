@@ -0,0 +1,119 @@
+package s2store
+
+import (
+	"container/heap"
+	"math"
+	"mnezerka/geonet/utils"
+
+	"github.com/golang/geo/s2"
+)
+
+// supported simplification algorithms
+const SIMPLIFY_DOUGLAS_PEUCKER = "douglas-peucker"
+const SIMPLIFY_VISVALINGAM_WHYATT = "visvalingam-whyatt"
+
+func locationToPoint(l *Location) s2.Point {
+	return s2.PointFromLatLng(s2.LatLngFromDegrees(l.Lat, l.Lng))
+}
+
+// [lng, lat] coordinates of locations
+func locationsToCoordinates(path []*Location) [][]float64 {
+	coordinates := make([][]float64, len(path))
+	for i, l := range path {
+		coordinates[i] = []float64{l.Lng, l.Lat}
+	}
+	return coordinates
+}
+
+// area in square meters of triangle given by three locations (local
+// equirectangular projection is precise enough for small triangles)
+func triangleArea(a, b, c *Location) float64 {
+	cosLat := math.Cos(b.Lat * math.Pi / 180)
+	toMeters := math.Pi / 180 * utils.EARTH_RADIUS_METERS
+
+	ax := (a.Lng - b.Lng) * cosLat * toMeters
+	ay := (a.Lat - b.Lat) * toMeters
+	cx := (c.Lng - b.Lng) * cosLat * toMeters
+	cy := (c.Lat - b.Lat) * toMeters
+
+	return math.Abs(ax*cy-ay*cx) / 2
+}
+
+type vwItem struct {
+	ix   int
+	area float64
+}
+
+type vwQueue []vwItem
+
+func (q vwQueue) Len() int { return len(q) }
+func (q vwQueue) Less(i, j int) bool {
+	if q[i].area != q[j].area {
+		return q[i].area < q[j].area
+	}
+	return q[i].ix < q[j].ix
+}
+func (q vwQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *vwQueue) Push(x interface{}) { *q = append(*q, x.(vwItem)) }
+func (q *vwQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// Visvalingam-Whyatt simplification, locations are dropped one by one
+// (the least significant first) while area of triangle formed with their
+// neighbours is smaller than minArea (square meters), returns mask of kept
+// locations
+func visvalingamWhyatt(path []*Location, minArea float64) []bool {
+
+	keep := make([]bool, len(path))
+	for i := range keep {
+		keep[i] = true
+	}
+
+	// doubly linked list of remaining locations
+	prev := make([]int, len(path))
+	next := make([]int, len(path))
+	area := make([]float64, len(path))
+
+	queue := &vwQueue{}
+	for i := range path {
+		prev[i] = i - 1
+		next[i] = i + 1
+		if i > 0 && i < len(path)-1 {
+			area[i] = triangleArea(path[i-1], path[i], path[i+1])
+			heap.Push(queue, vwItem{ix: i, area: area[i]})
+		}
+	}
+
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(vwItem)
+
+		// outdated item (location removed or area recomputed)
+		if !keep[item.ix] || item.area != area[item.ix] {
+			continue
+		}
+
+		if item.area >= minArea {
+			break
+		}
+
+		keep[item.ix] = false
+		p, n := prev[item.ix], next[item.ix]
+		next[p] = n
+		prev[n] = p
+
+		// area of removed location is propagated to neighbours, so the
+		// order of removal is monotonic
+		for _, ix := range []int{p, n} {
+			if ix > 0 && ix < len(path)-1 {
+				area[ix] = math.Max(triangleArea(path[prev[ix]], path[ix], path[next[ix]]), item.area)
+				heap.Push(queue, vwItem{ix: ix, area: area[ix]})
+			}
+		}
+	}
+
+	return keep
+}
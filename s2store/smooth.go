@@ -0,0 +1,248 @@
+package s2store
+
+import (
+	"fmt"
+	"mnezerka/geonet/log"
+	"mnezerka/geonet/utils"
+)
+
+// supported smoothing algorithms
+const SMOOTH_CHAIKIN = "chaikin"
+const SMOOTH_CATMULL_ROM = "catmull-rom"
+
+// Chaikin's corner cutting, each iteration replaces every corner by two
+// points at 1/4 and 3/4 of adjacent lines, first and last points are kept
+func smoothChaikin(coords [][]float64, iterations int) [][]float64 {
+
+	for it := 0; it < iterations && len(coords) > 2; it++ {
+		result := [][]float64{coords[0]}
+		for i := 1; i < len(coords); i++ {
+			a := coords[i-1]
+			b := coords[i]
+			result = append(result,
+				[]float64{0.75*a[0] + 0.25*b[0], 0.75*a[1] + 0.25*b[1]},
+				[]float64{0.25*a[0] + 0.75*b[0], 0.25*a[1] + 0.75*b[1]},
+			)
+		}
+		coords = append(result, coords[len(coords)-1])
+	}
+
+	return coords
+}
+
+// Catmull-Rom spline passing through all points, each line is replaced
+// by given number of spline pieces
+func smoothCatmullRom(coords [][]float64, pieces int) [][]float64 {
+
+	if len(coords) < 3 || pieces < 2 {
+		return coords
+	}
+
+	// virtual points before the first and after the last point
+	at := func(i int) []float64 {
+		return coords[max(0, min(i, len(coords)-1))]
+	}
+
+	var result [][]float64
+	for i := 0; i < len(coords)-1; i++ {
+		p0, p1, p2, p3 := at(i-1), at(i), at(i+1), at(i+2)
+		for k := 0; k < pieces; k++ {
+			t := float64(k) / float64(pieces)
+			t2 := t * t
+			t3 := t2 * t
+			point := make([]float64, 2)
+			for d := 0; d < 2; d++ {
+				point[d] = 0.5 * (2*p1[d] +
+					(p2[d]-p0[d])*t +
+					(2*p0[d]-5*p1[d]+4*p2[d]-p3[d])*t2 +
+					(3*p1[d]-p0[d]-3*p2[d]+p3[d])*t3)
+			}
+			result = append(result, point)
+		}
+	}
+
+	return append(result, coords[len(coords)-1])
+}
+
+// check configured smoothing options, exported geometry can be smoothed only
+// for merged segments (single edge has no inner points to smooth)
+func (s *S2Store) checkSmoothConfig(export bool) error {
+	switch s.cfg.SmoothAlgorithm {
+	case SMOOTH_CHAIKIN, SMOOTH_CATMULL_ROM, "":
+	default:
+		return fmt.Errorf("unknown smoothing algorithm: %s", s.cfg.SmoothAlgorithm)
+	}
+	if export && !s.cfg.GeoJsonMergeEdges {
+		return fmt.Errorf("smoothing of exported geometry requires merged edges")
+	}
+	return nil
+}
+
+// smooth coordinates ([lng, lat]) by configured algorithm and strength
+func (s *S2Store) smoothCoordinates(coords [][]float64) ([][]float64, error) {
+	switch s.cfg.SmoothAlgorithm {
+	case SMOOTH_CHAIKIN, "":
+		return smoothChaikin(coords, s.cfg.SmoothStrength), nil
+	case SMOOTH_CATMULL_ROM:
+		// strength 1 means each line is split into two pieces
+		return smoothCatmullRom(coords, s.cfg.SmoothStrength+1), nil
+	}
+	return nil, fmt.Errorf("unknown smoothing algorithm: %s", s.cfg.SmoothAlgorithm)
+}
+
+// location has to stay in place (segment boundaries)
+func isFixedLocation(l *Location) bool {
+	return l.Crossing || l.Begin || l.End
+}
+
+// smooth geometry of the path, fixed locations (crossings, begin, end) are kept
+// in place, returns coordinates [lng, lat] of the smoothed line
+func (s *S2Store) smoothPath(path []*Location) ([][]float64, error) {
+
+	var result [][]float64
+
+	for _, piece := range splitPathAtFixed(path) {
+		coords := make([][]float64, len(piece))
+		for i, loc := range piece {
+			coords[i] = []float64{loc.Lng, loc.Lat}
+		}
+
+		smoothed, err := s.smoothCoordinates(coords)
+		if err != nil {
+			return nil, err
+		}
+
+		// the first point is shared with the previous piece
+		if len(result) > 0 {
+			smoothed = smoothed[1:]
+		}
+		result = append(result, smoothed...)
+	}
+
+	return result, nil
+}
+
+// split path to parts with fixed locations at both ends
+func splitPathAtFixed(path []*Location) [][]*Location {
+	var pieces [][]*Location
+	begin := 0
+	for i := 1; i < len(path); i++ {
+		if i == len(path)-1 || isFixedLocation(path[i]) {
+			pieces = append(pieces, path[begin:i+1])
+			begin = i
+		}
+	}
+	return pieces
+}
+
+/*
+Smooth replaces geometry of all segments of the net by smoothed lines,
+fixed locations (crossings, begin and end of tracks) are kept in place,
+other locations are replaced by new locations of the smoothed line:
+
+before:  1 ---- 2          after:   1 --- 6
+
+	\                         \
+	 3 --- 4                   7 -- 4
+*/
+func (s *S2Store) Smooth() error {
+	log.Debug("======================== smooth =========================")
+	log.Debugf("algorithm: %s, strength: %d", s.cfg.SmoothAlgorithm, s.cfg.SmoothStrength)
+
+	if err := s.checkSmoothConfig(false); err != nil {
+		return err
+	}
+
+	if s.cfg.SmoothStrength <= 0 {
+		return nil
+	}
+
+	s.setEdgesNotProcessed()
+
+	for {
+		path := s.getNextFreeSegment()
+		if len(path) < 2 {
+			break
+		}
+
+		for _, piece := range splitPathAtFixed(path) {
+			if len(piece) < 3 {
+				continue
+			}
+
+			coords := make([][]float64, len(piece))
+			for i, loc := range piece {
+				coords[i] = []float64{loc.Lng, loc.Lat}
+			}
+
+			smoothed, err := s.smoothCoordinates(coords)
+			if err != nil {
+				return err
+			}
+
+			err = s.replacePiece(piece, smoothed)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// replace inner locations of the piece by new locations placed to given
+// coordinates (the first and last coordinates match ends of the piece)
+func (s *S2Store) replacePiece(piece []*Location, coords [][]float64) error {
+
+	begin := piece[0]
+	end := piece[len(piece)-1]
+
+	// all edges of the segment share tracks, the first one is the source
+	// of attributes for new edges and locations
+	firstEdge := s.getEdgeById(edgeIdFromPointIds(begin.Id, piece[1].Id))
+	if firstEdge == nil {
+		return fmt.Errorf("edge %d-%d not found", begin.Id, piece[1].Id)
+	}
+	forward := firstEdge.isForward(begin.Id)
+
+	log.Debugf("smoothing %v to %d points", pointsToIds(piece), len(coords))
+
+	s.removeLocationsByIds(pointsToIds(piece[1 : len(piece)-1]))
+
+	prev := begin
+	for i := 1; i < len(coords); i++ {
+
+		next := end
+		if i < len(coords)-1 {
+			next = NewLocation()
+			next.Id = s.GenPointId()
+			next.Lat = coords[i][1]
+			next.Lng = coords[i][0]
+			next.CentroidLat = next.Lat
+			next.CentroidLng = next.Lng
+			next.Weight = 1
+			utils.MapsMerge(next.Tracks, firstEdge.Tracks)
+			next.Usage.merge(firstEdge.Usage)
+			s.index.Add(next)
+			s.stat.PointsCreated++
+		}
+
+		edge := NewS2Edge()
+		edge.Id = edgeIdFromPointIds(prev.Id, next.Id)
+		edge.merge(firstEdge, forward != edge.isForward(prev.Id))
+		edge.Processed = true
+		if existing := s.getEdgeById(edge.Id); existing != nil {
+			existing.merge(edge, false)
+		} else {
+			s.AddEdge(edge)
+			s.stat.EdgesCreated++
+		}
+
+		prev = next
+	}
+
+	s.updateLocationFlags(begin)
+	s.updateLocationFlags(end)
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package s2store
+
+import (
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/tracks"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSmoothChaikin(t *testing.T) {
+
+	coords := [][]float64{{0, 0}, {4, 4}, {8, 0}}
+
+	assert.Equal(t, [][]float64{{0, 0}, {1, 1}, {3, 3}, {5, 3}, {7, 1}, {8, 0}}, smoothChaikin(coords, 1))
+	assert.Len(t, smoothChaikin(coords, 2), 12)
+
+	// straight line without inner points is not changed
+	assert.Equal(t, [][]float64{{0, 0}, {8, 0}}, smoothChaikin([][]float64{{0, 0}, {8, 0}}, 3))
+}
+
+func TestSmoothCatmullRom(t *testing.T) {
+
+	coords := [][]float64{{0, 0}, {4, 4}, {8, 0}}
+
+	smoothed := smoothCatmullRom(coords, 2)
+	assert.Len(t, smoothed, 5)
+
+	// spline passes through original points
+	assert.Equal(t, coords[0], smoothed[0])
+	assert.Equal(t, coords[1], smoothed[2])
+	assert.Equal(t, coords[2], smoothed[4])
+}
+
+func TestSmoothPathKeepsCrossings(t *testing.T) {
+
+	path := []*Location{
+		{Id: 1, Lat: 0, Lng: 0, Begin: true},
+		{Id: 2, Lat: 4, Lng: 4},
+		{Id: 3, Lat: 0, Lng: 8, Crossing: true},
+		{Id: 4, Lat: 4, Lng: 12},
+		{Id: 5, Lat: 0, Lng: 16, End: true},
+	}
+
+	cfg := config.Cfg
+	cfg.SmoothStrength = 1
+	s := NewS2Store(&cfg)
+
+	coords, err := s.smoothPath(path)
+	assert.Nil(t, err)
+	assert.Len(t, coords, 11)
+	assert.Equal(t, []float64{8, 0}, coords[5])
+
+	cfg.SmoothAlgorithm = "unknown"
+	_, err = s.smoothPath(path)
+	assert.NotNil(t, err)
+}
+
+func TestSmoothNet(t *testing.T) {
+
+	cfg := config.Cfg
+	cfg.SmoothStrength = 2
+
+	s := NewS2Store(&cfg)
+
+	// 1 ---- 2
+	//         \
+	//          3 ---- 4
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.01}, {49.995, 14.015}, {49.995, 14.025}})))
+
+	assert.Nil(t, s.Smooth())
+
+	// 4 points -> 8 points -> 16 points after 2 iterations
+	assert.Len(t, s.index.GetLocations(), 16)
+	assert.Len(t, s.edges, 15)
+	assert.Nil(t, s.index.GetLocation(2))
+	assert.True(t, s.index.GetLocation(1).Begin)
+	assert.True(t, s.index.GetLocation(4).End)
+
+	for _, edge := range s.edges {
+		assert.Equal(t, map[int64]bool{1: true}, edge.Tracks)
+		assert.Equal(t, 1, edge.Forward+edge.Backward)
+	}
+
+	// net is consistent, single segment from begin to end
+	s.setEdgesNotProcessed()
+	path := s.getNextFreeSegment()
+	assert.Len(t, path, 16)
+}
+
+func TestSmoothConfig(t *testing.T) {
+
+	cfg := config.Cfg
+	cfg.SmoothStrength = 0
+	cfg.SmoothAlgorithm = "unknown"
+	s := NewS2Store(&cfg)
+
+	// algorithm is checked even if there is nothing to smooth
+	assert.NotNil(t, s.Smooth())
+
+	cfg.SmoothAlgorithm = SMOOTH_CATMULL_ROM
+	assert.Nil(t, s.Smooth())
+	assert.Nil(t, s.checkSmoothConfig(true))
+
+	// edges exported as individual lines can't be smoothed
+	cfg.GeoJsonMergeEdges = false
+	assert.Nil(t, s.checkSmoothConfig(false))
+	assert.NotNil(t, s.checkSmoothConfig(true))
+}
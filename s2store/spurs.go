@@ -0,0 +1,172 @@
+package s2store
+
+import (
+	"mnezerka/geonet/log"
+	"mnezerka/geonet/utils"
+	"slices"
+)
+
+// RemoveSpurs deletes short dead-end chains of the net, typically caused by
+// gps fix wandering few meters off the road and back:
+//
+//	                spur
+//	                 *
+//	                 |
+//	*------*---------*---------*------*
+//	               junction
+//
+// spur starts in a location with single edge which is not begin or end of
+// any track and goes through locations with two edges up to a junction.
+// Tracks of removed spur are kept in the junction and in its edges continuing
+// the spur's tracks (see spurContinuation). Steps are repeated since removal
+// of a spur could turn its junction into part of another spur.
+// Returns number of removed spurs.
+func (s *S2Store) RemoveSpurs(maxLengthMeters float64) int {
+
+	removed := 0
+
+	for {
+		count := s.removeSpursOnce(maxLengthMeters)
+		if count == 0 {
+			break
+		}
+		removed += count
+	}
+
+	return removed
+}
+
+func (s *S2Store) removeSpursOnce(maxLengthMeters float64) int {
+
+	// deterministic order of processing
+	ids := utils.MapKeys(s.index.GetLocations())
+	slices.Sort(ids)
+
+	removed := 0
+
+	for _, id := range ids {
+		loc := s.index.GetLocation(id)
+		if loc == nil || len(loc.Edges) != 1 {
+			continue
+		}
+
+		chain, junction, length := s.spurFrom(loc)
+		if junction == nil || length > maxLengthMeters {
+			continue
+		}
+
+		log.Debugf("removing spur %v (%.1fm) at %d", pointsToIds(chain), length, junction.Id)
+
+		trackIds := make(map[int64]bool)
+		for _, l := range chain {
+			for trackId := range l.Tracks {
+				trackIds[trackId] = true
+			}
+			for _, edge := range l.Edges {
+				for trackId := range edge.Tracks {
+					trackIds[trackId] = true
+				}
+			}
+		}
+
+		s.removeLocationsByIds(pointsToIds(chain))
+
+		for trackId := range trackIds {
+			junction.Tracks[trackId] = true
+		}
+		for _, edge := range s.spurContinuation(junction, trackIds) {
+			for trackId := range trackIds {
+				edge.Tracks[trackId] = true
+			}
+		}
+
+		s.updateLocationFlags(junction)
+
+		removed++
+	}
+
+	return removed
+}
+
+// edges of junction sharing the most tracks with removed spur (the road the
+// spur hangs on), both edges of pass-through junction if no edge shares any
+func (s *S2Store) spurContinuation(junction *Location, trackIds map[int64]bool) []*S2Edge {
+
+	neighbourIds := utils.MapKeys(junction.Edges)
+	slices.Sort(neighbourIds)
+
+	var result []*S2Edge
+	best := 0
+
+	for _, neighbourId := range neighbourIds {
+		edge := junction.Edges[neighbourId]
+
+		shared := 0
+		for trackId := range trackIds {
+			if edge.Tracks[trackId] {
+				shared++
+			}
+		}
+
+		switch {
+		case shared > best:
+			best = shared
+			result = []*S2Edge{edge}
+		case shared == best && best > 0:
+			result = append(result, edge)
+		}
+	}
+
+	if best == 0 && len(junction.Edges) == 2 {
+		for _, neighbourId := range neighbourIds {
+			result = append(result, junction.Edges[neighbourId])
+		}
+	}
+
+	return result
+}
+
+// walk from dead-end location to the nearest junction, junction is nil if
+// chain is not a spur (e.g. ends in another dead end or contains begin or
+// end of a track)
+func (s *S2Store) spurFrom(start *Location) ([]*Location, *Location, float64) {
+
+	var chain []*Location
+	length := 0.0
+
+	var prev *Location
+	current := start
+
+	for {
+		if current.Begin || current.End {
+			return nil, nil, 0
+		}
+
+		chain = append(chain, current)
+
+		var next *Location
+		for neighbourId := range current.Edges {
+			if prev == nil || neighbourId != prev.Id {
+				next = s.index.GetLocation(neighbourId)
+				break
+			}
+		}
+
+		if next == nil {
+			return nil, nil, 0
+		}
+
+		length += utils.HaversineDistance(current.Lat, current.Lng, next.Lat, next.Lng)
+
+		switch {
+		case len(next.Edges) > 2:
+			return chain, next, length
+		case len(next.Edges) < 2:
+			// isolated chain with two dead ends
+			return nil, nil, 0
+		}
+
+		prev = current
+		current = next
+	}
+}
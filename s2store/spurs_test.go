@@ -0,0 +1,91 @@
+package s2store
+
+import (
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/tracks"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoveSpurs(t *testing.T) {
+
+	cfg := config.Cfg
+	cfg.MatchMaxDistance = 10
+
+	s := NewS2Store(&cfg)
+
+	//              4
+	//              |
+	// 1 --- 2 ---- 3 ---- 5 ---- 6
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{
+		{50.0, 14.0},
+		{50.0, 14.005},
+		{50.0, 14.01},
+		{50.0003, 14.01},
+		{50.0, 14.01},
+		{50.0, 14.015},
+		{50.0, 14.02},
+	})))
+
+	assert.Len(t, s.index.GetLocations(), 6)
+	assert.True(t, s.index.GetLocation(3).Crossing)
+
+	// spur is ~33m long
+	assert.Equal(t, 0, s.RemoveSpurs(20))
+	assert.Len(t, s.index.GetLocations(), 6)
+
+	assert.Equal(t, 1, s.RemoveSpurs(50))
+	assert.Len(t, s.index.GetLocations(), 5)
+	assert.Len(t, s.edges, 4)
+	assert.Nil(t, s.index.GetLocation(4))
+	assert.False(t, s.index.GetLocation(3).Crossing)
+
+	// begin and end of the track are not spurs
+	assert.True(t, s.index.GetLocation(1).Begin)
+	assert.True(t, s.index.GetLocation(6).End)
+}
+
+func TestRemoveSpursAtCrossing(t *testing.T) {
+
+	cfg := config.Cfg
+	cfg.MatchMaxDistance = 10
+
+	s := NewS2Store(&cfg)
+
+	//              7
+	//              |  8
+	//              | /
+	// 1 --- 2 ---- 3 ---- 4 ---- 5
+	//              |
+	//              6
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.005}, {50.0, 14.01}, {50.0, 14.015}, {50.0, 14.02}})))
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{49.999, 14.01}, {50.0, 14.01}, {50.001, 14.01}})))
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{
+		{50.0, 14.0},
+		{50.0, 14.005},
+		{50.0, 14.01},
+		{50.0002, 14.0103},
+		{50.0, 14.01},
+		{50.0, 14.015},
+		{50.0, 14.02},
+	})))
+
+	junction := s.index.GetLocation(3)
+	assert.Len(t, junction.Edges, 5)
+
+	// spur edge knows track which is missing at the crossing
+	junction.Edges[8].Tracks[99] = true
+
+	assert.Equal(t, 1, s.RemoveSpurs(50))
+	assert.Nil(t, s.index.GetLocation(8))
+	assert.Len(t, junction.Edges, 4)
+	assert.True(t, junction.Crossing)
+	assert.True(t, junction.Tracks[99])
+
+	// tracks of the spur continue along the road, not across it
+	assert.Equal(t, map[int64]bool{1: true, 3: true, 99: true}, junction.Edges[2].Tracks)
+	assert.Equal(t, map[int64]bool{1: true, 3: true, 99: true}, junction.Edges[4].Tracks)
+	assert.Equal(t, map[int64]bool{2: true}, junction.Edges[6].Tracks)
+	assert.Equal(t, map[int64]bool{2: true}, junction.Edges[7].Tracks)
+}
@@ -5,6 +5,9 @@ import (
 	"mnezerka/geonet/log"
 	"mnezerka/geonet/store"
 	"mnezerka/geonet/tracks"
+	"mnezerka/geonet/utils"
+	"slices"
+	"time"
 )
 
 const NIL_ID = -1
@@ -14,26 +17,83 @@ type S2EdgeKey struct {
 	P2 int64 `json:"p2"`
 }
 
+// S2Edge is not directed (id is built from sorted point ids), but passes are
+// counted for each direction, forward means direction from P1 to P2
 type S2Edge struct {
-	Id        S2EdgeKey      `json:"id"`
-	Tracks    map[int64]bool `json:"tracks"`
-	Processed bool           `json:"-"`
+	Id     S2EdgeKey      `json:"id"`
+	Tracks map[int64]bool `json:"tracks"`
+	Usage
+	Forward        int            `json:"forward"`
+	Backward       int            `json:"backward"`
+	ForwardTracks  map[int64]bool `json:"forward_tracks"`
+	BackwardTracks map[int64]bool `json:"backward_tracks"`
+	Processed      bool           `json:"-"`
 }
 
 func NewS2Edge() *S2Edge {
 	e := S2Edge{}
 	e.Tracks = make(map[int64]bool)
+	e.ForwardTracks = make(map[int64]bool)
+	e.BackwardTracks = make(map[int64]bool)
 	return &e
 }
 
+// register pass of the track starting at point fromId
+func (e *S2Edge) addPass(fromId int64, trackId int64, t time.Time) {
+	e.Tracks[trackId] = true
+	e.addTraversal(trackId, t, e.isForward(fromId))
+	if e.isForward(fromId) {
+		e.Forward++
+		e.ForwardTracks[trackId] = true
+	} else {
+		e.Backward++
+		e.BackwardTracks[trackId] = true
+	}
+}
+
+// check if direction from point fromId is the forward direction of the edge
+func (e *S2Edge) isForward(fromId int64) bool {
+	return e.Id.P1 == fromId
+}
+
+// swap directions, needed if edge points are changed
+func (e *S2Edge) reverse() {
+	e.Forward, e.Backward = e.Backward, e.Forward
+	e.ForwardTracks, e.BackwardTracks = e.BackwardTracks, e.ForwardTracks
+	e.Usage = e.Usage.reversed()
+}
+
+// merge attributes of other edge (e.g. edge being removed) into this edge,
+// reversed means that forward direction of other edge is backward direction
+// of this edge
+func (e *S2Edge) merge(other *S2Edge, reversed bool) {
+	utils.MapsMerge(e.Tracks, other.Tracks)
+	if reversed {
+		e.Usage.merge(other.Usage.reversed())
+		e.Forward += other.Backward
+		e.Backward += other.Forward
+		utils.MapsMerge(e.ForwardTracks, other.BackwardTracks)
+		utils.MapsMerge(e.BackwardTracks, other.ForwardTracks)
+	} else {
+		e.Usage.merge(other.Usage)
+		e.Forward += other.Forward
+		e.Backward += other.Backward
+		utils.MapsMerge(e.ForwardTracks, other.ForwardTracks)
+		utils.MapsMerge(e.BackwardTracks, other.BackwardTracks)
+	}
+}
+
 type S2Store struct {
 	cfg         *config.Configuration
 	index       *SpatialIndex
+	edgeIndex   *EdgeIndex
 	lastPointId int64
 	lastTrackId int64
 	tracks      map[int64]*store.Track
 	edges       map[S2EdgeKey]*S2Edge
 	stat        store.Stat
+	freeEdges   []S2EdgeKey // sorted ids of edges to be segmented
+	freeEdgePos int
 }
 
 func NewS2Store(cfg *config.Configuration) *S2Store {
@@ -41,6 +101,7 @@ func NewS2Store(cfg *config.Configuration) *S2Store {
 
 	s.cfg = cfg
 	s.index = NewSpatialIndex(15)
+	s.edgeIndex = NewEdgeIndex(15)
 	s.tracks = make(map[int64]*store.Track)
 	s.edges = make(map[S2EdgeKey]*S2Edge)
 
@@ -61,14 +122,23 @@ func (s *S2Store) GetStat() store.Stat {
 	return s.stat
 }
 
+// net is kept in memory, nothing to close
+func (s *S2Store) Close() error {
+	return nil
+}
+
 func (s *S2Store) AddGpx(track *tracks.Track) error {
+	return s.addGpx(track, s.GenTrackId())
+}
+
+func (s *S2Store) addGpx(track *tracks.Track, trackId int64) error {
 	var lastPointId int64 = NIL_ID
 	var finalPointId int64 = NIL_ID
 
 	log.Debugf("adding %s to the rtree store", track.Meta.PostTitle)
 
 	s2Track := store.Track{
-		Id:   s.GenTrackId(),
+		Id:   trackId,
 		Meta: track.Meta,
 	}
 
@@ -85,7 +155,9 @@ func (s *S2Store) AddGpx(track *tracks.Track) error {
 		isBegin := i == 0
 		isEnd := i == len(track.Points)-1
 
-		nearest := s.index.NearestOne(point.Latitude, point.Longitude, float64(s.cfg.MatchMaxDistance))
+		bearing, hasBearing := trackBearing(track.Points, i)
+
+		nearest := s.findNearest(point.Latitude, point.Longitude, bearing, hasBearing)
 
 		if nearest != nil {
 			log.Debugf("reusing point %d %.1fm", nearest.Location.Id, nearest.DistanceMeters)
@@ -94,8 +166,18 @@ func (s *S2Store) AddGpx(track *tracks.Track) error {
 			nearest.Location.Begin = nearest.Location.Begin || isBegin
 			nearest.Location.End = nearest.Location.End || isEnd
 			nearest.Location.Tracks[s2Track.Id] = true
+			nearest.Location.AddFix(point.Latitude, point.Longitude)
 
 			finalPointId = nearest.Location.Id
+		} else if projected := s.projectToEdge(point.Latitude, point.Longitude, bearing, hasBearing, s2Track.Id, lastPointId); projected != nil {
+			log.Debugf("reusing point %d projected to existing edge", projected.Id)
+
+			projected.Begin = isBegin
+			projected.End = isEnd
+			projected.Tracks[s2Track.Id] = true
+			projected.AddFix(point.Latitude, point.Longitude)
+
+			finalPointId = projected.Id
 		} else {
 			finalPointId = s.GenPointId()
 
@@ -104,6 +186,9 @@ func (s *S2Store) AddGpx(track *tracks.Track) error {
 			loc.Id = finalPointId
 			loc.Lat = point.Latitude
 			loc.Lng = point.Longitude
+			loc.CentroidLat = point.Latitude
+			loc.CentroidLng = point.Longitude
+			loc.Weight = 1
 			loc.Tracks[s2Track.Id] = true
 			loc.Begin = isBegin
 			loc.End = isEnd
@@ -112,6 +197,14 @@ func (s *S2Store) AddGpx(track *tracks.Track) error {
 			finalPointId = loc.Id
 		}
 
+		finalLoc := s.index.GetLocation(finalPointId)
+		finalLoc.addElevation(point.Elevation)
+
+		// count pass through the location (repeated fixes in the same location are single pass)
+		if lastPointId != finalPointId {
+			finalLoc.addTraversal(s2Track.Id, point.Timestamp, false)
+		}
+
 		// --------------------  edge processing
 
 		// ignore self edges (in case point was reused)
@@ -126,19 +219,27 @@ func (s *S2Store) AddGpx(track *tracks.Track) error {
 			if ok {
 				log.Debugf("reusing existing edge: %v", edgeId)
 				s.stat.EdgesReused++
-				edge.Tracks[s2Track.Id] = true
+				edge.addPass(lastPointId, s2Track.Id, point.Timestamp)
 			} else {
 				log.Debugf("registering new edge: %v", edgeId)
 				s.stat.EdgesCreated++
 				edge := NewS2Edge()
 				edge.Id = edgeId
-				edge.Tracks[s2Track.Id] = true
+				edge.addPass(lastPointId, s2Track.Id, point.Timestamp)
 				s.AddEdge(edge)
 
 				// new edge => some point could become a crossing
 				s.updateCrossingForEdgePoints(edgeId)
 			}
 		}
+		// remember track boundaries (needed for recomputing begin and end flags)
+		if isBegin {
+			s2Track.BeginPointId = finalPointId
+		}
+		if isEnd {
+			s2Track.EndPointId = finalPointId
+		}
+
 		// remember current point id for next iteration (for edge construction)
 		lastPointId = finalPointId
 	}
@@ -146,6 +247,115 @@ func (s *S2Store) AddGpx(track *tracks.Track) error {
 	return nil
 }
 
+// find the closest location to be reused, locations with edges heading
+// in different direction than the track are skipped (if enabled)
+func (s *S2Store) findNearest(lat, lng float64, bearing float64, hasBearing bool) *NearestResult {
+
+	if s.cfg.MatchMaxAngle <= 0 || !hasBearing {
+		return s.index.NearestOne(lat, lng, float64(s.cfg.MatchMaxDistance))
+	}
+
+	for _, candidate := range s.index.Nearest(lat, lng, float64(s.cfg.MatchMaxDistance)) {
+		if s.locationHeadingMatches(candidate.Location, bearing) {
+			return &candidate
+		}
+		log.Debugf("point %d rejected, heading doesn't match", candidate.Location.Id)
+	}
+
+	return nil
+}
+
+// check if at least one edge of the location has similar direction as the
+// track, location without edges matches any direction
+func (s *S2Store) locationHeadingMatches(loc *Location, bearing float64) bool {
+
+	if len(loc.Edges) == 0 {
+		return true
+	}
+
+	for neighbourId := range loc.Edges {
+		if s.edgeHeadingMatches(loc.Id, neighbourId, bearing) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *S2Store) edgeHeadingMatches(p1Id, p2Id int64, bearing float64) bool {
+	p1 := s.index.GetLocation(p1Id)
+	p2 := s.index.GetLocation(p2Id)
+	if p1 == nil || p2 == nil {
+		log.Exitf("inconsistent data, missing points for edge: %d-%d", p1Id, p2Id)
+	}
+
+	return bearingDiff(bearing, bearingBetween(p1.Lat, p1.Lng, p2.Lat, p2.Lng)) <= s.cfg.MatchMaxAngle
+}
+
+// find the closest edge and split it by new location placed at position of
+// the point projected to the edge
+func (s *S2Store) projectToEdge(lat, lng float64, bearing float64, hasBearing bool, trackId, lastPointId int64) *Location {
+
+	if !s.cfg.MatchEdges {
+		return nil
+	}
+
+	checkHeading := s.cfg.MatchMaxAngle > 0 && hasBearing
+
+	nearest := s.edgeIndex.NearestOne(lat, lng, float64(s.cfg.MatchMaxDistance), func(e *S2Edge) bool {
+		// ignore edge just created by the same track (track would be split by itself)
+		if e.Tracks[trackId] && (e.Id.P1 == lastPointId || e.Id.P2 == lastPointId) {
+			return false
+		}
+		return !checkHeading || s.edgeHeadingMatches(e.Id.P1, e.Id.P2, bearing)
+	})
+
+	if nearest == nil {
+		return nil
+	}
+
+	log.Debugf("point projected to edge %v %.1fm", nearest.Edge.Id, nearest.DistanceMeters)
+
+	return s.splitEdge(nearest.Edge, nearest.Lat, nearest.Lng)
+}
+
+/*
+split edge by new location, both new edges inherit attributes of the original edge
+
+edge:    1 ------------------------ 2
+result:  1 ------------ 3 --------- 2
+*/
+func (s *S2Store) splitEdge(edge *S2Edge, lat, lng float64) *Location {
+
+	loc := NewLocation()
+	loc.Id = s.GenPointId()
+	loc.Lat = lat
+	loc.Lng = lng
+	// projected position represents tracks of the split edge
+	loc.CentroidLat = lat
+	loc.CentroidLng = lng
+	loc.Weight = 1
+	utils.MapsMerge(loc.Tracks, edge.Tracks)
+	loc.Usage.merge(edge.Usage)
+	s.index.Add(loc)
+	s.stat.PointsCreated++
+
+	log.Debugf("splitting edge %v by point %d", edge.Id, loc.Id)
+
+	s.removeEdgeById(edge.Id)
+
+	for _, pointId := range []int64{edge.Id.P1, edge.Id.P2} {
+		newEdge := NewS2Edge()
+		newEdge.Id = edgeIdFromPointIds(pointId, loc.Id)
+		// new location has the highest id, direction from the original point is forward
+		newEdge.merge(edge, !edge.isForward(pointId))
+		s.AddEdge(newEdge)
+		s.stat.EdgesCreated++
+	}
+
+	return loc
+}
+
 func (s *S2Store) AddEdge(edge *S2Edge) {
 
 	log.Debugf("add new edge %v", edge.Id)
@@ -154,18 +364,19 @@ func (s *S2Store) AddEdge(edge *S2Edge) {
 	s.edges[edge.Id] = edge
 
 	// add edge to both corner locations
-	if loc := s.index.GetLocation(edge.Id.P1); loc != nil {
-		loc.Edges[edge.Id.P2] = edge
-	} else {
+	l1 := s.index.GetLocation(edge.Id.P1)
+	if l1 == nil {
 		log.Exitf("inconsistent data, missing points for edge: %v", edge.Id)
 	}
+	l1.Edges[edge.Id.P2] = edge
 
-	if loc := s.index.GetLocation(edge.Id.P2); loc != nil {
-		loc.Edges[edge.Id.P1] = edge
-	} else {
+	l2 := s.index.GetLocation(edge.Id.P2)
+	if l2 == nil {
 		log.Exitf("inconsistent data, missing points for edge: %v", edge.Id)
 	}
+	l2.Edges[edge.Id.P1] = edge
 
+	s.edgeIndex.Add(edge, l1, l2)
 }
 
 func (s *S2Store) GetEdgesFiltered(filter func(l *S2Edge) bool) []*S2Edge {
@@ -199,6 +410,7 @@ func (s *S2Store) removeEdgeById(edgeId S2EdgeKey) {
 
 	// delete from flat list of edges
 	delete(s.edges, edgeId)
+	s.edgeIndex.Remove(edgeId)
 
 	// delete form corner points
 	l1 := s.index.GetLocation(edgeId.P1)
@@ -214,6 +426,90 @@ func (s *S2Store) removeEdgeById(edgeId S2EdgeKey) {
 	delete(l2.Edges, edgeId.P1)
 }
 
+// change position of location, spatial indexes are updated accordingly
+func (s *S2Store) moveLocation(loc *Location, lat, lng float64) {
+
+	s.index.Remove(loc)
+	loc.Lat = lat
+	loc.Lng = lng
+	s.index.Add(loc)
+
+	for neighbourId, edge := range loc.Edges {
+		neighbour := s.index.GetLocation(neighbourId)
+		if neighbour == nil {
+			log.Exitf("inconsistent data, location %d not found", neighbourId)
+		}
+		s.edgeIndex.Add(edge, loc, neighbour)
+	}
+}
+
+/*
+merge location drop into location keep, edges of dropped location are
+reconnected to the kept one (or merged with existing edges)
+
+	before:  1 ---- 2(keep) -- 3(drop) ---- 4
+	                |
+	                5
+
+	after:   1 ---- 2(keep) --------------- 4
+	                |
+	                5
+*/
+func (s *S2Store) mergeLocations(keep, drop *Location) {
+
+	log.Debugf("merging point %d into %d", drop.Id, keep.Id)
+
+	for neighbourId, edge := range drop.Edges {
+
+		s.removeEdgeById(edge.Id)
+
+		// edge between merged locations disappears
+		if neighbourId == keep.Id {
+			continue
+		}
+
+		newEdgeId := edgeIdFromPointIds(keep.Id, neighbourId)
+		reversed := edge.isForward(drop.Id) != (newEdgeId.P1 == keep.Id)
+		if existing := s.getEdgeById(newEdgeId); existing != nil {
+			existing.merge(edge, reversed)
+		} else {
+			edge.Id = newEdgeId
+			if reversed {
+				edge.reverse()
+			}
+			s.AddEdge(edge)
+		}
+	}
+
+	utils.MapsMerge(keep.Tracks, drop.Tracks)
+	keep.Usage.merge(drop.Usage)
+	keep.Begin = keep.Begin || drop.Begin
+	keep.End = keep.End || drop.End
+	keep.mergeCentroid(drop.CentroidLat, drop.CentroidLng, max(drop.Weight, 1))
+	if keep.Ele == nil {
+		keep.Ele = drop.Ele
+	}
+
+	// track boundaries pointing to dropped location
+	for trackId := range drop.Tracks {
+		if t, ok := s.tracks[trackId]; ok {
+			if t.BeginPointId == drop.Id {
+				t.BeginPointId = keep.Id
+			}
+			if t.EndPointId == drop.Id {
+				t.EndPointId = keep.Id
+			}
+		}
+	}
+
+	s.index.Remove(drop)
+
+	s.updateLocationFlags(keep)
+	for neighbourId := range keep.Edges {
+		s.updateLocationFlags(s.index.GetLocation(neighbourId))
+	}
+}
+
 func (s *S2Store) updateCrossingForEdgePoints(edgeId S2EdgeKey) {
 	p1 := s.index.GetLocation(edgeId.P1)
 	if p1 == nil {
@@ -248,4 +544,33 @@ func (s *S2Store) setEdgesNotProcessed() {
 	for _, e := range s.edges {
 		e.Processed = false
 	}
+	s.freeEdges = nil
+	s.freeEdgePos = 0
+}
+
+// free edge with the lowest id, so segments don't depend on iteration order
+// of edges map, ids are collected again when all of them are used to get
+// edges created during processing of segments
+func (s *S2Store) getNextFreeEdge() *S2Edge {
+	for {
+		for s.freeEdgePos < len(s.freeEdges) {
+			edge := s.edges[s.freeEdges[s.freeEdgePos]]
+			s.freeEdgePos++
+			if edge != nil && !edge.Processed {
+				return edge
+			}
+		}
+
+		s.freeEdges = s.freeEdges[:0]
+		s.freeEdgePos = 0
+		for id, edge := range s.edges {
+			if !edge.Processed {
+				s.freeEdges = append(s.freeEdges, id)
+			}
+		}
+		if len(s.freeEdges) == 0 {
+			return nil
+		}
+		slices.SortFunc(s.freeEdges, compareEdgeKeys)
+	}
 }
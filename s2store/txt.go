@@ -16,7 +16,7 @@ func boolToStr(x bool) string {
 func (s *S2Store) ToTxt() string {
 
 	t := table.NewWriter()
-	t.AppendHeader(table.Row{"Point", "Tracks", "Neighbours", "Begin", "End", "Crossing"})
+	t.AppendHeader(table.Row{"Point", "Tracks", "Neighbours", "Count", "Begin", "End", "Crossing"})
 
 	points := s.index.GetLocations()
 	//var neighbours []int64
@@ -27,6 +27,7 @@ func (s *S2Store) ToTxt() string {
 			point.Id,
 			utils.MapKeys(point.Tracks),
 			utils.MapKeys(point.Edges),
+			point.Count,
 			boolToStr(point.Begin),
 			boolToStr(point.End),
 			boolToStr(point.Crossing),
@@ -0,0 +1,128 @@
+package s2store
+
+import "time"
+
+// Usage holds statistics of traversals of the net entity (location or edge)
+type Usage struct {
+	Count     int                   `json:"count"`            // total number of passes
+	FirstTime time.Time             `json:"first_time"`       // time of the first pass (taken from gpx points)
+	LastTime  time.Time             `json:"last_time"`        // time of the last pass
+	Passes    map[int64]TrackPasses `json:"passes,omitempty"` // passes of each track (needed for removal of tracks)
+}
+
+// TrackPasses holds statistics of traversals of single track
+type TrackPasses struct {
+	Count     int       `json:"count"`
+	Forward   int       `json:"forward,omitempty"` // passes in forward direction (edges only)
+	FirstTime time.Time `json:"first_time"`
+	LastTime  time.Time `json:"last_time"`
+}
+
+func (p *TrackPasses) addTime(t time.Time) {
+	addTime(&p.FirstTime, &p.LastTime, t)
+}
+
+func (p *TrackPasses) merge(other TrackPasses) {
+	p.Count += other.Count
+	p.Forward += other.Forward
+	p.addTime(other.FirstTime)
+	p.addTime(other.LastTime)
+}
+
+func (u *Usage) addTraversal(trackId int64, t time.Time, forward bool) {
+	u.Count++
+	u.addTime(t)
+
+	p := u.Passes[trackId]
+	p.Count++
+	if forward {
+		p.Forward++
+	}
+	p.addTime(t)
+	u.setPasses(trackId, p)
+}
+
+func (u *Usage) addTime(t time.Time) {
+	addTime(&u.FirstTime, &u.LastTime, t)
+}
+
+func (u *Usage) setPasses(trackId int64, p TrackPasses) {
+	if u.Passes == nil {
+		u.Passes = make(map[int64]TrackPasses)
+	}
+	u.Passes[trackId] = p
+}
+
+// merge statistics of other entity, passes are summed up
+func (u *Usage) merge(other Usage) {
+	u.Count += other.Count
+	u.mergeTimes(other)
+	for trackId, op := range other.Passes {
+		p := u.Passes[trackId]
+		p.merge(op)
+		u.setPasses(trackId, p)
+	}
+}
+
+// extend time ranges by times of other entity, passes are not counted
+func (u *Usage) mergeTimes(other Usage) {
+	u.addTime(other.FirstTime)
+	u.addTime(other.LastTime)
+	for trackId, op := range other.Passes {
+		p := u.Passes[trackId]
+		p.addTime(op.FirstTime)
+		p.addTime(op.LastTime)
+		u.setPasses(trackId, p)
+	}
+}
+
+// copy of the usage with swapped directions of passes
+func (u Usage) reversed() Usage {
+	r := u
+	r.Passes = nil
+	for trackId, p := range u.Passes {
+		p.Forward = p.Count - p.Forward
+		r.setPasses(trackId, p)
+	}
+	return r
+}
+
+// remove passes of the track, time range is recomputed from passes of
+// remaining tracks if all of them are known (net could be saved by older
+// version)
+func (u *Usage) removeTrack(trackId int64) TrackPasses {
+
+	p, ok := u.Passes[trackId]
+	if !ok {
+		return p
+	}
+	delete(u.Passes, trackId)
+	u.Count -= p.Count
+
+	known := 0
+	for _, other := range u.Passes {
+		known += other.Count
+	}
+	if known == u.Count {
+		u.FirstTime, u.LastTime = time.Time{}, time.Time{}
+		for _, other := range u.Passes {
+			u.addTime(other.FirstTime)
+			u.addTime(other.LastTime)
+		}
+	}
+
+	return p
+}
+
+// extend time range by time t (zero is unknown time)
+func addTime(first, last *time.Time, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	if first.IsZero() || t.Before(*first) {
+		*first = t
+	}
+	if last.IsZero() || t.After(*last) {
+		*last = t
+	}
+}
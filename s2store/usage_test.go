@@ -0,0 +1,101 @@
+package s2store
+
+import (
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/tracks"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsage(t *testing.T) {
+
+	s := NewS2Store(&config.Cfg)
+
+	t1 := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	t2 := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	track := tracks.NewTrackFromPoints([][2]float64{{50.0, 14.000}, {50.0, 14.002}})
+	track.Points[0].Timestamp = t1
+	track.Points[1].Timestamp = t1.Add(time.Minute)
+	assert.Nil(t, s.AddGpx(track))
+
+	// same path in reverse direction, second point matched twice
+	track = tracks.NewTrackFromPoints([][2]float64{{50.0, 14.002}, {50.0, 14.0021}, {50.0, 14.000}})
+	track.Points[0].Timestamp = t2
+	track.Points[1].Timestamp = t2.Add(time.Second)
+	track.Points[2].Timestamp = t2.Add(time.Minute)
+	assert.Nil(t, s.AddGpx(track))
+
+	// track without time
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.000}, {50.0, 14.002}})))
+
+	edge := s.edges[S2EdgeKey{1, 2}]
+	assert.Equal(t, 3, edge.Count)
+	assert.Equal(t, t1.Add(time.Minute), edge.FirstTime)
+	assert.Equal(t, t2.Add(time.Minute), edge.LastTime)
+
+	loc := s.index.GetLocation(2)
+	assert.Equal(t, 3, loc.Count)
+	assert.Equal(t, t1.Add(time.Minute), loc.FirstTime)
+	assert.Equal(t, t2, loc.LastTime)
+
+	collection := s.ToGeoJson(nil)
+	assert.Len(t, collection.Features, 1)
+	assert.Equal(t, 3, collection.Features[0].Properties["count"])
+	assert.Equal(t, 3, collection.Features[0].Properties["track_count"])
+	assert.Equal(t, "2024-06-01T10:01:00Z", collection.Features[0].Properties["last_time"])
+}
+
+func TestDirection(t *testing.T) {
+
+	s := NewS2Store(&config.Cfg)
+
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.000}, {50.0, 14.002}})))
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.002}, {50.0, 14.000}})))
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.000}, {50.0, 14.002}})))
+
+	edge := s.edges[S2EdgeKey{1, 2}]
+	assert.Equal(t, 2, edge.Forward)
+	assert.Equal(t, 1, edge.Backward)
+	assert.Equal(t, map[int64]bool{1: true, 3: true}, edge.ForwardTracks)
+	assert.Equal(t, map[int64]bool{2: true}, edge.BackwardTracks)
+
+	// counts are exported relatively to direction of the line
+	collection := s.ToGeoJson(nil)
+	assert.Len(t, collection.Features, 1)
+	line := collection.Features[0]
+	if line.Geometry.LineString[0][0] == 14.0 {
+		assert.Equal(t, 2, line.Properties["forward"])
+		assert.Equal(t, 1, line.Properties["backward"])
+	} else {
+		assert.Equal(t, 1, line.Properties["forward"])
+		assert.Equal(t, 2, line.Properties["backward"])
+	}
+}
+
+func TestDirectionSplitEdge(t *testing.T) {
+
+	cfg := config.Cfg
+	cfg.MatchEdges = true
+
+	s := NewS2Store(&cfg)
+
+	// one way road split by track in opposite direction
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.014}})))
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0001, 14.010}, {50.0001, 14.006}})))
+
+	// 1 -> 4 (first track)
+	assert.Equal(t, 1, s.edges[S2EdgeKey{1, 4}].Forward)
+	assert.Equal(t, 0, s.edges[S2EdgeKey{1, 4}].Backward)
+
+	// 4 -> 3 (first track), 3 -> 4 (second track)
+	assert.Equal(t, 1, s.edges[S2EdgeKey{3, 4}].Forward)
+	assert.Equal(t, 1, s.edges[S2EdgeKey{3, 4}].Backward)
+	assert.Equal(t, map[int64]bool{2: true}, s.edges[S2EdgeKey{3, 4}].ForwardTracks)
+
+	// 3 -> 2 (first track)
+	assert.Equal(t, 0, s.edges[S2EdgeKey{2, 3}].Forward)
+	assert.Equal(t, 1, s.edges[S2EdgeKey{2, 3}].Backward)
+}
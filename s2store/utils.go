@@ -3,20 +3,43 @@ package s2store
 import (
 	"fmt"
 	"math"
+
+	"github.com/tkrajina/gpxgo/gpx"
 )
 
-func haversineDistance(lat1, lng1, lat2, lng2 float64) float64 {
-	const R = 6371e3 // Earth radius in meters
+// initial bearing (degrees 0-360, clockwise from north) of the line between two points
+func bearingBetween(lat1, lng1, lat2, lng2 float64) float64 {
 	phi1 := lat1 * math.Pi / 180
 	phi2 := lat2 * math.Pi / 180
-	deltaPhi := (lat2 - lat1) * math.Pi / 180
 	deltaLambda := (lng2 - lng1) * math.Pi / 180
 
-	a := math.Sin(deltaPhi/2)*math.Sin(deltaPhi/2) +
-		math.Cos(phi1)*math.Cos(phi2)*math.Sin(deltaLambda/2)*math.Sin(deltaLambda/2)
+	y := math.Sin(deltaLambda) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(deltaLambda)
+
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
+
+// angle (degrees 0-90) between two undirected lines given by bearings,
+// direction of movement is ignored since edges are not directed
+func bearingDiff(b1, b2 float64) float64 {
+	diff := math.Mod(math.Abs(b1-b2), 180)
+	return math.Min(diff, 180-diff)
+}
+
+// bearing of the track at given point computed from neighbouring points,
+// false is returned if the bearing cannot be determined (e.g. single point track)
+func trackBearing(points []gpx.GPXPoint, i int) (float64, bool) {
+	prev := max(i-1, 0)
+	next := min(i+1, len(points)-1)
+
+	p1 := points[prev]
+	p2 := points[next]
+
+	if prev == next || (p1.Latitude == p2.Latitude && p1.Longitude == p2.Longitude) {
+		return 0, false
+	}
 
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
-	return R * c // distance in meters
+	return bearingBetween(p1.Latitude, p1.Longitude, p2.Latitude, p2.Longitude), true
 }
 
 // create edge with sorted point ids to avoid duplicates (reverse direction of track movement)
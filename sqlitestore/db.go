@@ -0,0 +1,433 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/log"
+	"mnezerka/geonet/store"
+
+	_ "modernc.org/sqlite"
+)
+
+const NIL_ID = -1
+
+// WGS 84
+const SRS_ID = 4326
+
+// "GPKG" and version 1.3.0 of the GeoPackage specification
+const GPKG_APPLICATION_ID = 0x47504B47
+const GPKG_USER_VERSION = 10300
+
+// schema of new geopackage, spatial index of points is used for matching,
+// edges are indexed for gis tools
+var schema = append(append([]string{
+	`CREATE TABLE gpkg_spatial_ref_sys (
+		srs_name TEXT NOT NULL,
+		srs_id INTEGER NOT NULL PRIMARY KEY,
+		organization TEXT NOT NULL,
+		organization_coordsys_id INTEGER NOT NULL,
+		definition TEXT NOT NULL,
+		description TEXT)`,
+	`INSERT INTO gpkg_spatial_ref_sys VALUES
+		('Undefined cartesian SRS', -1, 'NONE', -1, 'undefined', 'undefined cartesian coordinate reference system'),
+		('Undefined geographic SRS', 0, 'NONE', 0, 'undefined', 'undefined geographic coordinate reference system'),
+		('WGS 84 geodetic', 4326, 'EPSG', 4326, 'GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563,AUTHORITY["EPSG","7030"]],AUTHORITY["EPSG","6326"]],PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],AUTHORITY["EPSG","4326"]]', 'longitude/latitude coordinates in decimal degrees on the WGS 84 spheroid')`,
+	`CREATE TABLE gpkg_contents (
+		table_name TEXT NOT NULL PRIMARY KEY,
+		data_type TEXT NOT NULL,
+		identifier TEXT UNIQUE,
+		description TEXT DEFAULT '',
+		last_change DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+		min_x DOUBLE, min_y DOUBLE, max_x DOUBLE, max_y DOUBLE,
+		srs_id INTEGER,
+		CONSTRAINT fk_gc_r_srs_id FOREIGN KEY (srs_id) REFERENCES gpkg_spatial_ref_sys(srs_id))`,
+	`CREATE TABLE gpkg_geometry_columns (
+		table_name TEXT NOT NULL,
+		column_name TEXT NOT NULL,
+		geometry_type_name TEXT NOT NULL,
+		srs_id INTEGER NOT NULL,
+		z TINYINT NOT NULL,
+		m TINYINT NOT NULL,
+		CONSTRAINT pk_geom_cols PRIMARY KEY (table_name, column_name),
+		CONSTRAINT fk_gc_tn FOREIGN KEY (table_name) REFERENCES gpkg_contents(table_name),
+		CONSTRAINT fk_gc_srs FOREIGN KEY (srs_id) REFERENCES gpkg_spatial_ref_sys (srs_id))`,
+	`CREATE TABLE gpkg_extensions (
+		table_name TEXT,
+		column_name TEXT,
+		extension_name TEXT NOT NULL,
+		definition TEXT NOT NULL,
+		scope TEXT NOT NULL,
+		CONSTRAINT ge_tce UNIQUE (table_name, column_name, extension_name))`,
+
+	// net
+	`CREATE TABLE tracks (
+		id INTEGER PRIMARY KEY,
+		meta TEXT NOT NULL,
+		begin_point_id INTEGER NOT NULL DEFAULT 0,
+		end_point_id INTEGER NOT NULL DEFAULT 0)`,
+	`CREATE TABLE points (
+		id INTEGER PRIMARY KEY,
+		geom POINT NOT NULL,
+		lat DOUBLE NOT NULL,
+		lng DOUBLE NOT NULL,
+		tracks TEXT NOT NULL,
+		count INTEGER NOT NULL,
+		first_time DATETIME,
+		last_time DATETIME,
+		track_begin BOOLEAN NOT NULL DEFAULT 0,
+		track_end BOOLEAN NOT NULL DEFAULT 0,
+		crossing BOOLEAN NOT NULL DEFAULT 0,
+		processed BOOLEAN NOT NULL DEFAULT 0)`,
+	`CREATE TABLE edges (
+		id INTEGER PRIMARY KEY,
+		geom LINESTRING NOT NULL,
+		p1 INTEGER NOT NULL,
+		p2 INTEGER NOT NULL,
+		tracks TEXT NOT NULL,
+		count INTEGER NOT NULL,
+		first_time DATETIME,
+		last_time DATETIME,
+		forward INTEGER NOT NULL DEFAULT 0,
+		backward INTEGER NOT NULL DEFAULT 0,
+		UNIQUE (p1, p2))`,
+	`CREATE INDEX edges_p2 ON edges (p2)`,
+	`CREATE INDEX points_free ON points (track_begin, track_end, crossing, processed)`,
+
+	`INSERT INTO gpkg_contents (table_name, data_type, identifier, description, srs_id) VALUES
+		('points', 'features', 'geonet points', 'points of the net', 4326),
+		('edges', 'features', 'geonet edges', 'edges between points of the net', 4326),
+		('tracks', 'attributes', 'geonet tracks', 'tracks merged into the net', NULL)`,
+	`INSERT INTO gpkg_geometry_columns VALUES
+		('points', 'geom', 'POINT', 4326, 0, 0),
+		('edges', 'geom', 'LINESTRING', 4326, 0, 0)`,
+}, rtreeSchema("points", "geom")...), rtreeSchema("edges", "geom")...)
+
+type DbPoint struct {
+	Id        int64
+	Lat       float64
+	Lng       float64
+	Tracks    []int64
+	Count     int
+	FirstTime time.Time // zero if unknown
+	LastTime  time.Time
+	Begin     bool
+	End       bool
+	Crossing  bool
+}
+
+// DbEdge is stored with sorted point ids, forward means direction from P1 to P2
+type DbEdge struct {
+	Id        int64
+	P1        int64
+	P2        int64
+	Tracks    []int64
+	Count     int
+	FirstTime time.Time // zero if unknown
+	LastTime  time.Time
+	Forward   int
+	Backward  int
+}
+
+// common interface of db connection and transaction
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+type SqliteStore struct {
+	db          *sql.DB
+	lastPointId int64
+	lastTrackId int64
+	stat        store.Stat
+	cfg         *config.Configuration
+}
+
+// NewSqliteStore opens geopackage file, the file (and schema) is created if
+// it doesn't exist
+func NewSqliteStore(cfg *config.Configuration, path string) (*SqliteStore, error) {
+
+	ss := SqliteStore{cfg: cfg}
+
+	log.Infof("opening %s", path)
+
+	var err error
+	ss.db, err = sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	// sqlite allows single writer, connection is shared to keep transactions simple
+	ss.db.SetMaxOpenConns(1)
+
+	if err = ss.initSchema(); err != nil {
+		ss.db.Close()
+		return nil, err
+	}
+
+	if err = ss.db.QueryRow("SELECT COALESCE(MAX(id), 0) FROM points").Scan(&ss.lastPointId); err != nil {
+		ss.db.Close()
+		return nil, err
+	}
+
+	if err = ss.db.QueryRow("SELECT COALESCE(MAX(id), 0) FROM tracks").Scan(&ss.lastTrackId); err != nil {
+		ss.db.Close()
+		return nil, err
+	}
+
+	log.Debugf("last geo id set to: %d", ss.lastPointId)
+
+	return &ss, nil
+}
+
+func (ss *SqliteStore) initSchema() error {
+
+	var tables int
+	err := ss.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'points'").Scan(&tables)
+	if err != nil {
+		return err
+	}
+
+	if tables > 0 {
+		log.Debug("schema exists")
+		return nil
+	}
+
+	log.Info("creating geopackage schema")
+
+	return ss.withTx(func(q querier) error {
+		if _, err := q.Exec(fmt.Sprintf("PRAGMA application_id = %d", GPKG_APPLICATION_ID)); err != nil {
+			return err
+		}
+		if _, err := q.Exec(fmt.Sprintf("PRAGMA user_version = %d", GPKG_USER_VERSION)); err != nil {
+			return err
+		}
+		for _, stmt := range schema {
+			if _, err := q.Exec(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (ss *SqliteStore) Close() error {
+	log.Info("closing sqlite store")
+	return ss.db.Close()
+}
+
+// run function in transaction, transaction is rolled back on error
+func (ss *SqliteStore) withTx(fn func(q querier) error) error {
+	tx, err := ss.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (ss *SqliteStore) GenPointId() int64 {
+	ss.lastPointId++
+	return ss.lastPointId
+}
+
+func (ss *SqliteStore) GenTrackId() int64 {
+	ss.lastTrackId++
+	return ss.lastTrackId
+}
+
+func (ss *SqliteStore) GetMeta() store.Meta {
+
+	meta := store.Meta{Tracks: []*store.Track{}}
+
+	rows, err := ss.db.Query("SELECT id, meta, begin_point_id, end_point_id FROM tracks ORDER BY id")
+	if err != nil {
+		log.ExitWithError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		track := &store.Track{}
+		var trackMeta string
+		if err = rows.Scan(&track.Id, &trackMeta, &track.BeginPointId, &track.EndPointId); err != nil {
+			log.ExitWithError(err)
+		}
+		if err = json.Unmarshal([]byte(trackMeta), &track.Meta); err != nil {
+			log.ExitWithError(err)
+		}
+		meta.Tracks = append(meta.Tracks, track)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.ExitWithError(err)
+	}
+
+	return meta
+}
+
+func (ss *SqliteStore) GetStat() store.Stat {
+	if err := ss.db.QueryRow("SELECT COUNT(*) FROM points").Scan(&ss.stat.PointsFinal); err != nil {
+		log.ExitWithError(err)
+	}
+	if err := ss.db.QueryRow("SELECT COUNT(*) FROM edges").Scan(&ss.stat.EdgesFinal); err != nil {
+		log.ExitWithError(err)
+	}
+	return ss.stat
+}
+
+func insertTrack(q querier, t *store.Track) error {
+	meta, err := json.Marshal(t.Meta)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec("INSERT INTO tracks (id, meta, begin_point_id, end_point_id) VALUES (?, ?, ?, ?)", t.Id, string(meta), t.BeginPointId, t.EndPointId)
+	return err
+}
+
+const pointColumns = "id, lat, lng, tracks, count, first_time, last_time, track_begin, track_end, crossing"
+
+func scanPoint(row interface{ Scan(...any) error }) (*DbPoint, error) {
+	p := &DbPoint{}
+	var tracks string
+	var firstTime, lastTime sql.NullTime
+	err := row.Scan(&p.Id, &p.Lat, &p.Lng, &tracks, &p.Count, &firstTime, &lastTime, &p.Begin, &p.End, &p.Crossing)
+	if err != nil {
+		return nil, err
+	}
+	p.FirstTime = firstTime.Time
+	p.LastTime = lastTime.Time
+	p.Tracks, err = strToTracks(tracks)
+	return p, err
+}
+
+func queryPoints(q querier, query string, args ...any) ([]*DbPoint, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []*DbPoint
+	for rows.Next() {
+		p, err := scanPoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+
+	return points, rows.Err()
+}
+
+func getPoint(q querier, id int64) (*DbPoint, error) {
+	p, err := scanPoint(q.QueryRow("SELECT "+pointColumns+" FROM points WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+func insertPoint(q querier, p *DbPoint) error {
+	_, err := q.Exec(
+		"INSERT INTO points (id, geom, lat, lng, tracks, count, first_time, last_time, track_begin, track_end, crossing) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		p.Id, gpkgPoint(p.Lng, p.Lat), p.Lat, p.Lng, tracksToStr(p.Tracks), p.Count, timeToStr(p.FirstTime), timeToStr(p.LastTime), p.Begin, p.End, p.Crossing,
+	)
+	return err
+}
+
+func deletePoint(q querier, id int64) error {
+	_, err := q.Exec("DELETE FROM points WHERE id = ?", id)
+	return err
+}
+
+const edgeColumns = "id, p1, p2, tracks, count, first_time, last_time, forward, backward"
+
+func scanEdge(row interface{ Scan(...any) error }) (*DbEdge, error) {
+	e := &DbEdge{}
+	var tracks string
+	var firstTime, lastTime sql.NullTime
+	err := row.Scan(&e.Id, &e.P1, &e.P2, &tracks, &e.Count, &firstTime, &lastTime, &e.Forward, &e.Backward)
+	if err != nil {
+		return nil, err
+	}
+	e.FirstTime = firstTime.Time
+	e.LastTime = lastTime.Time
+	e.Tracks, err = strToTracks(tracks)
+	return e, err
+}
+
+func getEdge(q querier, p1, p2 int64) (*DbEdge, error) {
+	e, err := scanEdge(q.QueryRow("SELECT "+edgeColumns+" FROM edges WHERE p1 = ? AND p2 = ?", min(p1, p2), max(p1, p2)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return e, err
+}
+
+// insert edge between two points, geometry is taken from points, points
+// are sorted (directions are swapped if needed)
+func insertEdge(q querier, e *DbEdge) error {
+	if e.P1 > e.P2 {
+		e.P1, e.P2 = e.P2, e.P1
+		e.Forward, e.Backward = e.Backward, e.Forward
+	}
+
+	var lat1, lng1, lat2, lng2 float64
+	err := q.QueryRow("SELECT a.lat, a.lng, b.lat, b.lng FROM points a, points b WHERE a.id = ? AND b.id = ?", e.P1, e.P2).Scan(&lat1, &lng1, &lat2, &lng2)
+	if err != nil {
+		return fmt.Errorf("cannot find points of edge %s: %w", edgeIdFromPointIds(e.P1, e.P2), err)
+	}
+
+	res, err := q.Exec(
+		"INSERT INTO edges (geom, p1, p2, tracks, count, first_time, last_time, forward, backward) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		gpkgLineString([][]float64{{lng1, lat1}, {lng2, lat2}}), e.P1, e.P2, tracksToStr(e.Tracks), e.Count, timeToStr(e.FirstTime), timeToStr(e.LastTime), e.Forward, e.Backward,
+	)
+	if err != nil {
+		return err
+	}
+
+	e.Id, err = res.LastInsertId()
+	return err
+}
+
+// ids of points connected with given point by edge
+func neighbours(q querier, id int64) ([]int64, error) {
+	rows, err := q.Query("SELECT p2 FROM edges WHERE p1 = ? UNION ALL SELECT p1 FROM edges WHERE p2 = ?", id, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []int64
+	for rows.Next() {
+		var n int64
+		if err = rows.Scan(&n); err != nil {
+			return nil, err
+		}
+		result = append(result, n)
+	}
+
+	return result, rows.Err()
+}
+
+// update extent of feature tables (used by gis tools for zooming to layer)
+func updateContents(q querier) error {
+	_, err := q.Exec(`UPDATE gpkg_contents SET
+		min_x = (SELECT MIN(lng) FROM points),
+		min_y = (SELECT MIN(lat) FROM points),
+		max_x = (SELECT MAX(lng) FROM points),
+		max_y = (SELECT MAX(lat) FROM points),
+		last_change = strftime('%Y-%m-%dT%H:%M:%fZ','now')
+		WHERE data_type = 'features'`)
+	return err
+}
@@ -0,0 +1,7 @@
+/*
+Implementation of geonet store built on top of single sqlite file in
+GeoPackage format (points and edges are feature tables with r-tree spatial
+index), so the net could be opened directly in QGIS and updated incrementally
+without loading it into memory
+*/
+package sqlitestore
@@ -0,0 +1,211 @@
+package sqlitestore
+
+import (
+	"fmt"
+	"io"
+
+	"mnezerka/geonet/store"
+	"mnezerka/geonet/utils"
+)
+
+func pointToNet(p *DbPoint) *store.NetPoint {
+	np := &store.NetPoint{
+		Id:          p.Id,
+		Lat:         p.Lat,
+		Lng:         p.Lng,
+		CentroidLat: p.Lat,
+		CentroidLng: p.Lng,
+		Weight:      int64(p.Count),
+		Tracks:      map[int64]bool{},
+		Count:       p.Count,
+		FirstTime:   p.FirstTime,
+		LastTime:    p.LastTime,
+		Begin:       p.Begin,
+		End:         p.End,
+		Crossing:    p.Crossing,
+	}
+	for _, id := range p.Tracks {
+		np.Tracks[id] = true
+	}
+	return np
+}
+
+func edgeToNet(e *DbEdge) *store.NetEdge {
+	ne := &store.NetEdge{
+		Id:        store.NetEdgeId{P1: e.P1, P2: e.P2},
+		Tracks:    map[int64]bool{},
+		Count:     e.Count,
+		FirstTime: e.FirstTime,
+		LastTime:  e.LastTime,
+		Forward:   e.Forward,
+		Backward:  e.Backward,
+	}
+	for _, id := range e.Tracks {
+		ne.Tracks[id] = true
+	}
+	return ne
+}
+
+// iterate all points of the net
+func (ss *SqliteStore) eachPoint(fn func(p *DbPoint) error) error {
+	rows, err := ss.db.Query("SELECT " + pointColumns + " FROM points ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p, err := scanPoint(rows)
+		if err != nil {
+			return err
+		}
+		if err = fn(p); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// iterate all edges of the net
+func (ss *SqliteStore) eachEdge(fn func(e *DbEdge) error) error {
+	rows, err := ss.db.Query("SELECT " + edgeColumns + " FROM edges ORDER BY p1, p2")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanEdge(rows)
+		if err != nil {
+			return err
+		}
+		if err = fn(e); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// Save writes the net in json format shared by all stores, rows are
+// streamed from database
+func (ss *SqliteStore) Save(w io.Writer) error {
+
+	nw := store.NetWriter{
+		LastPointId: ss.lastPointId,
+		LastTrackId: ss.lastTrackId,
+		Tracks: func(yield func(any) error) error {
+			for _, t := range ss.GetMeta().Tracks {
+				if err := yield(t); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Locations: func(yield func(any) error) error {
+			return ss.eachPoint(func(p *DbPoint) error {
+				return yield(pointToNet(p))
+			})
+		},
+		Edges: func(yield func(any) error) error {
+			return ss.eachEdge(func(e *DbEdge) error {
+				return yield(edgeToNet(e))
+			})
+		},
+	}
+
+	return nw.Write(w)
+}
+
+// Load imports the net saved by any store, the store has to be empty
+func (ss *SqliteStore) Load(r io.Reader) error {
+
+	if ss.lastPointId > 0 || ss.lastTrackId > 0 {
+		return fmt.Errorf("cannot load net into non-empty sqlite store")
+	}
+
+	err := ss.withTx(func(q querier) error {
+
+		// older versions saved edges before locations
+		var pendingEdges []*DbEdge
+
+		nr := store.NetReader{}
+		nr.Track = func(t *store.Track) error {
+			ss.stat.TracksLoaded++
+			return insertTrack(q, t)
+		}
+		nr.Location = func(decode func(v any) error) error {
+			np := &store.NetPoint{}
+			if err := decode(np); err != nil {
+				return err
+			}
+			ss.stat.PointsLoaded++
+			return insertPoint(q, &DbPoint{
+				Id:        np.Id,
+				Lat:       np.Lat,
+				Lng:       np.Lng,
+				Tracks:    utils.MapKeys(np.Tracks),
+				Count:     np.Count,
+				FirstTime: np.FirstTime,
+				LastTime:  np.LastTime,
+				Begin:     np.Begin,
+				End:       np.End,
+				Crossing:  np.Crossing,
+			})
+		}
+		nr.Edge = func(decode func(v any) error) error {
+			ne := &store.NetEdge{}
+			if err := decode(ne); err != nil {
+				return err
+			}
+			ss.stat.EdgesLoaded++
+			e := &DbEdge{
+				P1:        ne.Id.P1,
+				P2:        ne.Id.P2,
+				Tracks:    utils.MapKeys(ne.Tracks),
+				Count:     ne.Count,
+				FirstTime: ne.FirstTime,
+				LastTime:  ne.LastTime,
+				Forward:   ne.Forward,
+				Backward:  ne.Backward,
+			}
+
+			p1, err := getPoint(q, e.P1)
+			if err != nil {
+				return err
+			}
+			p2, err := getPoint(q, e.P2)
+			if err != nil {
+				return err
+			}
+			if p1 == nil || p2 == nil {
+				pendingEdges = append(pendingEdges, e)
+				return nil
+			}
+			return insertEdge(q, e)
+		}
+
+		if err := nr.Read(r); err != nil {
+			return err
+		}
+
+		for _, e := range pendingEdges {
+			if err := insertEdge(q, e); err != nil {
+				return err
+			}
+		}
+
+		ss.lastPointId = nr.LastPointId
+		ss.lastTrackId = nr.LastTrackId
+
+		return updateContents(q)
+	})
+
+	if err != nil {
+		ss.lastPointId = 0
+		ss.lastTrackId = 0
+	}
+
+	return err
+}
@@ -0,0 +1,39 @@
+package sqlitestore
+
+import (
+	"mnezerka/geonet/log"
+	"mnezerka/geonet/store"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+func (ss *SqliteStore) ToGeoJson(each func(feature *geojson.Feature)) *geojson.FeatureCollection {
+
+	points := map[int64]*store.NetPoint{}
+	err := ss.eachPoint(func(p *DbPoint) error {
+		points[p.Id] = pointToNet(p)
+		return nil
+	})
+	if err != nil {
+		log.ExitWithError(err)
+	}
+
+	var edges []*store.NetEdge
+	err = ss.eachEdge(func(e *DbEdge) error {
+		edges = append(edges, edgeToNet(e))
+		return nil
+	})
+	if err != nil {
+		log.ExitWithError(err)
+	}
+
+	collection, err := store.NetToGeoJson(ss.cfg, points, edges, nil, each)
+	if err != nil {
+		log.ExitWithError(err)
+	}
+
+	ss.stat.PointsRendered = int64(len(points))
+	ss.stat.EdgesRendered = int64(len(edges))
+
+	return collection
+}
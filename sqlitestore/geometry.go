@@ -0,0 +1,153 @@
+package sqlitestore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+/*
+geometry is stored as GeoPackage binary blob - header followed by wkb,
+all numbers are little endian, no envelope:
+
+	+-----+---------+-------+--------+-------------------------+
+	| "GP"| version | flags | srs_id | wkb (order, type, x, y) |
+	+-----+---------+-------+--------+-------------------------+
+*/
+
+const (
+	GPKG_FLAGS_LITTLE_ENDIAN = 0x01
+	WKB_POINT                = 1
+	WKB_LINESTRING           = 2
+)
+
+func gpkgHeader(buf *bytes.Buffer) {
+	buf.WriteString("GP")
+	buf.WriteByte(0)
+	buf.WriteByte(GPKG_FLAGS_LITTLE_ENDIAN)
+	binary.Write(buf, binary.LittleEndian, int32(SRS_ID))
+
+	// wkb byte order
+	buf.WriteByte(1)
+}
+
+func gpkgPoint(lng, lat float64) []byte {
+	var buf bytes.Buffer
+	gpkgHeader(&buf)
+	binary.Write(&buf, binary.LittleEndian, uint32(WKB_POINT))
+	binary.Write(&buf, binary.LittleEndian, lng)
+	binary.Write(&buf, binary.LittleEndian, lat)
+	return buf.Bytes()
+}
+
+// coordinates are [lng, lat] pairs
+func gpkgLineString(coordinates [][]float64) []byte {
+	var buf bytes.Buffer
+	gpkgHeader(&buf)
+	binary.Write(&buf, binary.LittleEndian, uint32(WKB_LINESTRING))
+	binary.Write(&buf, binary.LittleEndian, uint32(len(coordinates)))
+	for _, c := range coordinates {
+		binary.Write(&buf, binary.LittleEndian, c[0])
+		binary.Write(&buf, binary.LittleEndian, c[1])
+	}
+	return buf.Bytes()
+}
+
+// envelope sizes (in doubles) given by envelope indicator of header flags
+var gpkgEnvelopeSizes = []int{0, 4, 6, 6, 8}
+
+const GPKG_FLAGS_EMPTY = 0x10
+
+// bounding box of GeoPackage geometry blob, envelope from header is used if
+// present, otherwise points and line strings of wkb are read
+func gpkgEnvelope(b []byte) (minX, maxX, minY, maxY float64, empty bool, err error) {
+
+	if len(b) < 8 || b[0] != 'G' || b[1] != 'P' {
+		return 0, 0, 0, 0, false, errors.New("invalid geopackage geometry")
+	}
+
+	flags := b[3]
+	if flags&GPKG_FLAGS_EMPTY != 0 {
+		return 0, 0, 0, 0, true, nil
+	}
+
+	var order binary.ByteOrder = binary.BigEndian
+	if flags&GPKG_FLAGS_LITTLE_ENDIAN != 0 {
+		order = binary.LittleEndian
+	}
+
+	indicator := int(flags>>1) & 0x07
+	if indicator >= len(gpkgEnvelopeSizes) {
+		return 0, 0, 0, 0, false, errors.New("invalid envelope of geopackage geometry")
+	}
+
+	if indicator > 0 {
+		if len(b) < 8+32 {
+			return 0, 0, 0, 0, false, errors.New("invalid envelope of geopackage geometry")
+		}
+		envelope := func(i int) float64 {
+			return math.Float64frombits(order.Uint64(b[8+i*8:]))
+		}
+		return envelope(0), envelope(1), envelope(2), envelope(3), false, nil
+	}
+
+	return wkbEnvelope(b[8:])
+}
+
+func wkbEnvelope(wkb []byte) (minX, maxX, minY, maxY float64, empty bool, err error) {
+
+	if len(wkb) < 5 {
+		return 0, 0, 0, 0, false, errors.New("invalid wkb geometry")
+	}
+
+	var order binary.ByteOrder = binary.BigEndian
+	if wkb[0] == 1 {
+		order = binary.LittleEndian
+	}
+
+	var coords []byte
+	switch order.Uint32(wkb[1:]) {
+	case WKB_POINT:
+		coords = wkb[5:]
+		if len(coords) < 16 {
+			return 0, 0, 0, 0, false, errors.New("invalid wkb point")
+		}
+		coords = coords[:16]
+	case WKB_LINESTRING:
+		if len(wkb) < 9 {
+			return 0, 0, 0, 0, false, errors.New("invalid wkb line string")
+		}
+		n := int(order.Uint32(wkb[5:]))
+		coords = wkb[9:]
+		if n > len(coords)/16 {
+			return 0, 0, 0, 0, false, errors.New("invalid wkb line string")
+		}
+		coords = coords[:n*16]
+	default:
+		return 0, 0, 0, 0, false, errors.New("unsupported wkb geometry")
+	}
+
+	if len(coords) == 0 {
+		return 0, 0, 0, 0, true, nil
+	}
+
+	minX, minY = math.Inf(1), math.Inf(1)
+	maxX, maxY = math.Inf(-1), math.Inf(-1)
+	for i := 0; i < len(coords); i += 16 {
+		x := math.Float64frombits(order.Uint64(coords[i:]))
+		y := math.Float64frombits(order.Uint64(coords[i+8:]))
+		if math.IsNaN(x) || math.IsNaN(y) {
+			// empty point
+			continue
+		}
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+	}
+
+	if math.IsInf(minX, 1) {
+		return 0, 0, 0, 0, true, nil
+	}
+
+	return minX, maxX, minY, maxY, false, nil
+}
@@ -0,0 +1,222 @@
+package sqlitestore
+
+import (
+	"math"
+
+	"mnezerka/geonet/log"
+	"mnezerka/geonet/store"
+	"mnezerka/geonet/tracks"
+	"mnezerka/geonet/utils"
+
+	"github.com/tkrajina/gpxgo/gpx"
+)
+
+// AddGpx merges track into the net, whole track is added in single
+// transaction
+func (ss *SqliteStore) AddGpx(track *tracks.Track) error {
+
+	log.Infof("adding %s to the sqlite store", track.Meta.PostTitle)
+
+	lastPointIdBefore := ss.lastPointId
+	lastTrackIdBefore := ss.lastTrackId
+
+	err := ss.withTx(func(q querier) error {
+
+		trackId := ss.GenTrackId()
+		ss.stat.TracksProcessed++
+
+		var err error
+		var lastPointId int64 = NIL_ID
+		var beginPointId int64
+
+		for i := 0; i < len(track.Points); i++ {
+			point := track.Points[i]
+			lastPointId, err = ss.addGpxPoint(
+				q,
+				&point,
+				trackId,                  // id of the current track
+				lastPointId,              // id of the previous point
+				i == 0,                   // is point beginning of the track?
+				i == len(track.Points)-1, // is point end of the track?
+			)
+			if err != nil {
+				return err
+			}
+			if i == 0 {
+				beginPointId = lastPointId
+			}
+		}
+
+		endPointId := lastPointId
+		if endPointId == NIL_ID {
+			endPointId = 0
+		}
+
+		err = insertTrack(q, &store.Track{Id: trackId, Meta: track.Meta, BeginPointId: beginPointId, EndPointId: endPointId})
+		if err != nil {
+			return err
+		}
+
+		log.Debugf("registered track %d", trackId)
+
+		return updateContents(q)
+	})
+
+	// ids generated in rolled back transaction could be reused
+	if err != nil {
+		ss.lastPointId = lastPointIdBefore
+		ss.lastTrackId = lastTrackIdBefore
+	}
+
+	return err
+}
+
+// find nearest point within matching distance, candidates are taken from
+// r-tree by bounding box around the position
+func (ss *SqliteStore) nearestPoint(q querier, lat, lng float64) (*DbPoint, error) {
+
+	dist := float64(ss.cfg.MatchMaxDistance)
+	dLat := dist / utils.EARTH_RADIUS_METERS * 180 / math.Pi
+	dLng := dLat / math.Max(math.Cos(lat*math.Pi/180), 0.01)
+
+	candidates, err := queryPoints(q,
+		"SELECT "+pointColumns+" FROM points WHERE id IN (SELECT id FROM rtree_points_geom WHERE minx <= ? AND maxx >= ? AND miny <= ? AND maxy >= ?)",
+		lng+dLng, lng-dLng, lat+dLat, lat-dLat,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var nearest *DbPoint
+	nearestDist := dist
+	for _, c := range candidates {
+		d := utils.HaversineDistance(lat, lng, c.Lat, c.Lng)
+		if d <= nearestDist {
+			nearest = c
+			nearestDist = d
+		}
+	}
+
+	return nearest, nil
+}
+
+func (ss *SqliteStore) addGpxPoint(q querier, point *gpx.GPXPoint, trackId int64, lastPointId int64, begin, end bool) (int64, error) {
+
+	var finalPointId int64
+
+	ss.stat.PointsGpx++
+
+	// look for existing point to be reused
+	n, err := ss.nearestPoint(q, point.Latitude, point.Longitude)
+	if err != nil {
+		return NIL_ID, err
+	}
+
+	if n != nil {
+		log.Debugf("reusing point %d", n.Id)
+
+		// count pass through the point (repeated fixes in the same point are single pass)
+		if n.Id != lastPointId {
+			n.Count++
+			addTime(&n.FirstTime, &n.LastTime, point.Timestamp)
+		}
+		_, err = q.Exec(
+			"UPDATE points SET count = ?, tracks = ?, first_time = ?, last_time = ?, track_begin = ?, track_end = ? WHERE id = ?",
+			n.Count, tracksToStr(mergeTracks(n.Tracks, []int64{trackId})), timeToStr(n.FirstTime), timeToStr(n.LastTime), n.Begin || begin, n.End || end, n.Id,
+		)
+		if err != nil {
+			return NIL_ID, err
+		}
+
+		finalPointId = n.Id
+		ss.stat.PointsReused++
+	} else {
+		// no near point exists, register new one
+		newPoint := &DbPoint{
+			Id:     ss.GenPointId(),
+			Lat:    point.Latitude,
+			Lng:    point.Longitude,
+			Tracks: []int64{trackId},
+			Count:  1,
+			Begin:  begin,
+			End:    end,
+		}
+		addTime(&newPoint.FirstTime, &newPoint.LastTime, point.Timestamp)
+
+		log.Debugf("creating new point %d", newPoint.Id)
+
+		if err = insertPoint(q, newPoint); err != nil {
+			return NIL_ID, err
+		}
+
+		finalPointId = newPoint.Id
+		ss.stat.PointsCreated++
+	}
+
+	// --------------------  edge processing
+	// ignore first point and self edges
+	if lastPointId == NIL_ID || lastPointId == finalPointId {
+		return finalPointId, nil
+	}
+
+	existingEdge, err := getEdge(q, lastPointId, finalPointId)
+	if err != nil {
+		return NIL_ID, err
+	}
+
+	if existingEdge != nil {
+		log.Debugf("reusing existing edge: %s", edgeIdFromPointIds(existingEdge.P1, existingEdge.P2))
+		addTime(&existingEdge.FirstTime, &existingEdge.LastTime, point.Timestamp)
+		if lastPointId == existingEdge.P1 {
+			existingEdge.Forward++
+		} else {
+			existingEdge.Backward++
+		}
+		_, err = q.Exec(
+			"UPDATE edges SET count = ?, tracks = ?, first_time = ?, last_time = ?, forward = ?, backward = ? WHERE id = ?",
+			existingEdge.Count+1, tracksToStr(mergeTracks(existingEdge.Tracks, []int64{trackId})),
+			timeToStr(existingEdge.FirstTime), timeToStr(existingEdge.LastTime), existingEdge.Forward, existingEdge.Backward, existingEdge.Id,
+		)
+		if err != nil {
+			return NIL_ID, err
+		}
+		ss.stat.EdgesReused++
+
+		return finalPointId, nil
+	}
+
+	newEdge := &DbEdge{
+		P1:      lastPointId,
+		P2:      finalPointId,
+		Tracks:  []int64{trackId},
+		Count:   1,
+		Forward: 1,
+	}
+	addTime(&newEdge.FirstTime, &newEdge.LastTime, point.Timestamp)
+
+	log.Debugf("registering new edge: %s", edgeIdFromPointIds(newEdge.P1, newEdge.P2))
+
+	if err = insertEdge(q, newEdge); err != nil {
+		return NIL_ID, err
+	}
+	ss.stat.EdgesCreated++
+
+	// update crossings
+	if err = updateCrossing(q, newEdge.P1); err != nil {
+		return NIL_ID, err
+	}
+	if err = updateCrossing(q, newEdge.P2); err != nil {
+		return NIL_ID, err
+	}
+
+	return finalPointId, nil
+}
+
+// point with more than two edges is crossing
+func updateCrossing(q querier, pointId int64) error {
+	_, err := q.Exec(
+		"UPDATE points SET crossing = ((SELECT COUNT(*) FROM edges WHERE p1 = ?1 OR p2 = ?1) > 2) WHERE id = ?1",
+		pointId,
+	)
+	return err
+}
@@ -0,0 +1,104 @@
+package sqlitestore
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	"modernc.org/sqlite"
+)
+
+// sql functions used by triggers of r-tree spatial index extension, gis
+// tools (e.g. QGIS) provide their own implementation when editing the file
+func init() {
+	envelopeFunc := func(pick func(minX, maxX, minY, maxY float64) float64) func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+		return func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			b, ok := args[0].([]byte)
+			if !ok {
+				return nil, nil
+			}
+			minX, maxX, minY, maxY, empty, err := gpkgEnvelope(b)
+			if err != nil || empty {
+				return nil, err
+			}
+			return pick(minX, maxX, minY, maxY), nil
+		}
+	}
+
+	sqlite.MustRegisterDeterministicScalarFunction("ST_MinX", 1, envelopeFunc(func(minX, maxX, minY, maxY float64) float64 { return minX }))
+	sqlite.MustRegisterDeterministicScalarFunction("ST_MaxX", 1, envelopeFunc(func(minX, maxX, minY, maxY float64) float64 { return maxX }))
+	sqlite.MustRegisterDeterministicScalarFunction("ST_MinY", 1, envelopeFunc(func(minX, maxX, minY, maxY float64) float64 { return minY }))
+	sqlite.MustRegisterDeterministicScalarFunction("ST_MaxY", 1, envelopeFunc(func(minX, maxX, minY, maxY float64) float64 { return maxY }))
+
+	sqlite.MustRegisterDeterministicScalarFunction("ST_IsEmpty", 1, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		b, ok := args[0].([]byte)
+		if !ok {
+			return nil, nil
+		}
+		_, _, _, _, empty, err := gpkgEnvelope(b)
+		if err != nil {
+			return nil, err
+		}
+		return empty, nil
+	})
+}
+
+// r-tree spatial index of geometry column with triggers from GeoPackage
+// specification keeping it in sync with the table
+func rtreeSchema(table, column string) []string {
+
+	values := `(NEW.id, ST_MinX(NEW.{geom}), ST_MaxX(NEW.{geom}), ST_MinY(NEW.{geom}), ST_MaxY(NEW.{geom}))`
+	notEmpty := `(NEW.{geom} NOTNULL AND NOT ST_IsEmpty(NEW.{geom}))`
+	empty := `(NEW.{geom} ISNULL OR ST_IsEmpty(NEW.{geom}))`
+
+	stmts := []string{
+		`CREATE VIRTUAL TABLE {rtree} USING rtree(id, minx, maxx, miny, maxy)`,
+
+		`CREATE TRIGGER {rtree}_insert AFTER INSERT ON {table}
+			WHEN ` + notEmpty + `
+		BEGIN
+			INSERT OR REPLACE INTO {rtree} VALUES ` + values + `;
+		END`,
+
+		`CREATE TRIGGER {rtree}_update1 AFTER UPDATE OF {geom} ON {table}
+			WHEN OLD.id = NEW.id AND ` + notEmpty + `
+		BEGIN
+			INSERT OR REPLACE INTO {rtree} VALUES ` + values + `;
+		END`,
+
+		`CREATE TRIGGER {rtree}_update2 AFTER UPDATE OF {geom} ON {table}
+			WHEN OLD.id = NEW.id AND ` + empty + `
+		BEGIN
+			DELETE FROM {rtree} WHERE id = OLD.id;
+		END`,
+
+		`CREATE TRIGGER {rtree}_update3 AFTER UPDATE ON {table}
+			WHEN OLD.id != NEW.id AND ` + notEmpty + `
+		BEGIN
+			DELETE FROM {rtree} WHERE id = OLD.id;
+			INSERT OR REPLACE INTO {rtree} VALUES ` + values + `;
+		END`,
+
+		`CREATE TRIGGER {rtree}_update4 AFTER UPDATE ON {table}
+			WHEN OLD.id != NEW.id AND ` + empty + `
+		BEGIN
+			DELETE FROM {rtree} WHERE id IN (OLD.id, NEW.id);
+		END`,
+
+		`CREATE TRIGGER {rtree}_delete AFTER DELETE ON {table}
+			WHEN OLD.{geom} NOT NULL
+		BEGIN
+			DELETE FROM {rtree} WHERE id = OLD.id;
+		END`,
+
+		`INSERT INTO gpkg_extensions VALUES
+			('{table}', '{geom}', 'gpkg_rtree_index', 'http://www.geopackage.org/spec120/#extension_rtree', 'write-only')`,
+	}
+
+	r := strings.NewReplacer("{rtree}", fmt.Sprintf("rtree_%s_%s", table, column), "{table}", table, "{geom}", column)
+	for i, stmt := range stmts {
+		stmts[i] = r.Replace(stmt)
+	}
+
+	return stmts
+}
@@ -0,0 +1,231 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"slices"
+
+	"mnezerka/geonet/log"
+	"mnezerka/geonet/utils"
+)
+
+// Simplify removes points of segments (paths between begin, end and
+// crossing points) using Douglas-Peucker algorithm, each segment is
+// simplified in its own transaction
+func (ss *SqliteStore) Simplify() error {
+
+	log.Infof("simplifying net, min distance %d", ss.cfg.SimplifyMinDistance)
+
+	// reset all points to not processed state to be sure we start with clean setup
+	if _, err := ss.db.Exec("UPDATE points SET processed = 0"); err != nil {
+		return err
+	}
+
+	// simplify segments until there is no more to simplify
+	for {
+		found := false
+		err := ss.withTx(func(q querier) error {
+			var err error
+			found, err = ss.simplifySegment(q)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if !found {
+			break
+		}
+	}
+
+	return ss.withTx(updateContents)
+}
+
+func (ss *SqliteStore) simplifySegment(q querier) (bool, error) {
+
+	// 1. pick the first free point (free = not crossing, not start nor end of track)
+	freePoint, err := scanPoint(q.QueryRow(
+		"SELECT " + pointColumns + " FROM points WHERE track_begin = 0 AND track_end = 0 AND crossing = 0 AND processed = 0 LIMIT 1",
+	))
+	if err == sql.ErrNoRows {
+		log.Infof("no free point found")
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// 2. find segment from the free point - both sides
+	path, err := ss.findSegment(q, freePoint)
+	if err != nil {
+		return false, err
+	}
+
+	log.Debugf("path for simplification: %v", pointsToIds(path))
+	ss.stat.SegmentsProcessed++
+
+	// 3. simplify
+	keep := utils.DouglasPeucker(pointsToCoordinates(path), float64(ss.cfg.SimplifyMinDistance))
+
+	// 4. adapt edges
+	return true, ss.adaptEdges(q, path, keep)
+}
+
+// path of free points through given point, path is closed by begin, end or
+// crossing points on both sides (if they exist), all free points of the
+// path are marked as processed
+func (ss *SqliteStore) findSegment(q querier, start *DbPoint) ([]*DbPoint, error) {
+
+	visited := map[int64]bool{start.Id: true}
+
+	nIds, err := neighbours(q, start.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	var legs [][]*DbPoint
+	for _, nId := range nIds {
+		var leg []*DbPoint
+		prevId := start.Id
+		nextId := nId
+		for !visited[nextId] {
+			p, err := getPoint(q, nextId)
+			if err != nil {
+				return nil, err
+			}
+			if p == nil {
+				break
+			}
+
+			visited[p.Id] = true
+			leg = append(leg, p)
+
+			// stop on track ends, begins and crossings
+			if p.Begin || p.End || p.Crossing {
+				break
+			}
+
+			pn, err := neighbours(q, p.Id)
+			if err != nil {
+				return nil, err
+			}
+
+			// free point has exactly two neighbours
+			idx := slices.IndexFunc(pn, func(id int64) bool { return id != prevId })
+			if idx < 0 {
+				break
+			}
+			prevId = p.Id
+			nextId = pn[idx]
+		}
+		legs = append(legs, leg)
+	}
+
+	path := []*DbPoint{start}
+	if len(legs) > 0 {
+		slices.Reverse(legs[0])
+		path = append(legs[0], path...)
+	}
+	if len(legs) > 1 {
+		path = append(path, legs[1]...)
+	}
+
+	for _, p := range path {
+		if _, err := q.Exec("UPDATE points SET processed = 1 WHERE id = ?", p.Id); err != nil {
+			return nil, err
+		}
+	}
+
+	return path, nil
+}
+
+// replace edges of path by edges between kept points, removed points are
+// deleted
+func (ss *SqliteStore) adaptEdges(q querier, path []*DbPoint, keep []bool) error {
+
+	if slices.Contains(keep, false) {
+		ss.stat.SegmentsSimplified++
+	}
+
+	from := 0
+	for i := 1; i < len(path); i++ {
+		if !keep[i] {
+			continue
+		}
+
+		// nothing removed between kept points
+		if i-from == 1 {
+			from = i
+			continue
+		}
+
+		// tracks are taken from first edge of the part
+		first, err := getEdge(q, path[from].Id, path[from+1].Id)
+		if err != nil {
+			return err
+		}
+
+		edge := &DbEdge{P1: path[from].Id, P2: path[i].Id, Tracks: []int64{}}
+		if first != nil {
+			edge.Tracks = first.Tracks
+			edge.Count = first.Count
+			edge.FirstTime = first.FirstTime
+			edge.LastTime = first.LastTime
+			// directions of the new edge go along the path
+			edge.Forward, edge.Backward = first.Forward, first.Backward
+			if first.P1 != path[from].Id {
+				edge.Forward, edge.Backward = first.Backward, first.Forward
+			}
+		}
+
+		for j := from; j < i; j++ {
+			// passes could be recorded with different times along the path
+			removed, err := getEdge(q, path[j].Id, path[j+1].Id)
+			if err != nil {
+				return err
+			}
+			if removed != nil {
+				addTime(&edge.FirstTime, &edge.LastTime, removed.FirstTime)
+				addTime(&edge.FirstTime, &edge.LastTime, removed.LastTime)
+			}
+
+			_, err = q.Exec("DELETE FROM edges WHERE p1 = ? AND p2 = ?", min(path[j].Id, path[j+1].Id), max(path[j].Id, path[j+1].Id))
+			if err != nil {
+				return err
+			}
+			ss.stat.EdgesSimplified++
+		}
+
+		for j := from + 1; j < i; j++ {
+			if err := deletePoint(q, path[j].Id); err != nil {
+				return err
+			}
+			ss.stat.PointsSimplified++
+		}
+
+		existing, err := getEdge(q, edge.P1, edge.P2)
+		if err != nil {
+			return err
+		}
+
+		if existing != nil {
+			if existing.P1 != edge.P1 {
+				edge.Forward, edge.Backward = edge.Backward, edge.Forward
+			}
+			addTime(&existing.FirstTime, &existing.LastTime, edge.FirstTime)
+			addTime(&existing.FirstTime, &existing.LastTime, edge.LastTime)
+			_, err = q.Exec(
+				"UPDATE edges SET count = ?, tracks = ?, first_time = ?, last_time = ?, forward = ?, backward = ? WHERE id = ?",
+				existing.Count+edge.Count, tracksToStr(mergeTracks(existing.Tracks, edge.Tracks)),
+				timeToStr(existing.FirstTime), timeToStr(existing.LastTime), existing.Forward+edge.Forward, existing.Backward+edge.Backward, existing.Id,
+			)
+		} else {
+			err = insertEdge(q, edge)
+		}
+		if err != nil {
+			return err
+		}
+
+		from = i
+	}
+
+	return nil
+}
@@ -0,0 +1,263 @@
+package sqlitestore
+
+import (
+	"bytes"
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/s2store"
+	"mnezerka/geonet/tracks"
+	"path/filepath"
+	"testing"
+	"time"
+
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestStore(t *testing.T, cfg *config.Configuration) (*SqliteStore, string) {
+	path := filepath.Join(t.TempDir(), "net.gpkg")
+	s, err := NewSqliteStore(cfg, path)
+	assert.Nil(t, err)
+	return s, path
+}
+
+func TestAddGpx(t *testing.T) {
+
+	cfg := config.Cfg
+	s, _ := newTestStore(t, &cfg)
+	defer s.Close()
+
+	// second track shares first two points and continues to north
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.01}, {50.0, 14.02}})))
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0001}, {50.0, 14.0101}, {50.01, 14.01}})))
+
+	points, err := queryPoints(s.db, "SELECT "+pointColumns+" FROM points ORDER BY id")
+	assert.Nil(t, err)
+	assert.Len(t, points, 4)
+	assert.Equal(t, []int64{1, 2}, points[0].Tracks)
+	assert.Equal(t, 2, points[1].Count)
+	assert.True(t, points[1].Crossing)
+	assert.True(t, points[0].Begin)
+	assert.True(t, points[3].End)
+
+	e, err := getEdge(s.db, 2, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1, 2}, e.Tracks)
+	assert.Equal(t, 2, e.Count)
+
+	meta := s.GetMeta()
+	assert.Len(t, meta.Tracks, 2)
+	assert.Equal(t, int64(1), meta.Tracks[1].BeginPointId)
+	assert.Equal(t, int64(4), meta.Tracks[1].EndPointId)
+}
+
+func TestReopen(t *testing.T) {
+
+	cfg := config.Cfg
+	s, path := newTestStore(t, &cfg)
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.01}})))
+	assert.Nil(t, s.Close())
+
+	// net is updated incrementally, ids continue
+	s, err := NewSqliteStore(&cfg, path)
+	assert.Nil(t, err)
+	defer s.Close()
+
+	var appId int
+	assert.Nil(t, s.db.QueryRow("PRAGMA application_id").Scan(&appId))
+	assert.Equal(t, GPKG_APPLICATION_ID, appId)
+
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.1, 14.0}, {50.1, 14.01}})))
+	assert.Equal(t, int64(4), s.lastPointId)
+	assert.Len(t, s.GetMeta().Tracks, 2)
+
+	var indexed int
+	assert.Nil(t, s.db.QueryRow("SELECT COUNT(*) FROM rtree_points_geom").Scan(&indexed))
+	assert.Equal(t, 4, indexed)
+}
+
+func TestSimplify(t *testing.T) {
+
+	cfg := config.Cfg
+	cfg.MatchMaxDistance = 1
+	cfg.SimplifyMinDistance = 10
+	cfg.ShowPoints = true
+	cfg.ShowEdges = true
+	s, _ := newTestStore(t, &cfg)
+	defer s.Close()
+
+	// almost straight line (~280m) with one significant bend
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{
+		{50.0, 14.0}, {50.00001, 14.001}, {50.0, 14.002}, {50.001, 14.003}, {50.0, 14.004},
+	})))
+
+	assert.Nil(t, s.Simplify())
+
+	points, err := queryPoints(s.db, "SELECT "+pointColumns+" FROM points ORDER BY id")
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1, 3, 4, 5}, pointsToIds(points))
+
+	// edges are merged into single segment
+	collection := s.ToGeoJson(nil)
+	assert.Len(t, collection.Features, 1+4)
+	assert.Equal(t, "1-3-4-5", collection.Features[0].Properties["id"])
+	assert.Equal(t, []int64{1}, collection.Features[0].Properties["tracks"])
+
+	cfg.GeoJsonMergeEdges = false
+	collection = s.ToGeoJson(nil)
+	assert.Len(t, collection.Features, 3+4)
+	assert.Equal(t, "1-3", collection.Features[0].Properties["id"])
+
+	var indexed int
+	assert.Nil(t, s.db.QueryRow("SELECT COUNT(*) FROM rtree_points_geom").Scan(&indexed))
+	assert.Equal(t, 4, indexed)
+}
+
+func TestGeometry(t *testing.T) {
+	b := gpkgPoint(14.0, 50.0)
+	assert.Equal(t, []byte("GP"), b[:2])
+	assert.Len(t, b, 8+21)
+	assert.Len(t, gpkgLineString([][]float64{{14, 50}, {15, 51}}), 8+9+32)
+
+	minX, maxX, minY, maxY, empty, err := gpkgEnvelope(gpkgLineString([][]float64{{15, 50}, {14, 51}}))
+	assert.Nil(t, err)
+	assert.False(t, empty)
+	assert.Equal(t, []float64{14, 15, 50, 51}, []float64{minX, maxX, minY, maxY})
+
+	_, _, _, _, empty, err = gpkgEnvelope(gpkgLineString(nil))
+	assert.Nil(t, err)
+	assert.True(t, empty)
+
+	_, _, _, _, _, err = gpkgEnvelope([]byte("invalid"))
+	assert.NotNil(t, err)
+}
+
+func TestSpatialIndexes(t *testing.T) {
+
+	cfg := config.Cfg
+	s, _ := newTestStore(t, &cfg)
+	defer s.Close()
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.01}, {50.0, 14.02}})))
+
+	count := func(query string) int {
+		var result int
+		assert.Nil(t, s.db.QueryRow(query).Scan(&result))
+		return result
+	}
+
+	assert.Equal(t, 3, count("SELECT COUNT(*) FROM rtree_points_geom"))
+	assert.Equal(t, 2, count("SELECT COUNT(*) FROM rtree_edges_geom"))
+	assert.Equal(t, 2, count("SELECT COUNT(*) FROM gpkg_extensions WHERE extension_name = 'gpkg_rtree_index'"))
+
+	// index follows changes made by other writers (e.g. point moved in gis tool)
+	_, err := s.db.Exec("UPDATE points SET geom = ? WHERE id = 1", gpkgPoint(14.5, 50.5))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count("SELECT COUNT(*) FROM rtree_points_geom WHERE id = 1 AND minx = 14.5 AND miny = 50.5"))
+
+	_, err = s.db.Exec("DELETE FROM edges WHERE p1 = 1")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count("SELECT COUNT(*) FROM rtree_edges_geom"))
+
+}
+
+func TestSaveLoad(t *testing.T) {
+
+	cfg := config.Cfg
+	cfg.ShowPoints = true
+	s, _ := newTestStore(t, &cfg)
+	defer s.Close()
+
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.01}, {50.0, 14.02}})))
+	assert.Nil(t, s.AddGpx(tracks.NewTrackFromPoints([][2]float64{{50.005, 14.01}, {50.0, 14.01}, {49.995, 14.01}})))
+
+	var buf bytes.Buffer
+	assert.Nil(t, s.Save(&buf))
+
+	// net in shared format is loaded into another store
+	loaded, _ := newTestStore(t, &cfg)
+	defer loaded.Close()
+	assert.Nil(t, loaded.Load(bytes.NewReader(buf.Bytes())))
+
+	assert.Equal(t, s.ToGeoJson(nil), loaded.ToGeoJson(nil))
+	assert.Equal(t, s.GetMeta(), loaded.GetMeta())
+	assert.Equal(t, int64(5), loaded.GetStat().PointsLoaded)
+
+	// store must be empty
+	assert.NotNil(t, s.Load(bytes.NewReader(buf.Bytes())))
+}
+
+func TestGeoJsonParity(t *testing.T) {
+
+	t1 := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	// same path in both directions with times
+	timed := tracks.NewTrackFromPoints([][2]float64{{50.0, 14.0}, {50.0, 14.002}, {50.0, 14.004}})
+	reversed := tracks.NewTrackFromPoints([][2]float64{{50.0, 14.004}, {50.0, 14.002}, {50.0, 14.0}})
+	for i := range timed.Points {
+		timed.Points[i].Timestamp = t1.Add(time.Duration(i) * time.Minute)
+		reversed.Points[i].Timestamp = t1.Add(time.Duration(i) * time.Hour)
+	}
+
+	// two consecutive fixes in the same point are single pass
+	repeated := tracks.NewTrackFromPoints([][2]float64{{50.2, 14.0}, {50.2, 14.0001}, {50.2, 14.002}})
+
+	// straight track with middle point removed by simplification and
+	// track going directly between its ends
+	bent := tracks.NewTrackFromPoints([][2]float64{{50.1, 14.0}, {50.1, 14.002}, {50.1, 14.004}})
+	direct := tracks.NewTrackFromPoints([][2]float64{{50.1, 14.0}, {50.1, 14.004}})
+
+	for _, merge := range []bool{true, false} {
+		cfg := config.Cfg
+		cfg.ShowPoints = true
+		cfg.GeoJsonMergeEdges = merge
+
+		s, _ := newTestStore(t, &cfg)
+		defer s.Close()
+		s2 := s2store.NewS2Store(&cfg)
+
+		for _, track := range []*tracks.Track{tracks.NewTrack("../test_data/t1.gpx"), timed, reversed, repeated, bent, direct} {
+			assert.Nil(t, s.AddGpx(track))
+			assert.Nil(t, s2.AddGpx(track))
+		}
+
+		collection := s.ToGeoJson(nil)
+		assert.Equal(t, s2.ToGeoJson(nil), collection)
+
+		// usage statistics are part of the export
+		lastTimes := map[any]bool{}
+		for _, feature := range collection.Features {
+			lastTimes[feature.Properties["last_time"]] = true
+		}
+		assert.True(t, lastTimes["2024-05-01T12:00:00Z"])
+		assert.Equal(t, 1, featureAt(t, collection, 50.2, 14.0, true).Properties["count"])
+
+		assert.Nil(t, s.Simplify())
+		assert.Nil(t, s2.Simplify())
+
+		collection = s.ToGeoJson(nil)
+		assert.Equal(t, s2.ToGeoJson(nil), collection)
+
+		// passes of simplified edge are added to the existing one
+		line := featureAt(t, collection, 50.1, 14.0, false)
+		assert.Len(t, line.Geometry.LineString, 2)
+		assert.Equal(t, 2, line.Properties["count"])
+		assert.Equal(t, 2, line.Properties["forward"])
+	}
+}
+
+// point or line feature starting at given position
+func featureAt(t *testing.T, collection *geojson.FeatureCollection, lat, lng float64, point bool) *geojson.Feature {
+	for _, feature := range collection.Features {
+		var position []float64
+		switch {
+		case point && feature.Geometry.IsPoint():
+			position = feature.Geometry.Point
+		case !point && feature.Geometry.IsLineString():
+			position = feature.Geometry.LineString[0]
+		}
+		if position != nil && position[0] == lng && position[1] == lat {
+			return feature
+		}
+	}
+	t.Fatalf("no feature at %f, %f", lat, lng)
+	return nil
+}
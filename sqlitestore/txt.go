@@ -0,0 +1,45 @@
+package sqlitestore
+
+import (
+	"mnezerka/geonet/log"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+func boolToStr(x bool) string {
+	if x {
+		return "X"
+	}
+	return ""
+}
+
+func (ss *SqliteStore) ToTxt() string {
+
+	t := table.NewWriter()
+	t.AppendHeader(table.Row{"Point", "Tracks", "Neighbours", "Count", "Begin", "End", "Crossing"})
+
+	points, err := queryPoints(ss.db, "SELECT "+pointColumns+" FROM points ORDER BY id")
+	if err != nil {
+		log.ExitWithError(err)
+	}
+
+	for _, point := range points {
+
+		n, err := neighbours(ss.db, point.Id)
+		if err != nil {
+			log.ExitWithError(err)
+		}
+
+		t.AppendRow(table.Row{
+			point.Id,
+			point.Tracks,
+			n,
+			point.Count,
+			boolToStr(point.Begin),
+			boolToStr(point.End),
+			boolToStr(point.Crossing),
+		})
+	}
+
+	return t.Render()
+}
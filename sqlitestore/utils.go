@@ -0,0 +1,80 @@
+package sqlitestore
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+)
+
+func edgeIdFromPointIds(from, to int64) string {
+	return fmt.Sprintf("%d-%d", min(from, to), max(from, to))
+}
+
+func pointsToIds(points []*DbPoint) []int64 {
+	var ids []int64
+	for i := 0; i < len(points); i++ {
+		ids = append(ids, points[i].Id)
+	}
+
+	return ids
+}
+
+// track ids are stored as json array (readable in QGIS attribute table)
+func tracksToStr(tracks []int64) string {
+	if tracks == nil {
+		tracks = []int64{}
+	}
+	slices.Sort(tracks)
+	b, _ := json.Marshal(tracks)
+	return string(b)
+}
+
+func strToTracks(str string) ([]int64, error) {
+	var tracks []int64
+	if str == "" {
+		return tracks, nil
+	}
+	err := json.Unmarshal([]byte(str), &tracks)
+	return tracks, err
+}
+
+func mergeTracks(a, b []int64) []int64 {
+	result := slices.Clone(a)
+	for _, id := range b {
+		if !slices.Contains(result, id) {
+			result = append(result, id)
+		}
+	}
+	slices.Sort(result)
+	return result
+}
+
+func pointsToCoordinates(points []*DbPoint) [][]float64 {
+	var coordinates [][]float64
+	for _, p := range points {
+		coordinates = append(coordinates, []float64{p.Lng, p.Lat})
+	}
+	return coordinates
+}
+
+// times are stored in iso format used by geopackage, unknown time is null
+func timeToStr(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// extend time range of traversals by time t (zero is unknown time)
+func addTime(first, last *time.Time, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	if first.IsZero() || t.Before(*first) {
+		*first = t
+	}
+	if last.IsZero() || t.After(*last) {
+		*last = t
+	}
+}
@@ -0,0 +1,170 @@
+package store
+
+import (
+	"fmt"
+	"html"
+	"mnezerka/geonet/kml"
+	"mnezerka/geonet/log"
+	"slices"
+	"strings"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// KML document with segments (and points) of the net, each segment has
+// list of its tracks in description
+func toKml(store Store) *kml.KML {
+
+	meta := store.GetMeta()
+	tracksById := map[int64]*Track{}
+	for _, t := range meta.Tracks {
+		tracksById[t.Id] = t
+	}
+
+	k := kml.NewKML("GeoNet")
+
+	for i, s := range popularityStyles {
+		k.AddLineStyle(fmt.Sprintf("popularity-%d", i), kml.Color(s.Color, 1), s.Width)
+	}
+	k.AddIconStyle("point", kml.Color("#000000", 1), 0.5)
+	k.AddIconStyle("begin", kml.Color("#4dd091", 1), 0.8)
+	k.AddIconStyle("end", kml.Color("#ff5c77", 1), 0.8)
+	k.AddIconStyle("crossing", kml.Color("#1ba1e2", 1), 0.8)
+
+	collection := store.ToGeoJson(nil)
+	sortFeatures(collection.Features)
+
+	for _, feature := range collection.Features {
+		trackIds := featureTracks(feature)
+
+		switch feature.Geometry.Type {
+		case geojson.GeometryLineString:
+			style := popularityLevel(len(trackIds))
+			k.AddFeature(feature, kml.Placemark{
+				Name:        fmt.Sprintf("Segment %v", feature.Properties["id"]),
+				Description: kmlTracksDescription(trackIds, tracksById),
+				StyleId:     fmt.Sprintf("popularity-%d", style),
+				Data:        []string{"id", "count", "track_count", "first_time", "last_time"},
+			})
+
+		case geojson.GeometryPoint:
+			style := "point"
+			for _, flag := range []string{"begin", "end", "crossing"} {
+				if val, exists := feature.Properties[flag]; exists && val == true {
+					style = flag
+				}
+			}
+			k.AddFeature(feature, kml.Placemark{
+				Name:        fmt.Sprintf("Point %v", feature.Properties["id"]),
+				Description: kmlTracksDescription(trackIds, tracksById),
+				StyleId:     style,
+				Data:        []string{"id", "count", "track_count"},
+			})
+		}
+	}
+
+	return k
+}
+
+// sorted track ids stored in feature properties (slice of ints or of
+// generic values if feature was decoded from json)
+func featureTracks(feature *geojson.Feature) []int64 {
+	var result []int64
+
+	switch ids := feature.Properties["tracks"].(type) {
+	case []int64:
+		result = append(result, ids...)
+	case []interface{}:
+		for _, id := range ids {
+			if v, ok := id.(float64); ok {
+				result = append(result, int64(v))
+			}
+		}
+	}
+
+	slices.Sort(result)
+
+	return result
+}
+
+// html list of tracks with titles, dates and links
+func kmlTracksDescription(trackIds []int64, tracksById map[int64]*Track) string {
+	if len(trackIds) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<ul>")
+	for _, id := range trackIds {
+		t, exists := tracksById[id]
+		if !exists {
+			fmt.Fprintf(&sb, "<li>Track %d</li>", id)
+			continue
+		}
+
+		title := t.Meta.TrackTitle
+		if len(title) == 0 {
+			title = fmt.Sprintf("Track %d", id)
+		}
+		title = html.EscapeString(title)
+		if len(t.Meta.TrackUrl) > 0 {
+			title = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(t.Meta.TrackUrl), title)
+		}
+		sb.WriteString("<li>" + title)
+
+		if !t.Meta.TrackDate.IsZero() {
+			sb.WriteString(" " + t.Meta.TrackDate.Format("2006-01-02"))
+		}
+
+		if len(t.Meta.PostTitle) > 0 {
+			post := html.EscapeString(t.Meta.PostTitle)
+			if len(t.Meta.PostUrl) > 0 {
+				post = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(t.Meta.PostUrl), post)
+			}
+			sb.WriteString(" (" + post + ")")
+		}
+		sb.WriteString("</li>")
+	}
+	sb.WriteString("</ul>")
+
+	return sb.String()
+}
+
+func ExportKml(store Store) []byte {
+
+	content, err := toKml(store).Bytes()
+	if err != nil {
+		log.ExitWithError(err)
+	}
+
+	return content
+}
+
+func ExportKmz(store Store) []byte {
+
+	content, err := toKml(store).Kmz()
+	if err != nil {
+		log.ExitWithError(err)
+	}
+
+	return content
+}
+
+// features ordered by geometry type and id, so the document doesn't depend
+// on iteration order of the store (geometry decides for equal ids)
+func sortFeatures(features []*geojson.Feature) {
+	key := func(f *geojson.Feature) string {
+		id := fmt.Sprint(f.Properties["id"])
+		if n, ok := f.Properties["id"].(int64); ok {
+			id = fmt.Sprintf("%020d", n)
+		}
+		return fmt.Sprintf("%s %s", f.Geometry.Type, id)
+	}
+
+	slices.SortStableFunc(features, func(a, b *geojson.Feature) int {
+		if c := strings.Compare(key(a), key(b)); c != 0 {
+			return c
+		}
+		return strings.Compare(fmt.Sprint(a.Geometry.LineString), fmt.Sprint(b.Geometry.LineString))
+	})
+}
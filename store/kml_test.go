@@ -0,0 +1,49 @@
+package store
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportKml(t *testing.T) {
+
+	s := newTwoTracksStore()
+	s.tracks[0].Meta.TrackTitle = "Morning <ride>"
+	s.tracks[0].Meta.PostUrl = "https://example.com/post"
+	s.tracks[0].Meta.PostTitle = "Post"
+
+	content := string(ExportKml(s))
+
+	assert.True(t, strings.HasPrefix(content, "<?xml"))
+	assert.Equal(t, 3, strings.Count(content, "<Placemark>"))
+	assert.Contains(t, content, "<coordinates>14,50 14.01,50</coordinates>")
+
+	// shared segment is styled as more popular than others
+	assert.Contains(t, content, "<styleUrl>#popularity-1</styleUrl>")
+	assert.Equal(t, 2, strings.Count(content, "<styleUrl>#popularity-0</styleUrl>"))
+
+	// track metadata in description
+	assert.Contains(t, content, `Morning &lt;ride&gt; (<a href="https://example.com/post">Post</a>)`)
+	assert.Contains(t, content, "<li>Track 2</li>")
+
+	// document doesn't depend on iteration order of the store
+	assert.Equal(t, content, string(ExportKml(s)))
+
+	// kmz is zip archive with single kml document
+	kmz := ExportKmz(s)
+	zr, err := zip.NewReader(bytes.NewReader(kmz), int64(len(kmz)))
+	assert.Nil(t, err)
+	assert.Len(t, zr.File, 1)
+	assert.Equal(t, "doc.kml", zr.File[0].Name)
+
+	f, err := zr.File[0].Open()
+	assert.Nil(t, err)
+	unpacked, err := io.ReadAll(f)
+	assert.Nil(t, err)
+	assert.Equal(t, content, string(unpacked))
+}
@@ -0,0 +1,241 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// version of the saved net, files without version are considered to be
+// version 1
+const NET_FORMAT_VERSION = 2
+
+/*
+saved net is a json object, locations are written before edges, so both
+could be streamed (edges refer to existing locations):
+
+	{
+	 "version": 2,
+	 "last-point-id": 10,
+	 "last-track-id": 2,
+	 "tracks": [...],
+	 "locations": [...],
+	 "edges": [...]
+	}
+
+format is shared by all stores, each store writes and reads its own
+representation of locations and edges
+*/
+
+// NetPoint is location of saved net with attributes known to all stores
+type NetPoint struct {
+	Id          int64          `json:"id"`
+	Lat         float64        `json:"lat"`
+	Lng         float64        `json:"lng"`
+	CentroidLat float64        `json:"centroid_lat"`
+	CentroidLng float64        `json:"centroid_lng"`
+	Weight      int64          `json:"weight"`
+	Tracks      map[int64]bool `json:"tracks"`
+	Count       int            `json:"count"`
+	FirstTime   time.Time      `json:"first_time"` // zero if unknown
+	LastTime    time.Time      `json:"last_time"`
+	Begin       bool           `json:"begin"`
+	End         bool           `json:"end"`
+	Crossing    bool           `json:"crossing"`
+}
+
+type NetEdgeId struct {
+	P1 int64 `json:"p1"`
+	P2 int64 `json:"p2"`
+}
+
+// NetEdge is edge of saved net with attributes known to all stores, forward
+// means direction from P1 to P2
+type NetEdge struct {
+	Id        NetEdgeId      `json:"id"`
+	Tracks    map[int64]bool `json:"tracks"`
+	Count     int            `json:"count"`
+	FirstTime time.Time      `json:"first_time"` // zero if unknown
+	LastTime  time.Time      `json:"last_time"`
+	Forward   int            `json:"forward"`
+	Backward  int            `json:"backward"`
+}
+
+// iterator of items to be written, item is passed to yield
+type NetItems func(yield func(any) error) error
+
+type NetWriter struct {
+	LastPointId int64
+	LastTrackId int64
+	Tracks      NetItems
+	Locations   NetItems
+	Edges       NetItems
+}
+
+// Write writes the net in json format, items are encoded one by one, so the
+// net is not held in memory twice
+func (nw *NetWriter) Write(w io.Writer) error {
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "{\n \"version\": %d,\n \"last-point-id\": %d,\n \"last-track-id\": %d", NET_FORMAT_VERSION, nw.LastPointId, nw.LastTrackId)
+
+	if err := writeJsonArray(bw, "tracks", nw.Tracks); err != nil {
+		return err
+	}
+
+	if err := writeJsonArray(bw, "locations", nw.Locations); err != nil {
+		return err
+	}
+
+	if err := writeJsonArray(bw, "edges", nw.Edges); err != nil {
+		return err
+	}
+
+	fmt.Fprint(bw, "\n}\n")
+
+	return bw.Flush()
+}
+
+// write array of json values as attribute of the object, values are
+// provided by iterator
+func writeJsonArray(w *bufio.Writer, name string, items NetItems) error {
+
+	fmt.Fprintf(w, ",\n \"%s\": [", name)
+
+	first := true
+	if items != nil {
+		err := items(func(item any) error {
+			if !first {
+				w.WriteString(",")
+			}
+			first = false
+			w.WriteString("\n  ")
+
+			b, err := json.Marshal(item)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(b)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if !first {
+		w.WriteString("\n ")
+	}
+	_, err := w.WriteString("]")
+	return err
+}
+
+// NetReader reads the net saved by NetWriter (any version), items are
+// decoded one by one by callbacks, version and ids are set before items
+// are read
+type NetReader struct {
+	Version     int
+	LastPointId int64
+	LastTrackId int64
+	Track       func(t *Track) error
+	Location    func(decode func(v any) error) error
+	Edge        func(decode func(v any) error) error
+}
+
+func (nr *NetReader) Read(r io.Reader) error {
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	nr.Version = 1
+
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		key, ok := token.(string)
+		if !ok {
+			return fmt.Errorf("invalid geonet file, unexpected token %v", token)
+		}
+
+		switch key {
+		case "version":
+			if err := dec.Decode(&nr.Version); err != nil {
+				return err
+			}
+			if nr.Version > NET_FORMAT_VERSION {
+				return fmt.Errorf("unsupported geonet file version %d (max %d)", nr.Version, NET_FORMAT_VERSION)
+			}
+		case "last-point-id":
+			err = dec.Decode(&nr.LastPointId)
+		case "last-track-id":
+			err = dec.Decode(&nr.LastTrackId)
+		case "tracks":
+			err = readJsonArray(dec, func() error {
+				t := &Track{}
+				if err := dec.Decode(t); err != nil {
+					return err
+				}
+				return nr.Track(t)
+			})
+		case "locations":
+			err = readJsonArray(dec, func() error {
+				return nr.Location(dec.Decode)
+			})
+		case "edges":
+			err = readJsonArray(dec, func() error {
+				return nr.Edge(dec.Decode)
+			})
+		default:
+			// unknown attribute (e.g. written by newer version)
+			var ignored json.RawMessage
+			err = dec.Decode(&ignored)
+		}
+
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", key, err)
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("invalid geonet file, expected '%v', got %v", delim, token)
+	}
+	return nil
+}
+
+// read json array, each item is decoded by callback
+func readJsonArray(dec *json.Decoder, item func() error) error {
+
+	// null is accepted as empty array
+	token, err := dec.Token()
+	if err != nil || token == nil {
+		return err
+	}
+	if token != json.Delim('[') {
+		return fmt.Errorf("expected array, got %v", token)
+	}
+
+	for dec.More() {
+		if err := item(); err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, ']')
+}
@@ -0,0 +1,203 @@
+package store
+
+import (
+	"cmp"
+	"fmt"
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/utils"
+	"slices"
+	"strings"
+	"time"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// NetGeometry returns coordinates of line going through points of path
+// (e.g. smoothed), straight lines between points are used if it is nil
+type NetGeometry func(path []int64) ([][]float64, error)
+
+// NetToGeoJson renders points and edges of the net, it is used by all
+// stores, so exports are identical for the same content
+func NetToGeoJson(cfg *config.Configuration, points map[int64]*NetPoint, edges []*NetEdge, geometry NetGeometry, each func(feature *geojson.Feature)) (*geojson.FeatureCollection, error) {
+
+	collection := geojson.NewFeatureCollection()
+
+	add := func(feature *geojson.Feature) {
+		if each != nil {
+			each(feature)
+		}
+		collection.AddFeature(feature)
+	}
+
+	slices.SortFunc(edges, func(a, b *NetEdge) int {
+		if c := cmp.Compare(a.Id.P1, b.Id.P1); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Id.P2, b.Id.P2)
+	})
+
+	if cfg.ShowEdges {
+
+		var paths [][]int64
+		if cfg.GeoJsonMergeEdges {
+			paths = netSegments(points, edges)
+		} else {
+			for _, e := range edges {
+				paths = append(paths, []int64{e.Id.P1, e.Id.P2})
+			}
+		}
+
+		edgesById := map[NetEdgeId]*NetEdge{}
+		for _, e := range edges {
+			edgesById[e.Id] = e
+		}
+
+		for _, path := range paths {
+
+			coordinates := [][]float64{}
+			for _, id := range path {
+				p, ok := points[id]
+				if !ok {
+					return nil, fmt.Errorf("inconsistent data, edge point %d was not found", id)
+				}
+				coordinates = append(coordinates, []float64{p.Lng, p.Lat})
+			}
+
+			if geometry != nil {
+				var err error
+				if coordinates, err = geometry(path); err != nil {
+					return nil, err
+				}
+			}
+
+			line := geojson.NewLineStringFeature(coordinates)
+
+			// ids of all points => 1-5-3-6-7
+			if len(path) < 10 {
+				line.SetProperty("id", strings.Trim(strings.Join(strings.Fields(fmt.Sprint(path)), "-"), "[]"))
+			} else {
+				line.SetProperty("id", fmt.Sprintf("%d-%d", path[0], path[len(path)-1]))
+			}
+
+			// tracks are taken from first edge of the path
+			edge := edgesById[NetEdgeId{min(path[0], path[1]), max(path[0], path[1])}]
+			tracks := sortedTracks(edge.Tracks)
+			line.SetProperty("tracks", tracks)
+			SetUsageProperties(line, edge.Count, len(tracks), edge.FirstTime, edge.LastTime)
+
+			// passes in direction of line geometry and in opposite direction
+			if edge.Id.P1 == path[0] {
+				line.SetProperty("forward", edge.Forward)
+				line.SetProperty("backward", edge.Backward)
+			} else {
+				line.SetProperty("forward", edge.Backward)
+				line.SetProperty("backward", edge.Forward)
+			}
+
+			add(line)
+		}
+	}
+
+	if cfg.ShowPoints {
+
+		ids := utils.MapKeys(points)
+		slices.Sort(ids)
+
+		for _, id := range ids {
+			point := points[id]
+			tracks := sortedTracks(point.Tracks)
+
+			pnt := geojson.NewPointFeature([]float64{point.Lng, point.Lat})
+			pnt.SetProperty("id", point.Id)
+			pnt.SetProperty("tracks", tracks)
+			pnt.SetProperty("begin", point.Begin)
+			pnt.SetProperty("end", point.End)
+			pnt.SetProperty("crossing", point.Crossing)
+			SetUsageProperties(pnt, point.Count, len(tracks), point.FirstTime, point.LastTime)
+
+			add(pnt)
+		}
+	}
+
+	return collection, nil
+}
+
+// SetUsageProperties sets traversal statistics of feature: number of passes,
+// distinct tracks and time of first and last pass (if known)
+func SetUsageProperties(feature *geojson.Feature, count, trackCount int, firstTime, lastTime time.Time) {
+	feature.SetProperty("count", count)
+	feature.SetProperty("track_count", trackCount)
+	if !firstTime.IsZero() {
+		feature.SetProperty("first_time", firstTime.UTC().Format(time.RFC3339))
+	}
+	if !lastTime.IsZero() {
+		feature.SetProperty("last_time", lastTime.UTC().Format(time.RFC3339))
+	}
+}
+
+func sortedTracks(tracks map[int64]bool) []int64 {
+	ids := utils.MapKeys(tracks)
+	slices.Sort(ids)
+	return ids
+}
+
+/*
+join edges into segments - paths through points which are not begin, end
+or crossing and where both edges belong to the same tracks:
+
+	B---o---o---X---o---E   =>   B-------X-------E
+*/
+func netSegments(points map[int64]*NetPoint, edges []*NetEdge) [][]int64 {
+
+	adjacent := map[int64][]*NetEdge{}
+	for _, e := range edges {
+		adjacent[e.Id.P1] = append(adjacent[e.Id.P1], e)
+		adjacent[e.Id.P2] = append(adjacent[e.Id.P2], e)
+	}
+
+	visited := map[NetEdgeId]bool{}
+
+	// continue path from its last point as long as possible
+	extend := func(path []int64, tracks map[int64]bool) []int64 {
+		for {
+			last := path[len(path)-1]
+			p := points[last]
+			if p == nil || p.Begin || p.End || p.Crossing || len(adjacent[last]) != 2 {
+				return path
+			}
+
+			var next *NetEdge
+			for _, e := range adjacent[last] {
+				if !visited[e.Id] {
+					next = e
+				}
+			}
+			if next == nil || !utils.MapsEqual(next.Tracks, tracks) {
+				return path
+			}
+
+			visited[next.Id] = true
+			if next.Id.P1 == last {
+				path = append(path, next.Id.P2)
+			} else {
+				path = append(path, next.Id.P1)
+			}
+		}
+	}
+
+	var segments [][]int64
+	for _, e := range edges {
+		if visited[e.Id] {
+			continue
+		}
+		visited[e.Id] = true
+
+		path := extend([]int64{e.Id.P2, e.Id.P1}, e.Tracks)
+		slices.Reverse(path)
+		path = extend(path, e.Tracks)
+
+		segments = append(segments, path)
+	}
+
+	return segments
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/log"
+	"mnezerka/geonet/raster"
+	"mnezerka/geonet/svg"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// style of svg, lines have width from config unless they are styled by
+// data (color and width are given by line style as in svg)
+func pngStyle(styled bool) raster.StyleFunc {
+	return func(feature *geojson.Feature) raster.Style {
+		style, _ := feature.Properties["style"].(string)
+		result := raster.ParseStyle(style)
+
+		if feature.Geometry.Type == geojson.GeometryLineString && !styled {
+			result.StrokeWidth = config.Cfg.PngLineWidth
+		}
+
+		return result
+	}
+}
+
+func ExportPng(store Store) []byte {
+
+	lineStyle := LineStyle()
+
+	r := raster.NewRaster()
+	r.AddFeatureCollection(store.ToGeoJson(func(feature *geojson.Feature) {
+		setSvgStyle(feature)
+		if lineStyle != nil {
+			setLineStyle(lineStyle, feature)
+		}
+	}))
+
+	projection, err := svg.ProjectionByName(config.Cfg.SvgProjection)
+	if err != nil {
+		log.ExitWithError(err)
+	}
+
+	img := r.Draw(
+		float64(config.Cfg.PngWidth),
+		float64(config.Cfg.PngHeight),
+		raster.WithDpi(float64(config.Cfg.PngDpi)),
+		raster.WithProjection(projection),
+		raster.WithStyle(pngStyle(lineStyle != nil)),
+		raster.WithPadding(raster.Padding{
+			Top:    float64(config.Cfg.PngPadding),
+			Right:  float64(config.Cfg.PngPadding),
+			Bottom: float64(config.Cfg.PngPadding),
+			Left:   float64(config.Cfg.PngPadding),
+		}),
+	)
+
+	content, err := raster.EncodePng(img, float64(config.Cfg.PngDpi))
+	if err != nil {
+		log.ExitWithError(err)
+	}
+
+	return content
+}
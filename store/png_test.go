@@ -0,0 +1,45 @@
+package store
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"mnezerka/geonet/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportPng(t *testing.T) {
+
+	s := newSingleTrackStore()
+
+	cfg := config.Cfg
+	defer func() { config.Cfg = cfg }()
+	config.Cfg.PngWidth = 200
+	config.Cfg.PngHeight = 100
+	config.Cfg.PngPadding = 10
+	config.Cfg.PngDpi = 192
+	config.Cfg.Style = "popularity"
+
+	content := ExportPng(s)
+
+	// physical resolution follows size of image
+	assert.Equal(t, "pHYs", string(content[37:41]))
+
+	img, err := png.Decode(bytes.NewReader(content))
+	assert.Nil(t, err)
+	assert.Equal(t, 400, img.Bounds().Dx())
+	assert.Equal(t, 200, img.Bounds().Dy())
+
+	// background is white, horizontal line goes through the center
+	// colored as segment of single track
+	assert.Equal(t, color.RGBA{255, 255, 255, 255}, color.RGBAModel.Convert(img.At(200, 20)))
+	assert.Equal(t, color.RGBA{0x00, 0x65, 0xa2, 255}, color.RGBAModel.Convert(img.At(200, 100)))
+
+	// lines are black without style
+	config.Cfg.Style = ""
+	img, err = png.Decode(bytes.NewReader(ExportPng(s)))
+	assert.Nil(t, err)
+	assert.Equal(t, color.RGBA{0, 0, 0, 255}, color.RGBAModel.Convert(img.At(200, 100)))
+}
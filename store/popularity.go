@@ -0,0 +1,18 @@
+package store
+
+import "mnezerka/geonet/style"
+
+// segments are styled by popularity - number of distinct tracks, the
+// stops of continuous popularity style are used as discrete levels
+var popularityStyles = style.Popularity.Stops
+
+// index of popularity style for given number of tracks
+func popularityLevel(trackCount int) int {
+	level := 0
+	for i, s := range popularityStyles {
+		if float64(trackCount) >= s.Value {
+			level = i
+		}
+	}
+	return level
+}
@@ -13,18 +13,28 @@ import (
 )
 
 type Track struct {
-	Id   int64            `json:"id" bson:"id"`
-	Meta tracks.TrackMeta `json:"meta" bson:"meta"`
+	Id           int64            `json:"id" bson:"id"`
+	Meta         tracks.TrackMeta `json:"meta" bson:"meta"`
+	BeginPointId int64            `json:"begin_point_id,omitempty" bson:"begin_point_id,omitempty"` // first point of the track in the net (0 = unknown)
+	EndPointId   int64            `json:"end_point_id,omitempty" bson:"end_point_id,omitempty"`     // last point of the track in the net (0 = unknown)
 }
 
 type Meta struct {
 	Tracks []*Track `json:"tracks"`
 }
 
+// Store is implemented by all backends keeping the net (memory, mongodb,
+// sqlite)
 type Store interface {
+	AddGpx(track *tracks.Track) error
+	Simplify() error
+	Save(w io.Writer) error // net in json format shared by all stores
+	Load(r io.Reader) error
+	GetStat() Stat
 	ToGeoJson(func(feature *geojson.Feature)) *geojson.FeatureCollection
 	ToTxt() string
 	GetMeta() Meta
+	Close() error
 }
 
 func Export(store Store) []byte {
@@ -45,44 +55,70 @@ func Export(store Store) []byte {
 	return bytesJson
 }
 
-func ExportSvg(store Store) string {
+// svg style of net features (black lines, colored begin, end and crossing
+// points)
+func setSvgStyle(feature *geojson.Feature) {
 
-	gs := store.ToGeoJson(func(feature *geojson.Feature) {
+	if feature.Geometry.Type == "LineString" {
+		feature.SetProperty("style", "stroke: black; stroke-width: 2; fill: none")
+	}
 
-		if feature.Geometry.Type == "LineString" {
-			feature.SetProperty("style", "stroke: black; stroke-width: 2; fill: none")
+	if feature.Geometry.Type == "Point" {
+		fill := "black"
+		radius := "5px"
+		style := "stroke: none;"
+		if val, exists := feature.Properties["begin"]; exists && val == true {
+			fill = "#4dd091"
+			radius = "8px"
+		}
+		if val, exists := feature.Properties["end"]; exists && val == true {
+			fill = "#ff5c77"
+			radius = "8px"
+		}
+		if val, exists := feature.Properties["crossing"]; exists && val == true {
+			style += "fill: #1BA1E2; r: 8px;"
+			fill = "#1BA1E2"
+			radius = "8px"
 		}
 
-		if feature.Geometry.Type == "Point" {
-			fill := "black"
-			radius := "5px"
-			style := "stroke: none;"
-			if val, exists := feature.Properties["begin"]; exists && val == true {
-				fill = "#4dd091"
-				radius = "8px"
-			}
-			if val, exists := feature.Properties["end"]; exists && val == true {
-				fill = "#ff5c77"
-				radius = "8px"
-			}
-			if val, exists := feature.Properties["crossing"]; exists && val == true {
-				style += "fill: #1BA1E2; r: 8px;"
-				fill = "#1BA1E2"
-				radius = "8px"
-			}
+		feature.SetProperty("style", fmt.Sprintf("stroke: none; fill: %s; r: %s", fill, radius))
+	}
+}
 
-			feature.SetProperty("style", fmt.Sprintf("stroke: none; fill: %s; r: %s", fill, radius))
-		}
+func ExportSvg(store Store) string {
+
+	lineStyle := LineStyle()
 
+	gs := store.ToGeoJson(func(feature *geojson.Feature) {
+		setSvgStyle(feature)
+		if lineStyle != nil {
+			setLineStyle(lineStyle, feature)
+		}
 	})
 
 	s := svg.NewSVG()
 	s.AddFeatureCollection(gs)
 
+	projection, err := svg.ProjectionByName(config.Cfg.SvgProjection)
+	if err != nil {
+		log.ExitWithError(err)
+	}
+
+	legendTitle := ""
+	var legend []svg.LegendItem
+	if lineStyle != nil {
+		legendTitle = lineStyle.Title
+		legend = svgLegend(lineStyle)
+	}
+
 	got := s.Draw(
 		float64(config.Cfg.SvgWidth),
 		float64(config.Cfg.SvgHeight),
 		svg.WithAttribute("xmlns", "http://www.w3.org/2000/svg"),
+		svg.WithProjection(projection),
+		svg.WithScaleBar(config.Cfg.SvgScaleBar),
+		svg.WithNorthArrow(config.Cfg.SvgNorthArrow),
+		svg.WithLegend(legendTitle, legend),
 		svg.UseProperties([]string{"style"}),
 		svg.WithPadding(svg.Padding{
 			Top:    float64(config.Cfg.SvgPadding),
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"io"
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/log"
+	"mnezerka/geonet/tracks"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// netStore is store with fixed net for testing of exports
+type netStore struct {
+	points map[int64]*NetPoint
+	edges  []*NetEdge
+	tracks []*Track
+}
+
+// single track 1-2-3 going east
+func newSingleTrackStore() *netStore {
+	return &netStore{
+		points: map[int64]*NetPoint{
+			1: {Id: 1, Lat: 50.0, Lng: 14.0, Tracks: map[int64]bool{1: true}, Count: 1, Begin: true},
+			2: {Id: 2, Lat: 50.0, Lng: 14.01, Tracks: map[int64]bool{1: true}, Count: 1},
+			3: {Id: 3, Lat: 50.0, Lng: 14.02, Tracks: map[int64]bool{1: true}, Count: 1, End: true},
+		},
+		edges: []*NetEdge{
+			{Id: NetEdgeId{1, 2}, Tracks: map[int64]bool{1: true}, Count: 1, Forward: 1},
+			{Id: NetEdgeId{2, 3}, Tracks: map[int64]bool{1: true}, Count: 1, Forward: 1},
+		},
+		tracks: []*Track{{Id: 1}},
+	}
+}
+
+// two tracks sharing the first segment, track 1 is 1-2-3, track 2 is 1-2-4
+//
+//	       4
+//	       |
+//	1 ---- 2 ---- 3
+func newTwoTracksStore() *netStore {
+	return &netStore{
+		points: map[int64]*NetPoint{
+			1: {Id: 1, Lat: 50.0, Lng: 14.0, Tracks: map[int64]bool{1: true, 2: true}, Count: 2, Begin: true},
+			2: {Id: 2, Lat: 50.0, Lng: 14.01, Tracks: map[int64]bool{1: true, 2: true}, Count: 2, Crossing: true},
+			3: {Id: 3, Lat: 50.0, Lng: 14.02, Tracks: map[int64]bool{1: true}, Count: 1, End: true},
+			4: {Id: 4, Lat: 50.01, Lng: 14.01, Tracks: map[int64]bool{2: true}, Count: 1, End: true},
+		},
+		edges: []*NetEdge{
+			{Id: NetEdgeId{1, 2}, Tracks: map[int64]bool{1: true, 2: true}, Count: 2, Forward: 2},
+			{Id: NetEdgeId{2, 3}, Tracks: map[int64]bool{1: true}, Count: 1, Forward: 1},
+			{Id: NetEdgeId{2, 4}, Tracks: map[int64]bool{2: true}, Count: 1, Forward: 1},
+		},
+		tracks: []*Track{{Id: 1}, {Id: 2}},
+	}
+}
+
+func (ns *netStore) AddGpx(track *tracks.Track) error { return nil }
+func (ns *netStore) Simplify() error                  { return nil }
+func (ns *netStore) Save(w io.Writer) error           { return nil }
+func (ns *netStore) Load(r io.Reader) error           { return nil }
+func (ns *netStore) GetStat() Stat                    { return Stat{} }
+func (ns *netStore) ToTxt() string                    { return "" }
+func (ns *netStore) GetMeta() Meta                    { return Meta{Tracks: ns.tracks} }
+func (ns *netStore) Close() error                     { return nil }
+
+func (ns *netStore) ToGeoJson(each func(feature *geojson.Feature)) *geojson.FeatureCollection {
+	collection, err := NetToGeoJson(&config.Cfg, ns.points, ns.edges, nil, each)
+	if err != nil {
+		log.ExitWithError(err)
+	}
+	return collection
+}
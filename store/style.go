@@ -0,0 +1,42 @@
+package store
+
+import (
+	"fmt"
+	"mnezerka/geonet/config"
+	"mnezerka/geonet/log"
+	"mnezerka/geonet/style"
+	"mnezerka/geonet/svg"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// LineStyle returns configured style of lines or nil if lines are not styled
+// by data
+func LineStyle() *style.Style {
+	if len(config.Cfg.Style) == 0 {
+		return nil
+	}
+
+	s, err := style.Load(config.Cfg.Style)
+	if err != nil {
+		log.ExitWithError(err)
+	}
+
+	return s
+}
+
+// overrides svg style of line by style driven by feature properties
+func setLineStyle(s *style.Style, feature *geojson.Feature) {
+	if feature.Geometry.Type == geojson.GeometryLineString {
+		color, width := s.Line(feature.Properties)
+		feature.SetProperty("style", fmt.Sprintf("stroke: %s; stroke-width: %g; fill: none", color, width))
+	}
+}
+
+func svgLegend(s *style.Style) []svg.LegendItem {
+	var result []svg.LegendItem
+	for _, item := range s.Legend() {
+		result = append(result, svg.LegendItem{Label: item.Label, Color: item.Color, Width: item.Width})
+	}
+	return result
+}
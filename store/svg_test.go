@@ -0,0 +1,38 @@
+package store
+
+import (
+	"mnezerka/geonet/config"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportSvgStyle(t *testing.T) {
+
+	s := newTwoTracksStore()
+
+	cfg := config.Cfg
+	defer func() { config.Cfg = cfg }()
+	config.Cfg.SvgEdgeLabels = false
+
+	// lines are black without style
+	content := ExportSvg(s)
+	assert.Contains(t, content, "stroke: black; stroke-width: 2")
+	assert.NotContains(t, content, `<g class="legend">`)
+
+	// shared segment of two tracks and segments of single track
+	config.Cfg.Style = "popularity"
+	content = ExportSvg(s)
+	assert.NotContains(t, content, "stroke: black; stroke-width: 2")
+	// legend has sample of each stop
+	assert.Equal(t, 2, strings.Count(content, "stroke: #00a5e3; stroke-width: 3; fill: none"))
+	assert.Equal(t, 3, strings.Count(content, "stroke: #0065a2; stroke-width: 2; fill: none"))
+	assert.Contains(t, content, `<g class="legend">`)
+	assert.Contains(t, content, ">10+</text>")
+
+	config.Cfg.Style = "track:2"
+	content = ExportSvg(s)
+	assert.Equal(t, 3, strings.Count(content, "stroke: #ff5c77; stroke-width: 4; fill: none"))
+	assert.Contains(t, content, ">other</text>")
+}
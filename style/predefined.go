@@ -0,0 +1,70 @@
+package style
+
+// lines colored by number of distinct tracks
+var Popularity = Style{
+	Title:    "Tracks",
+	Property: "track_count",
+	Ramp:     RAMP_CONTINUOUS,
+	Stops: []Stop{
+		{1, "1", "#0065a2", 2},
+		{2, "2", "#00a5e3", 3},
+		{4, "4", "#ffa23a", 4},
+		{10, "10+", "#ff5c77", 5},
+	},
+	Default: Class{Color: "#999999", Width: 1},
+}
+
+// lines colored by number of passes
+var Usage = Style{
+	Title:    "Passes",
+	Property: "count",
+	Ramp:     RAMP_CONTINUOUS,
+	Stops: []Stop{
+		{1, "1", "#0065a2", 1},
+		{5, "5", "#00a5e3", 2},
+		{20, "20", "#ffa23a", 4},
+		{100, "100+", "#ff5c77", 6},
+	},
+	Default: Class{Color: "#999999", Width: 1},
+}
+
+// lines colored by age of the last pass
+var Recency = Style{
+	Title:    "Last ridden",
+	Property: "last_time",
+	Ramp:     RAMP_CONTINUOUS,
+	Stops: []Stop{
+		{0, "today", "#ff5c77", 4},
+		{30, "month ago", "#ffa23a", 3},
+		{365, "year ago", "#0065a2", 2},
+		{1825, "5 years ago", "#999999", 1},
+	},
+	Default: Class{Label: "unknown", Color: "#cccccc", Width: 1},
+}
+
+// Predefined returns copy of predefined style or nil for unknown name
+func Predefined(name string) *Style {
+	var s Style
+	switch name {
+	case "popularity":
+		s = Popularity
+	case "usage":
+		s = Usage
+	case "recency":
+		s = Recency
+	default:
+		return nil
+	}
+	return &s
+}
+
+// Track highlights lines of single track
+func Track(id string) *Style {
+	return &Style{
+		Title:    "Track " + id,
+		Property: "tracks",
+		Ramp:     RAMP_CATEGORICAL,
+		Classes:  []Class{{Value: id, Label: "track " + id, Color: "#ff5c77", Width: 4}},
+		Default:  Class{Label: "other", Color: "#999999", Width: 1},
+	}
+}
@@ -0,0 +1,258 @@
+// Package style defines data driven styles of lines - color and width of
+// line is given by feature property through continuous or categorical
+// ramp. Styles are serializable to json, so the same definition is used
+// by svg and html exports.
+package style
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// types of ramps
+const RAMP_CONTINUOUS = "continuous"
+const RAMP_CATEGORICAL = "categorical"
+
+// Stop of continuous ramp, color and width are interpolated between stops
+type Stop struct {
+	Value float64 `json:"value"`
+	Label string  `json:"label,omitempty"`
+	Color string  `json:"color"`
+	Width float64 `json:"width"`
+}
+
+// Class of categorical ramp, value is compared with property formatted as
+// string (any item matches if property is a list, e.g. tracks)
+type Class struct {
+	Value string  `json:"value"`
+	Label string  `json:"label,omitempty"`
+	Color string  `json:"color"`
+	Width float64 `json:"width"`
+}
+
+// Style of lines driven by property of feature, dates (RFC3339 strings)
+// are converted to age in days
+type Style struct {
+	Title    string  `json:"title"`
+	Property string  `json:"property"`
+	Ramp     string  `json:"ramp"`
+	Stops    []Stop  `json:"stops,omitempty"`
+	Classes  []Class `json:"classes,omitempty"`
+	Default  Class   `json:"default"` // features without property or not matching any class
+
+	Now time.Time `json:"-"` // reference time for age of dates (zero = now)
+}
+
+// LegendItem describes one color of the style
+type LegendItem struct {
+	Label string
+	Color string
+	Width float64
+}
+
+// Load returns predefined style (popularity, usage, recency, track:ID) or
+// style read from json file
+func Load(name string) (*Style, error) {
+
+	if s := Predefined(name); s != nil {
+		return s, nil
+	}
+
+	if id, found := strings.CutPrefix(name, "track:"); found {
+		if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid track id in style %s", name)
+		}
+		return Track(id), nil
+	}
+
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown style %s: %w", name, err)
+	}
+
+	var s Style
+	if err := json.Unmarshal(content, &s); err != nil {
+		return nil, fmt.Errorf("invalid style %s: %w", name, err)
+	}
+
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (s *Style) Validate() error {
+	if len(s.Property) == 0 {
+		return fmt.Errorf("style property is not set")
+	}
+
+	switch s.Ramp {
+	case RAMP_CONTINUOUS:
+		if len(s.Stops) == 0 {
+			return fmt.Errorf("continuous style has no stops")
+		}
+		for i := 1; i < len(s.Stops); i++ {
+			if s.Stops[i].Value <= s.Stops[i-1].Value {
+				return fmt.Errorf("stops of continuous style are not increasing")
+			}
+		}
+	case RAMP_CATEGORICAL:
+		if len(s.Classes) == 0 {
+			return fmt.Errorf("categorical style has no classes")
+		}
+	default:
+		return fmt.Errorf("unknown ramp: %s", s.Ramp)
+	}
+
+	return nil
+}
+
+// Line returns color and width of line with given properties
+func (s *Style) Line(properties map[string]interface{}) (string, float64) {
+
+	value, exists := properties[s.Property]
+	if !exists || value == nil {
+		return s.Default.Color, s.Default.Width
+	}
+
+	if s.Ramp == RAMP_CATEGORICAL {
+		for _, c := range s.Classes {
+			if matches(value, c.Value) {
+				return c.Color, c.Width
+			}
+		}
+		return s.Default.Color, s.Default.Width
+	}
+
+	v, ok := s.number(value)
+	if !ok {
+		return s.Default.Color, s.Default.Width
+	}
+
+	return s.interpolate(v)
+}
+
+// Legend lists stops or classes followed by default
+func (s *Style) Legend() []LegendItem {
+	var result []LegendItem
+
+	for _, stop := range s.Stops {
+		label := stop.Label
+		if len(label) == 0 {
+			label = strconv.FormatFloat(stop.Value, 'f', -1, 64)
+		}
+		result = append(result, LegendItem{label, stop.Color, stop.Width})
+	}
+
+	for _, c := range s.Classes {
+		label := c.Label
+		if len(label) == 0 {
+			label = c.Value
+		}
+		result = append(result, LegendItem{label, c.Color, c.Width})
+	}
+
+	if len(s.Default.Label) > 0 {
+		result = append(result, LegendItem{s.Default.Label, s.Default.Color, s.Default.Width})
+	}
+
+	return result
+}
+
+// numeric value of property, dates are converted to age in days
+func (s *Style) number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return 0, false
+		}
+		now := s.Now
+		if now.IsZero() {
+			now = time.Now()
+		}
+		return now.Sub(t).Hours() / 24, true
+	}
+	return 0, false
+}
+
+func (s *Style) interpolate(v float64) (string, float64) {
+	first := s.Stops[0]
+	last := s.Stops[len(s.Stops)-1]
+
+	if v <= first.Value {
+		return first.Color, first.Width
+	}
+	if v >= last.Value {
+		return last.Color, last.Width
+	}
+
+	for i := 1; i < len(s.Stops); i++ {
+		a, b := s.Stops[i-1], s.Stops[i]
+		if v <= b.Value {
+			k := (v - a.Value) / (b.Value - a.Value)
+			return mixColors(a.Color, b.Color, k), a.Width + (b.Width-a.Width)*k
+		}
+	}
+
+	return last.Color, last.Width
+}
+
+// single value or any item of list formatted as string equals to class value
+func matches(value interface{}, classValue string) bool {
+	switch v := value.(type) {
+	case []int64:
+		for _, item := range v {
+			if strconv.FormatInt(item, 10) == classValue {
+				return true
+			}
+		}
+		return false
+	case []interface{}:
+		for _, item := range v {
+			if fmt.Sprint(item) == classValue {
+				return true
+			}
+		}
+		return false
+	}
+	return fmt.Sprint(value) == classValue
+}
+
+// linear interpolation of #rrggbb colors
+func mixColors(a, b string, k float64) string {
+	ra, ga, ba, okA := parseColor(a)
+	rb, gb, bb, okB := parseColor(b)
+	if !okA || !okB {
+		return a
+	}
+
+	mix := func(x, y uint8) uint8 {
+		return uint8(math.Round(float64(x) + (float64(y)-float64(x))*k))
+	}
+
+	return fmt.Sprintf("#%02x%02x%02x", mix(ra, rb), mix(ga, gb), mix(ba, bb))
+}
+
+func parseColor(c string) (uint8, uint8, uint8, bool) {
+	if len(c) != 7 || c[0] != '#' {
+		return 0, 0, 0, false
+	}
+	rgb, err := strconv.ParseUint(c[1:], 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return uint8(rgb >> 16), uint8(rgb >> 8), uint8(rgb), true
+}
@@ -0,0 +1,92 @@
+package style
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContinuous(t *testing.T) {
+	s := Predefined("popularity")
+
+	color, width := s.Line(map[string]interface{}{"track_count": 1})
+	assert.Equal(t, "#0065a2", color)
+	assert.Equal(t, 2.0, width)
+
+	// half way between stops 4 and 10
+	color, width = s.Line(map[string]interface{}{"track_count": 7})
+	assert.Equal(t, "#ff7f59", color)
+	assert.Equal(t, 4.5, width)
+
+	color, width = s.Line(map[string]interface{}{"track_count": 100.0})
+	assert.Equal(t, "#ff5c77", color)
+	assert.Equal(t, 5.0, width)
+
+	color, width = s.Line(map[string]interface{}{})
+	assert.Equal(t, "#999999", color)
+	assert.Equal(t, 1.0, width)
+}
+
+func TestDate(t *testing.T) {
+	s := Predefined("recency")
+	s.Now = time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	color, width := s.Line(map[string]interface{}{"last_time": "2024-01-01T00:00:00Z"})
+	assert.Equal(t, "#ffa23a", color)
+	assert.Equal(t, 3.0, width)
+
+	color, _ = s.Line(map[string]interface{}{"last_time": "yesterday"})
+	assert.Equal(t, "#cccccc", color)
+
+	legend := s.Legend()
+	assert.Len(t, legend, 5)
+	assert.Equal(t, LegendItem{"unknown", "#cccccc", 1}, legend[4])
+
+	// predefined styles are not modified
+	assert.True(t, Recency.Now.IsZero())
+}
+
+func TestCategorical(t *testing.T) {
+	s, err := Load("track:2")
+	assert.Nil(t, err)
+
+	color, width := s.Line(map[string]interface{}{"tracks": []int64{1, 2}})
+	assert.Equal(t, "#ff5c77", color)
+	assert.Equal(t, 4.0, width)
+
+	// decoded from json
+	color, _ = s.Line(map[string]interface{}{"tracks": []interface{}{2.0}})
+	assert.Equal(t, "#ff5c77", color)
+
+	color, width = s.Line(map[string]interface{}{"tracks": []int64{1, 3}})
+	assert.Equal(t, "#999999", color)
+	assert.Equal(t, 1.0, width)
+
+	_, err = Load("track:x")
+	assert.NotNil(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "style.json")
+	content := `{"title": "Surface", "property": "surface", "ramp": "categorical",
+		"classes": [{"value": "gravel", "color": "#ffa23a", "width": 3}],
+		"default": {"color": "#000000", "width": 1}}`
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+
+	s, err := Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "Surface", s.Title)
+
+	color, _ := s.Line(map[string]interface{}{"surface": "gravel"})
+	assert.Equal(t, "#ffa23a", color)
+
+	assert.Nil(t, os.WriteFile(path, []byte(`{"property": "count", "ramp": "continuous"}`), 0644))
+	_, err = Load(path)
+	assert.NotNil(t, err)
+
+	_, err = Load("unknown")
+	assert.NotNil(t, err)
+}
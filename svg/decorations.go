@@ -0,0 +1,87 @@
+package svg
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"mnezerka/geonet/utils"
+)
+
+const DECORATION_STYLE = "stroke: black; stroke-width: 2; fill: none"
+const DECORATION_TEXT_STYLE = "font-family: sans-serif; font-size: 12px"
+
+// WithScaleBar enables metric scale bar in bottom left corner
+func WithScaleBar(enabled bool) Option {
+	return func(svg *SVG) {
+		svg.scaleBar = enabled
+	}
+}
+
+// WithNorthArrow enables arrow pointing to the north in top right corner
+func WithNorthArrow(enabled bool) Option {
+	return func(svg *SVG) {
+		svg.northArrow = enabled
+	}
+}
+
+// scale bar of round length (1, 2 or 5 * 10^n meters) taking at most
+// quarter of svg width, scale is measured in the center of the content
+//
+//	500 m
+//	|_______|
+func drawScaleBar(sf ScaleFunc, w io.Writer, ps [][]float64, width, height float64, padding Padding) {
+
+	if len(ps) < 2 {
+		return
+	}
+
+	bb := getBoundingBox(ps)
+	lng := (bb[0] + bb[2]) / 2
+	lat := (bb[1] + bb[3]) / 2
+
+	// meters per svg unit in horizontal direction
+	const step = 0.01
+	x1, _ := sf(lng, lat)
+	x2, _ := sf(lng+step, lat)
+	if x2 == x1 {
+		return
+	}
+	metersPerUnit := utils.HaversineDistance(lat, lng, lat, lng+step) / math.Abs(x2-x1)
+
+	meters := roundLength((width - padding.Left - padding.Right) / 4 * metersPerUnit)
+	length := meters / metersPerUnit
+
+	label := fmt.Sprintf("%g m", meters)
+	if meters >= 1000 {
+		label = fmt.Sprintf("%g km", meters/1000)
+	}
+
+	x := padding.Left
+	y := height - padding.Bottom/2
+	fmt.Fprintf(w, `<g class="scale-bar"><path d="M%f %f V%f H%f V%f" style="%s"/><text x="%f" y="%f" style="%s">%s</text></g>`,
+		x, y-6, y, x+length, y-6, DECORATION_STYLE, x, y-10, DECORATION_TEXT_STYLE, label)
+}
+
+// the biggest 1, 2 or 5 * 10^n not greater than limit
+func roundLength(limit float64) float64 {
+	if limit <= 0 {
+		return 0
+	}
+
+	base := math.Pow(10, math.Floor(math.Log10(limit)))
+	for _, k := range []float64{5, 2} {
+		if k*base <= limit {
+			return k * base
+		}
+	}
+
+	return base
+}
+
+// arrow with letter N below, all supported projections have north up
+func drawNorthArrow(w io.Writer, width float64) {
+	x := width - 20
+	y := 10.0
+	fmt.Fprintf(w, `<g class="north-arrow"><path d="M%f %f L%f %f L%f %f L%f %f Z" style="fill: black"/><text x="%f" y="%f" text-anchor="middle" style="%s">N</text></g>`,
+		x, y, x+8, y+24, x, y+18, x-8, y+24, x, y+38, DECORATION_TEXT_STYLE)
+}
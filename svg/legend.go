@@ -0,0 +1,65 @@
+package svg
+
+import (
+	"fmt"
+	"html"
+	"io"
+)
+
+const LEGEND_ROW_HEIGHT = 18
+const LEGEND_SAMPLE_LENGTH = 24
+
+// LegendItem is one row of legend - line sample and its label
+type LegendItem struct {
+	Label string
+	Color string
+	Width float64
+}
+
+// WithLegend draws legend with given title and items in top left corner,
+// legend is not drawn if there are no items
+func WithLegend(title string, items []LegendItem) Option {
+	return func(svg *SVG) {
+		svg.legendTitle = title
+		svg.legend = items
+	}
+}
+
+// box with title and line samples, one row per item
+//
+//	Tracks
+//	━━━  1
+//	━━━  10+
+func drawLegend(w io.Writer, title string, items []LegendItem, padding Padding) {
+
+	if len(items) == 0 {
+		return
+	}
+
+	// text width is estimated, svg has no text metrics
+	chars := len(title)
+	for _, item := range items {
+		chars = max(chars, len(item.Label)+4)
+	}
+
+	x := padding.Left / 2
+	y := padding.Top / 2
+	width := float64(chars)*7 + 16
+	height := float64(len(items)+1)*LEGEND_ROW_HEIGHT + 8
+
+	fmt.Fprintf(w, `<g class="legend"><rect x="%f" y="%f" width="%f" height="%f" style="fill: white; fill-opacity: 0.8; stroke: #999999"/>`,
+		x, y, width, height)
+
+	x += 8
+	y += LEGEND_ROW_HEIGHT
+	fmt.Fprintf(w, `<text x="%f" y="%f" style="%s; font-weight: bold">%s</text>`, x, y, DECORATION_TEXT_STYLE, html.EscapeString(title))
+
+	for _, item := range items {
+		y += LEGEND_ROW_HEIGHT
+		fmt.Fprintf(w, `<path d="M%f %f H%f" style="stroke: %s; stroke-width: %g; fill: none"/><text x="%f" y="%f" style="%s">%s</text>`,
+			x, y-4, x+LEGEND_SAMPLE_LENGTH, item.Color, item.Width,
+			x+LEGEND_SAMPLE_LENGTH+8, y, DECORATION_TEXT_STYLE, html.EscapeString(item.Label))
+	}
+
+	fmt.Fprint(w, `</g>`)
+}
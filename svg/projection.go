@@ -0,0 +1,71 @@
+package svg
+
+import (
+	"fmt"
+	"math"
+	"mnezerka/geonet/utils"
+)
+
+// max latitude of web mercator
+const MERCATOR_MAX_LAT = 85.05112878
+
+// names of projections
+const PROJECTION_NONE = "none"
+const PROJECTION_MERCATOR = "mercator"
+const PROJECTION_EQUIRECTANGULAR = "equirectangular"
+
+// Projection creates function converting lng, lat to planar coordinates
+// (y grows to the north), all points of the svg are passed to allow
+// projections centered to the content
+type Projection func(ps [][]float64) func(lng, lat float64) (float64, float64)
+
+// PlateCarree maps lng, lat linearly to x, y (no projection), content is
+// stretched horizontally out of equator
+func PlateCarree(ps [][]float64) func(lng, lat float64) (float64, float64) {
+	return func(lng, lat float64) (float64, float64) { return lng, lat }
+}
+
+// WebMercator is projection of web maps (osm, google), x and y are in
+// meters on equator
+func WebMercator(ps [][]float64) func(lng, lat float64) (float64, float64) {
+	return func(lng, lat float64) (float64, float64) {
+		lat = math.Max(-MERCATOR_MAX_LAT, math.Min(MERCATOR_MAX_LAT, lat))
+		x := utils.EARTH_RADIUS_METERS * lng * math.Pi / 180
+		y := utils.EARTH_RADIUS_METERS * math.Log(math.Tan(math.Pi/4+lat*math.Pi/360))
+		return x, y
+	}
+}
+
+// Equirectangular is local projection with longitude scaled by cos of
+// latitude of content center, x and y are in meters (precise for small
+// areas)
+func Equirectangular(ps [][]float64) func(lng, lat float64) (float64, float64) {
+	bb := getBoundingBox(ps)
+	k := math.Cos((bb[1] + bb[3]) / 2 * math.Pi / 180)
+
+	return func(lng, lat float64) (float64, float64) {
+		x := utils.EARTH_RADIUS_METERS * lng * math.Pi / 180 * k
+		y := utils.EARTH_RADIUS_METERS * lat * math.Pi / 180
+		return x, y
+	}
+}
+
+// ProjectionByName returns projection for name used in config and cli
+func ProjectionByName(name string) (Projection, error) {
+	switch name {
+	case PROJECTION_NONE, "":
+		return PlateCarree, nil
+	case PROJECTION_MERCATOR:
+		return WebMercator, nil
+	case PROJECTION_EQUIRECTANGULAR:
+		return Equirectangular, nil
+	}
+	return nil, fmt.Errorf("unknown projection: %s", name)
+}
+
+// WithProjection configures projection of lng, lat coordinates
+func WithProjection(p Projection) Option {
+	return func(svg *SVG) {
+		svg.projection = p
+	}
+}
@@ -32,6 +32,11 @@ type customFeatureDecorator func(w io.Writer, sf ScaleFunc, feature *geojson.Fea
 type SVG struct {
 	useProp            func(string) bool
 	padding            Padding
+	projection         Projection
+	scaleBar           bool
+	northArrow         bool
+	legendTitle        string
+	legend             []LegendItem
 	attributes         map[string]string
 	featureDecorator   customFeatureDecorator
 	geometries         []*geojson.Geometry
@@ -52,6 +57,7 @@ func NewSVG() *SVG {
 		useProp:          func(prop string) bool { return prop == "class" },
 		featureDecorator: func(w io.Writer, sf ScaleFunc, feature *geojson.Feature) {},
 		attributes:       make(map[string]string),
+		projection:       PlateCarree,
 	}
 }
 
@@ -63,15 +69,27 @@ func (svg *SVG) Draw(width, height float64, opts ...Option) string {
 	}
 
 	points := svg.points()
-	sf := makeScaleFunc(width, svg.padding, points)
 
-	bb := getBoundingBox(points)
+	// bounding box and scaling are computed from projected points
+	project := svg.projection(points)
+	projected := make([][]float64, 0, len(points))
+	for _, p := range points {
+		x, y := project(p[0], p[1])
+		projected = append(projected, []float64{x, y})
+	}
+	psf := makeScaleFunc(width, svg.padding, projected)
+	sf := func(lng, lat float64) (float64, float64) {
+		return psf(project(lng, lat))
+	}
+
+	bb := getBoundingBox(projected)
 	log.Debugf("bounding box: %v", bb)
-	minX, minY := sf(bb[0], bb[1])
-	maxX, maxY := sf(bb[2], bb[3])
+	minX, minY := psf(bb[0], bb[1])
+	maxX, maxY := psf(bb[2], bb[3])
 	log.Debugf("bounding box recalculated: [%f %f][%v %v]", minX, minY, maxX, maxY)
-	// take minY instead of maxY due to scaling function, that reverses the Y axis coordinates (real world -> svg)
-	height = minY + svg.padding.Top + svg.padding.Bottom
+	// take minY instead of maxY due to scaling function, that reverses the Y axis coordinates (real world -> svg),
+	// minY already includes top padding
+	height = minY + svg.padding.Bottom
 	log.Debugf("auto correcting height to: %f", height)
 
 	content := bytes.NewBufferString("")
@@ -91,6 +109,14 @@ func (svg *SVG) Draw(width, height float64, opts ...Option) string {
 		}
 	}
 
+	if svg.scaleBar {
+		drawScaleBar(sf, content, points, width, height, svg.padding)
+	}
+	if svg.northArrow {
+		drawNorthArrow(content, width)
+	}
+	drawLegend(content, svg.legendTitle, svg.legend, svg.padding)
+
 	attributes := makeAttributes(svg.attributes)
 	return fmt.Sprintf(`<svg width="%f" height="%f"%s>%s</svg>`, width, height, attributes, content)
 }
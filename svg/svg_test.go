@@ -0,0 +1,84 @@
+package svg
+
+import (
+	"math"
+	"mnezerka/geonet/utils"
+	"regexp"
+	"strconv"
+	"testing"
+
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/stretchr/testify/assert"
+)
+
+// square 1km x 1km at 50N
+func newTestCollection() *geojson.FeatureCollection {
+	dLat := 1000 / (utils.EARTH_RADIUS_METERS * math.Pi / 180)
+	dLng := dLat / math.Cos(50*math.Pi/180)
+
+	fc := geojson.NewFeatureCollection()
+	fc.AddFeature(geojson.NewLineStringFeature([][]float64{{14, 50}, {14 + dLng, 50}, {14 + dLng, 50 + dLat}, {14, 50 + dLat}}))
+	return fc
+}
+
+func drawnHeight(t *testing.T, content string) float64 {
+	m := regexp.MustCompile(`^<svg width="[0-9.]+" height="([0-9.]+)"`).FindStringSubmatch(content)
+	assert.Len(t, m, 2)
+	h, err := strconv.ParseFloat(m[1], 64)
+	assert.Nil(t, err)
+	return h
+}
+
+func TestProjection(t *testing.T) {
+
+	// without projection the square is stretched horizontally
+	s := NewSVG()
+	s.AddFeatureCollection(newTestCollection())
+	assert.InDelta(t, 100*math.Cos(50*math.Pi/180), drawnHeight(t, s.Draw(100, 100)), 0.5)
+
+	for _, p := range []Projection{Equirectangular, WebMercator} {
+		s := NewSVG()
+		s.AddFeatureCollection(newTestCollection())
+		assert.InDelta(t, 100, drawnHeight(t, s.Draw(100, 100, WithProjection(p))), 0.5)
+	}
+
+	// padding is added to height once
+	s = NewSVG()
+	s.AddFeatureCollection(newTestCollection())
+	assert.InDelta(t, 120, drawnHeight(t, s.Draw(120, 120, WithProjection(Equirectangular), WithPadding(Padding{10, 10, 10, 10}))), 0.5)
+
+	_, err := ProjectionByName("unknown")
+	assert.NotNil(t, err)
+}
+
+func TestScaleBar(t *testing.T) {
+	assert.Equal(t, 500.0, roundLength(700))
+	assert.Equal(t, 2000.0, roundLength(2500))
+	assert.Equal(t, 1.0, roundLength(1.9))
+
+	s := NewSVG()
+	s.AddFeatureCollection(newTestCollection())
+	content := s.Draw(400, 400, WithProjection(Equirectangular), WithScaleBar(true), WithNorthArrow(true))
+
+	// quarter of the width is 250m, bar of 200m is 80 units long
+	assert.Contains(t, content, `<path d="M0.000000 394.000000 V400.000000 H80.000000 V394.000000"`)
+	assert.Contains(t, content, ">200 m</text>")
+	assert.Contains(t, content, `<g class="north-arrow">`)
+}
+
+func TestLegend(t *testing.T) {
+	s := NewSVG()
+	s.AddFeatureCollection(newTestCollection())
+
+	content := s.Draw(400, 400, WithLegend("Tracks", nil))
+	assert.NotContains(t, content, `<g class="legend">`)
+
+	content = s.Draw(400, 400, WithLegend("Tracks", []LegendItem{
+		{Label: "1", Color: "#0065a2", Width: 2},
+		{Label: "10+ & more", Color: "#ff5c77", Width: 5},
+	}))
+	assert.Contains(t, content, `<g class="legend">`)
+	assert.Contains(t, content, ">Tracks</text>")
+	assert.Contains(t, content, `style="stroke: #ff5c77; stroke-width: 5; fill: none"`)
+	assert.Contains(t, content, ">10+ &amp; more</text>")
+}
@@ -45,6 +45,14 @@ function onCheckBoxClick(cb){
 
 //////////////////////////////////////// highlighting
 
+// tracks of feature, vector tiles keep list of tracks as json string
+function featureTracks(properties) {
+    if (typeof properties.tracks === 'string') {
+        return JSON.parse(properties.tracks)
+    }
+    return properties.tracks
+}
+
 function isFeatureOnTrack(feature, trackId) {
 
     // check if geojson object has relevant attributes
@@ -53,7 +61,7 @@ function isFeatureOnTrack(feature, trackId) {
         return false
     }
 
-    return feature.properties.tracks.indexOf(trackId) > -1
+    return featureTracks(feature.properties).indexOf(trackId) > -1
 }
 
 function highlightedAdd(trackId) {
@@ -63,6 +71,13 @@ function highlightedAdd(trackId) {
         return;
     }
 
+    // vector tiles are restyled
+    if (tilesUrl) {
+        trackLayers[trackId] = {id: trackId, layer: null}
+        matchesLayer.redraw()
+        return
+    }
+
     let l = L.geoJson(
         geojson,
         {
@@ -86,8 +101,14 @@ function highlightedRemove(trackId) {
         return;
     }
 
-    map.removeLayer(trackLayers[trackId].layer)
+    if (trackLayers[trackId].layer) {
+        map.removeLayer(trackLayers[trackId].layer)
+    }
     delete(trackLayers[trackId])
+
+    if (tilesUrl) {
+        matchesLayer.redraw()
+    }
 }
 
 
@@ -192,19 +213,132 @@ const styleFuncHighlighted = function(feature) {
 }
 
 
+// colors of features exported by diff (change property)
+const changeStyles = {
+    'added': {color: 'green', weight: 5},
+    'removed': {color: 'gray', dashArray: '5, 5'},
+    'changed': {color: 'orange'},
+    'unchanged': {color: 'blue', opacity: 0.4}
+}
+
+//////////////////////////////////////// data driven style
+
+// line style exported by geonet (see style package) - color and width of
+// line is given by feature property through continuous or categorical ramp
+function lineStyleFunc(properties) {
+    let value = properties[lineStyle.property]
+    let result = lineStyle.default
+
+    if (value !== undefined && value !== null) {
+        if (lineStyle.ramp === 'categorical') {
+            result = lineStyleClass(value) || lineStyle.default
+        } else {
+            result = lineStyleInterpolate(value) || lineStyle.default
+        }
+    }
+
+    return {color: result.color, weight: result.width, opacity: 1}
+}
+
+// first class matching value or any item of list, vector tiles keep lists as json string
+function lineStyleClass(value) {
+    if (typeof value === 'string' && value.startsWith('[')) {
+        value = JSON.parse(value)
+    }
+    let values = Array.isArray(value) ? value.map(String) : [String(value)]
+
+    for (let i = 0; i < lineStyle.classes.length; i++) {
+        if (values.indexOf(lineStyle.classes[i].value) > -1) {
+            return lineStyle.classes[i]
+        }
+    }
+}
+
+// color and width interpolated between stops, dates are converted to age in days
+function lineStyleInterpolate(value) {
+    if (typeof value === 'string') {
+        let time = Date.parse(value)
+        if (isNaN(time)) {
+            return
+        }
+        value = (Date.now() - time) / (24 * 3600 * 1000)
+    }
+
+    let stops = lineStyle.stops
+    if (value <= stops[0].value) {
+        return stops[0]
+    }
+    for (let i = 1; i < stops.length; i++) {
+        if (value <= stops[i].value) {
+            let a = stops[i - 1]
+            let b = stops[i]
+            let k = (value - a.value) / (b.value - a.value)
+            return {color: mixColors(a.color, b.color, k), width: a.width + (b.width - a.width) * k}
+        }
+    }
+    return stops[stops.length - 1]
+}
+
+function mixColors(a, b, k) {
+    let result = '#'
+    for (let i = 1; i < 7; i += 2) {
+        let x = parseInt(a.substring(i, i + 2), 16)
+        let y = parseInt(b.substring(i, i + 2), 16)
+        result += componentToHex(Math.round(x + (y - x) * k))
+    }
+    return result
+}
+
+function addLegend() {
+    let legend = L.control({position: 'bottomright'})
+
+    legend.onAdd = function() {
+        let el = L.DomUtil.create('div', 'legend')
+        let items = (lineStyle.stops || []).concat(lineStyle.classes || [])
+        if (lineStyle.default.label) {
+            items.push(lineStyle.default)
+        }
+
+        let elTitle = document.createElement('b')
+        elTitle.textContent = lineStyle.title
+        el.appendChild(elTitle)
+
+        for (let i = 0; i < items.length; i++) {
+            let elItem = document.createElement('div')
+            let elSample = document.createElement('i')
+            elSample.style.borderTop = items[i].width + 'px solid ' + items[i].color
+            elItem.appendChild(elSample)
+            elItem.appendChild(document.createTextNode(items[i].label || items[i].value))
+            el.appendChild(elItem)
+        }
+
+        return el
+    }
+
+    legend.addTo(map)
+}
+
 // executed for each geojson feature (point, linestring, etc...)
 const styleFunc = function(feature) {
 
     if (feature.geometry.type === 'LineString') {
+        if (feature.properties.change !== undefined) {
+            return changeStyles[feature.properties.change] || {}
+        }
+
+        if (lineStyle) {
+            return lineStyleFunc(feature.properties)
+        }
+
         let tstyle = {
-            color: getColorForTracks(feature.properties.tracks),
+            color: getColorForTracks(featureTracks(feature.properties)),
         }
         return tstyle
     }
 
     if (feature.geometry.type === 'Point') {
         let tstyle = {
-            fillColor: getColorForTracks(feature.properties.tracks),
+            fillColor: getColorForTracks(featureTracks(feature.properties)),
             radius: 5
         }
 
@@ -213,6 +347,10 @@ const styleFunc = function(feature) {
             tstyle.radius = 10 
          }
 
+        if (feature.properties.change !== undefined && changeStyles[feature.properties.change]) {
+            tstyle.fillColor = changeStyles[feature.properties.change].color
+        }
+
         return tstyle
     }
 
@@ -237,6 +375,10 @@ const onEachFeatureFunc = function (feature, layer) {
 }
 
 function openFeaturePopup(feature, layer) {
+    layer.bindPopup(featurePopupContent(feature)).openPopup();
+}
+
+function featurePopupContent(feature) {
     let el = document.createElement('div');
 
     // title
@@ -246,12 +388,32 @@ function openFeaturePopup(feature, layer) {
         el.appendChild(elTitle)
     }
 
+    // change (diff of two nets)
+    if (feature.properties.change !== undefined) {
+        let elChange = document.createElement('p');
+        elChange.innerHTML = 'change: ' + feature.properties.change
+        if (feature.properties.old_tracks !== undefined) {
+            elChange.innerHTML += ' (tracks before: ' + feature.properties.old_tracks.join(', ') + ')'
+        }
+        el.appendChild(elChange)
+    }
+
+    // usage
+    if (feature.properties.count !== undefined) {
+        let elUsage = document.createElement('p');
+        elUsage.innerHTML = 'passes: ' + feature.properties.count + ', tracks: ' + feature.properties.track_count
+        if (feature.properties.last_time !== undefined) {
+            elUsage.innerHTML += ', last: ' + feature.properties.last_time.substring(0, 10)
+        }
+        el.appendChild(elUsage)
+    }
+
     // tracks
     if (feature.properties.tracks !== undefined) {
 
         let elTracks = document.createElement('div');
 
-        let tracks = feature.properties.tracks;
+        let tracks = featureTracks(feature.properties);
         for (let i = 0; i < tracks.length; i++) {
 
             let elTrack = document.createElement('div');
@@ -270,16 +432,55 @@ function openFeaturePopup(feature, layer) {
         el.appendChild(elTracks);
     }
 
-    layer.bindPopup(el).openPopup();
+    return el
 }
 
+// style of feature from vector tiles (only properties are known),
+// highlighted tracks are drawn over the rest of the net
+const tileStyleFunc = function(properties, type) {
+    let feature = {type: 'Feature', geometry: {type: type}, properties: properties}
+
+    let tstyle = styleFunc(feature)
+    for (let trackId in trackLayers) {
+        if (isFeatureOnTrack(feature, Number(trackId))) {
+            tstyle = styleFuncHighlighted(feature)
+        }
+    }
 
-matchesLayer = L.geoJson(
-    geojson,
-    {
-        style: styleFunc,
-        pointToLayer: pointToLayerFunc,
-        onEachFeature: onEachFeatureFunc
+    if (type === 'Point') {
+        tstyle = Object.assign({}, geojsonMarkerOptions, tstyle, {fill: true})
+    }
+
+    return tstyle
+}
+
+if (tilesUrl) {
+    matchesLayer = L.vectorGrid.protobuf(tilesUrl, {
+        vectorTileLayerStyles: {
+            edges: function(properties) { return tileStyleFunc(properties, 'LineString') },
+            points: function(properties) { return tileStyleFunc(properties, 'Point') }
+        },
+        maxNativeZoom: tilesMaxZoom,
+        interactive: true
+    }).on('click', function(e) {
+        let type = e.layer instanceof L.CircleMarker ? 'Point' : 'LineString'
+        let feature = {type: 'Feature', geometry: {type: type}, properties: e.layer.properties}
+        L.popup().setLatLng(e.latlng).setContent(featurePopupContent(feature)).openOn(map)
     }).addTo(map);
 
-map.fitBounds(matchesLayer.getBounds())
+    map.fitBounds(bounds)
+} else {
+    matchesLayer = L.geoJson(
+        geojson,
+        {
+            style: styleFunc,
+            pointToLayer: pointToLayerFunc,
+            onEachFeature: onEachFeatureFunc
+        }).addTo(map);
+
+    map.fitBounds(matchesLayer.getBounds())
+}
+
+if (lineStyle) {
+    addLegend()
+}
@@ -0,0 +1,21 @@
+package tiles
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/paulmach/orb/maptile"
+)
+
+// DirWriter writes tiles to files dir/z/x/y.pbf (not compressed, could be
+// served by any static http server)
+func DirWriter(dir string) TileFunc {
+	return func(tile maptile.Tile, data []byte) error {
+		tileDir := filepath.Join(dir, fmt.Sprint(tile.Z), fmt.Sprint(tile.X))
+		if err := os.MkdirAll(tileDir, 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(tileDir, fmt.Sprintf("%d.pbf", tile.Y)), data, 0644)
+	}
+}
@@ -0,0 +1,304 @@
+package tiles
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/maptile"
+)
+
+// PMTiles v3 archive (https://github.com/protomaps/PMTiles/blob/main/spec/v3/spec.md)
+//
+// +--------+----------------+----------+------------------+-----------+
+// | header | root directory | metadata | leaf directories | tile data |
+// +--------+----------------+----------+------------------+-----------+
+//
+// header and root directory have to fit into first 16 KiB, entries are
+// split into leaf directories if there are too many tiles
+const PMTILES_HEADER_LENGTH = 127
+const PMTILES_ROOT_MAX_LENGTH = 16384 - PMTILES_HEADER_LENGTH
+
+const PMTILES_COMPRESSION_GZIP = 2
+const PMTILES_TILE_TYPE_MVT = 1
+
+type pmtilesEntry struct {
+	tileId    uint64
+	offset    uint64
+	length    uint32
+	runLength uint32
+}
+
+// PMTiles collects tiles in memory, archive is written by Write
+type PMTiles struct {
+	entries []pmtilesEntry
+	data    bytes.Buffer
+	minZoom int
+	maxZoom int
+}
+
+func NewPMTiles() *PMTiles {
+	return &PMTiles{minZoom: math.MaxInt, maxZoom: -1}
+}
+
+// AddTile stores gzip compressed tile, it could be used as TileFunc
+func (p *PMTiles) AddTile(tile maptile.Tile, data []byte) error {
+
+	compressed, err := gzipBytes(data)
+	if err != nil {
+		return err
+	}
+
+	p.entries = append(p.entries, pmtilesEntry{
+		tileId:    ZxyToId(uint8(tile.Z), tile.X, tile.Y),
+		offset:    uint64(p.data.Len()),
+		length:    uint32(len(compressed)),
+		runLength: 1,
+	})
+	p.data.Write(compressed)
+
+	p.minZoom = min(p.minZoom, int(tile.Z))
+	p.maxZoom = max(p.maxZoom, int(tile.Z))
+
+	return nil
+}
+
+// Write writes the archive, bound of content is stored in header
+func (p *PMTiles) Write(w io.Writer, bound orb.Bound) error {
+
+	// tile data are clustered - ordered by tile id
+	order := make([]int, len(p.entries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return p.entries[order[i]].tileId < p.entries[order[j]].tileId })
+
+	var tileData bytes.Buffer
+	entries := make([]pmtilesEntry, 0, len(p.entries))
+	for _, i := range order {
+		e := p.entries[i]
+		chunk := p.data.Bytes()[e.offset : e.offset+uint64(e.length)]
+		e.offset = uint64(tileData.Len())
+		tileData.Write(chunk)
+		entries = append(entries, e)
+	}
+
+	root, leaves, err := buildDirectories(entries)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := p.metadata()
+	if err != nil {
+		return err
+	}
+
+	minZoom, maxZoom := p.minZoom, p.maxZoom
+	if len(entries) == 0 {
+		minZoom, maxZoom = 0, 0
+	}
+
+	header := make([]byte, PMTILES_HEADER_LENGTH)
+	copy(header[0:7], "PMTiles")
+	header[7] = 3
+
+	offset := uint64(PMTILES_HEADER_LENGTH)
+	sections := [][]byte{root, metadata, leaves, tileData.Bytes()}
+	for i, section := range sections {
+		binary.LittleEndian.PutUint64(header[8+i*16:], offset)
+		binary.LittleEndian.PutUint64(header[16+i*16:], uint64(len(section)))
+		offset += uint64(len(section))
+	}
+
+	binary.LittleEndian.PutUint64(header[72:], uint64(len(entries))) // addressed tiles
+	binary.LittleEndian.PutUint64(header[80:], uint64(len(entries))) // tile entries
+	binary.LittleEndian.PutUint64(header[88:], uint64(len(entries))) // tile contents
+	header[96] = 1                                                   // clustered
+	header[97] = PMTILES_COMPRESSION_GZIP
+	header[98] = PMTILES_COMPRESSION_GZIP
+	header[99] = PMTILES_TILE_TYPE_MVT
+	header[100] = uint8(minZoom)
+	header[101] = uint8(maxZoom)
+	putE7(header[102:], bound.Min[0])
+	putE7(header[106:], bound.Min[1])
+	putE7(header[110:], bound.Max[0])
+	putE7(header[114:], bound.Max[1])
+	header[118] = uint8(minZoom)
+	putE7(header[119:], bound.Center()[0])
+	putE7(header[123:], bound.Center()[1])
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	for _, section := range sections {
+		if _, err := w.Write(section); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// description of layers used by viewers
+func (p *PMTiles) metadata() ([]byte, error) {
+
+	type vectorLayer struct {
+		Id      string            `json:"id"`
+		Fields  map[string]string `json:"fields"`
+		MinZoom int               `json:"minzoom"`
+		MaxZoom int               `json:"maxzoom"`
+	}
+
+	layers := []vectorLayer{}
+	for _, name := range []string{LAYER_EDGES, LAYER_POINTS} {
+		layers = append(layers, vectorLayer{
+			Id: name,
+			Fields: map[string]string{
+				"id":          "String",
+				"tracks":      "String",
+				"count":       "Number",
+				"track_count": "Number",
+			},
+			MinZoom: p.minZoom,
+			MaxZoom: p.maxZoom,
+		})
+	}
+
+	content, err := json.Marshal(map[string]interface{}{
+		"name":          "geonet",
+		"format":        "pbf",
+		"vector_layers": layers,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return gzipBytes(content)
+}
+
+// root directory and leaf directories (empty if all entries fit into root)
+func buildDirectories(entries []pmtilesEntry) ([]byte, []byte, error) {
+
+	root, err := serializeDirectory(entries)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(root) <= PMTILES_ROOT_MAX_LENGTH {
+		return root, nil, nil
+	}
+
+	for leafSize := 4096; ; leafSize *= 2 {
+		var leaves bytes.Buffer
+		var rootEntries []pmtilesEntry
+
+		for i := 0; i < len(entries); i += leafSize {
+			chunk := entries[i:min(i+leafSize, len(entries))]
+			leaf, err := serializeDirectory(chunk)
+			if err != nil {
+				return nil, nil, err
+			}
+			rootEntries = append(rootEntries, pmtilesEntry{
+				tileId: chunk[0].tileId,
+				offset: uint64(leaves.Len()),
+				length: uint32(len(leaf)),
+				// run length 0 = entry points to leaf directory
+			})
+			leaves.Write(leaf)
+		}
+
+		root, err = serializeDirectory(rootEntries)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(root) <= PMTILES_ROOT_MAX_LENGTH {
+			return root, leaves.Bytes(), nil
+		}
+	}
+}
+
+// columns of varints (delta encoded tile ids, run lengths, lengths and
+// offsets where 0 = continuation of previous entry), compressed by gzip
+func serializeDirectory(entries []pmtilesEntry) ([]byte, error) {
+
+	var buf []byte
+	buf = binary.AppendUvarint(buf, uint64(len(entries)))
+
+	var lastId uint64
+	for _, e := range entries {
+		buf = binary.AppendUvarint(buf, e.tileId-lastId)
+		lastId = e.tileId
+	}
+
+	for _, e := range entries {
+		buf = binary.AppendUvarint(buf, uint64(e.runLength))
+	}
+
+	for _, e := range entries {
+		buf = binary.AppendUvarint(buf, uint64(e.length))
+	}
+
+	for i, e := range entries {
+		if i > 0 && e.offset == entries[i-1].offset+uint64(entries[i-1].length) {
+			buf = binary.AppendUvarint(buf, 0)
+		} else {
+			buf = binary.AppendUvarint(buf, e.offset+1)
+		}
+	}
+
+	return gzipBytes(buf)
+}
+
+// ZxyToId converts tile coordinates to position on hilbert curve, ids of
+// lower zoom levels go first
+func ZxyToId(z uint8, x, y uint32) uint64 {
+
+	// number of tiles of all lower zoom levels
+	id := (uint64(1)<<(2*uint64(z)) - 1) / 3
+
+	n := uint32(1) << z
+	for s := n / 2; s > 0; s /= 2 {
+		var rx, ry uint32
+		if x&s > 0 {
+			rx = 1
+		}
+		if y&s > 0 {
+			ry = 1
+		}
+		id += uint64(s) * uint64(s) * uint64((3*rx)^ry)
+
+		// rotate quadrant
+		if ry == 0 {
+			if rx == 1 {
+				x = n - 1 - x
+				y = n - 1 - y
+			}
+			x, y = y, x
+		}
+	}
+
+	return id
+}
+
+func putE7(b []byte, value float64) {
+	binary.LittleEndian.PutUint32(b, uint32(int32(math.Round(value*1e7))))
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
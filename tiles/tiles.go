@@ -0,0 +1,192 @@
+// Package tiles cuts geojson features of the net into mapbox vector tiles
+// (MVT), tiles are written to directory (z/x/y.pbf) or to single PMTiles
+// archive
+package tiles
+
+import (
+	"fmt"
+	"sort"
+
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/mvt"
+	orbgeojson "github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/maptile"
+	"github.com/paulmach/orb/simplify"
+)
+
+// names of layers in generated tiles
+const LAYER_EDGES = "edges"
+const LAYER_POINTS = "points"
+
+// buffer around tile in tile units (extent is 4096) to avoid gaps between
+// lines clipped in neighbouring tiles
+const TILE_BUFFER = 64
+
+// tolerance of line simplification in tile units, half a pixel of 256px
+// tile (16 units = 1 pixel)
+const SIMPLIFY_TOLERANCE = 8
+
+// called for each generated tile with encoded (not compressed) content
+type TileFunc func(tile maptile.Tile, data []byte) error
+
+type feature struct {
+	layer    string
+	geometry orb.Geometry
+	bound    orb.Bound
+	props    orbgeojson.Properties
+}
+
+// Generate cuts features (lines and points) into tiles for all zoom levels
+// in range, lines are simplified separately for each zoom level, tiles are
+// passed to each in order of zoom, x and y
+func Generate(collection *geojson.FeatureCollection, minZoom, maxZoom int, each TileFunc) error {
+
+	if minZoom < 0 || maxZoom > 24 || minZoom > maxZoom {
+		return fmt.Errorf("invalid zoom range %d-%d", minZoom, maxZoom)
+	}
+
+	features := convertFeatures(collection)
+
+	for z := minZoom; z <= maxZoom; z++ {
+
+		// features touching each tile of the zoom level
+		byTile := map[maptile.Tile][]*feature{}
+		for _, f := range features {
+			for _, t := range coveringTiles(f.bound, maptile.Zoom(z)) {
+				byTile[t] = append(byTile[t], f)
+			}
+		}
+
+		keys := make([]maptile.Tile, 0, len(byTile))
+		for t := range byTile {
+			keys = append(keys, t)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].X != keys[j].X {
+				return keys[i].X < keys[j].X
+			}
+			return keys[i].Y < keys[j].Y
+		})
+
+		for _, t := range keys {
+			data, err := encodeTile(t, byTile[t])
+			if err != nil {
+				return err
+			}
+
+			if data == nil {
+				continue
+			}
+
+			if err := each(t, data); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Bound of all features
+func Bound(collection *geojson.FeatureCollection) orb.Bound {
+	var bound orb.Bound
+	for i, f := range convertFeatures(collection) {
+		if i == 0 {
+			bound = f.bound
+		} else {
+			bound = bound.Union(f.bound)
+		}
+	}
+	return bound
+}
+
+// only points and line strings are supported (the net has no polygons)
+func convertFeatures(collection *geojson.FeatureCollection) []*feature {
+	var result []*feature
+
+	for _, f := range collection.Features {
+		var g orb.Geometry
+		layer := LAYER_EDGES
+
+		switch f.Geometry.Type {
+		case geojson.GeometryLineString:
+			ls := make(orb.LineString, 0, len(f.Geometry.LineString))
+			for _, c := range f.Geometry.LineString {
+				ls = append(ls, orb.Point{c[0], c[1]})
+			}
+			g = ls
+		case geojson.GeometryPoint:
+			g = orb.Point{f.Geometry.Point[0], f.Geometry.Point[1]}
+			layer = LAYER_POINTS
+		default:
+			continue
+		}
+
+		result = append(result, &feature{
+			layer:    layer,
+			geometry: g,
+			bound:    g.Bound(),
+			props:    orbgeojson.Properties(f.Properties),
+		})
+	}
+
+	return result
+}
+
+// tiles of given zoom intersecting the bound
+func coveringTiles(bound orb.Bound, z maptile.Zoom) []maptile.Tile {
+	// tile y grows to the south
+	nw := maptile.At(orb.Point{bound.Min[0], bound.Max[1]}, z)
+	se := maptile.At(orb.Point{bound.Max[0], bound.Min[1]}, z)
+
+	limit := uint32(1)<<z - 1
+
+	var result []maptile.Tile
+	for x := nw.X; x <= se.X && x <= limit; x++ {
+		for y := nw.Y; y <= se.Y && y <= limit; y++ {
+			result = append(result, maptile.New(x, y, z))
+		}
+	}
+
+	return result
+}
+
+// project features to tile coordinates, clip, simplify and encode
+// to protobuf, nil is returned if nothing remains in the tile
+func encodeTile(t maptile.Tile, features []*feature) ([]byte, error) {
+
+	collections := map[string]*orbgeojson.FeatureCollection{}
+	for _, f := range features {
+		fc, exists := collections[f.layer]
+		if !exists {
+			fc = orbgeojson.NewFeatureCollection()
+			collections[f.layer] = fc
+		}
+
+		of := orbgeojson.NewFeature(orb.Clone(f.geometry))
+		of.Properties = f.props
+		fc.Append(of)
+	}
+
+	layers := mvt.NewLayers(collections)
+	layers.ProjectToTile(t)
+	layers.Clip(orb.Bound{
+		Min: orb.Point{-TILE_BUFFER, -TILE_BUFFER},
+		Max: orb.Point{mvt.DefaultExtent + TILE_BUFFER, mvt.DefaultExtent + TILE_BUFFER},
+	})
+	layers.Simplify(simplify.DouglasPeucker(SIMPLIFY_TOLERANCE))
+	layers.RemoveEmpty(1, 1)
+
+	empty := true
+	for _, l := range layers {
+		if len(l.Features) > 0 {
+			empty = false
+		}
+	}
+	if empty {
+		return nil, nil
+	}
+
+	return mvt.Marshal(layers)
+}
@@ -0,0 +1,118 @@
+package tiles
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	geojson "github.com/paulmach/go.geojson"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/mvt"
+	"github.com/paulmach/orb/maptile"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCollection() *geojson.FeatureCollection {
+	collection := geojson.NewFeatureCollection()
+
+	line := geojson.NewLineStringFeature([][]float64{{14.0, 50.0}, {14.001, 50.0001}, {14.002, 50.0}, {14.5, 50.2}})
+	line.SetProperty("id", "1-2-3-4")
+	line.SetProperty("tracks", []int64{1, 2})
+	line.SetProperty("count", 2)
+	line.SetProperty("track_count", 2)
+	collection.AddFeature(line)
+
+	pnt := geojson.NewPointFeature([]float64{14.0, 50.0})
+	pnt.SetProperty("id", 1)
+	pnt.SetProperty("begin", true)
+	collection.AddFeature(pnt)
+
+	return collection
+}
+
+func TestZxyToId(t *testing.T) {
+	assert.Equal(t, uint64(0), ZxyToId(0, 0, 0))
+	assert.Equal(t, uint64(1), ZxyToId(1, 0, 0))
+	assert.Equal(t, uint64(2), ZxyToId(1, 0, 1))
+	assert.Equal(t, uint64(3), ZxyToId(1, 1, 1))
+	assert.Equal(t, uint64(4), ZxyToId(1, 1, 0))
+	assert.Equal(t, uint64(5), ZxyToId(2, 0, 0))
+	assert.Equal(t, uint64(20), ZxyToId(2, 3, 0))
+	assert.Equal(t, uint64(21), ZxyToId(3, 0, 0))
+}
+
+func TestGenerate(t *testing.T) {
+
+	generated := map[maptile.Tile][]byte{}
+	err := Generate(newTestCollection(), 0, 12, func(tile maptile.Tile, data []byte) error {
+		generated[tile] = data
+		return nil
+	})
+	assert.Nil(t, err)
+
+	// single tile for low zooms, line crosses more tiles on higher zooms
+	assert.Contains(t, generated, maptile.New(0, 0, 0))
+	assert.Contains(t, generated, maptile.At([2]float64{14.0, 50.0}, 12))
+	assert.Contains(t, generated, maptile.At([2]float64{14.5, 50.2}, 12))
+
+	layers, err := mvt.Unmarshal(generated[maptile.New(0, 0, 0)])
+	assert.Nil(t, err)
+	assert.Len(t, layers, 2)
+
+	for _, l := range layers {
+		assert.Len(t, l.Features, 1)
+		if l.Name == LAYER_EDGES {
+			// properties are kept, small detour is simplified on low zoom
+			assert.Equal(t, "[1,2]", l.Features[0].Properties["tracks"])
+			assert.Equal(t, float64(2), l.Features[0].Properties["track_count"])
+			assert.Equal(t, "1-2-3-4", l.Features[0].Properties["id"])
+			assert.Len(t, l.Features[0].Geometry.(orb.LineString), 2)
+		}
+	}
+
+	assert.NotNil(t, Generate(newTestCollection(), 5, 2, nil))
+}
+
+func TestPMTiles(t *testing.T) {
+
+	p := NewPMTiles()
+	assert.Nil(t, Generate(newTestCollection(), 0, 3, p.AddTile))
+
+	var buf bytes.Buffer
+	assert.Nil(t, p.Write(&buf, Bound(newTestCollection())))
+
+	content := buf.Bytes()
+	assert.Equal(t, "PMTiles", string(content[0:7]))
+	assert.Equal(t, byte(3), content[7])
+	assert.Equal(t, uint64(PMTILES_HEADER_LENGTH), binary.LittleEndian.Uint64(content[8:]))
+	assert.Equal(t, uint64(4), binary.LittleEndian.Uint64(content[80:])) // one tile per zoom
+	assert.Equal(t, byte(0), content[100])
+	assert.Equal(t, byte(3), content[101])
+	assert.Equal(t, int32(140000000), int32(binary.LittleEndian.Uint32(content[102:])))
+
+	// root directory lists tiles ordered by tile id
+	rootOffset := binary.LittleEndian.Uint64(content[8:])
+	rootLength := binary.LittleEndian.Uint64(content[16:])
+	zr, err := gzip.NewReader(bytes.NewReader(content[rootOffset : rootOffset+rootLength]))
+	assert.Nil(t, err)
+	dir, err := io.ReadAll(zr)
+	assert.Nil(t, err)
+
+	r := bytes.NewReader(dir)
+	n, _ := binary.ReadUvarint(r)
+	assert.Equal(t, uint64(4), n)
+	first, _ := binary.ReadUvarint(r)
+	assert.Equal(t, uint64(0), first)
+
+	// first tile could be decompressed and decoded
+	dataOffset := binary.LittleEndian.Uint64(content[56:])
+	zr, err = gzip.NewReader(bytes.NewReader(content[dataOffset:]))
+	assert.Nil(t, err)
+	zr.Multistream(false)
+	tile, err := io.ReadAll(zr)
+	assert.Nil(t, err)
+	_, err = mvt.Unmarshal(tile)
+	assert.Nil(t, err)
+}
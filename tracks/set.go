@@ -3,9 +3,12 @@ package tracks
 import (
 	"encoding/json"
 	"fmt"
+	"html"
 	"io"
 	"mnezerka/geonet/config"
+	"mnezerka/geonet/kml"
 	"mnezerka/geonet/log"
+	"mnezerka/geonet/raster"
 	"mnezerka/geonet/svg"
 	"mnezerka/geonet/utils"
 
@@ -100,40 +103,50 @@ func (s *Set) ExportGeoJson() []byte {
 	return bytesJson
 }
 
-func (s *Set) ExportSvg() string {
-
-	gs := s.ToGeoJson(func(feature *geojson.Feature) {
+// svg style of tracks, each track has its own color
+func setSvgStyle(feature *geojson.Feature) {
 
-		if feature.Geometry.Type == "LineString" {
-			color := "black"
-			if val, exists := feature.Properties["id"]; exists {
-				color = utils.GetDarkPastelColor(val.(int))
-			}
+	if feature.Geometry.Type == "LineString" {
+		color := "black"
+		if val, exists := feature.Properties["id"]; exists {
+			color = utils.GetDarkPastelColor(val.(int))
+		}
 
-			style := fmt.Sprintf("stroke: %s; stroke-width: 2; fill: none", color)
-			feature.SetProperty("style", style)
+		style := fmt.Sprintf("stroke: %s; stroke-width: 2; fill: none", color)
+		feature.SetProperty("style", style)
+	}
+	if feature.Geometry.Type == "Point" {
+		color := "black"
+		radius := "5px"
+		if val, exists := feature.Properties["track"]; exists {
+			color = utils.GetDarkPastelColor(val.(int))
 		}
-		if feature.Geometry.Type == "Point" {
-			color := "black"
-			radius := "5px"
-			if val, exists := feature.Properties["track"]; exists {
-				color = utils.GetDarkPastelColor(val.(int))
-			}
 
-			style := fmt.Sprintf("stroke: none; fill: %s; r: %s", color, radius)
+		style := fmt.Sprintf("stroke: none; fill: %s; r: %s", color, radius)
 
-			feature.SetProperty("style", style)
-		}
+		feature.SetProperty("style", style)
+	}
+}
+
+func (s *Set) ExportSvg() string {
 
-	})
+	gs := s.ToGeoJson(setSvgStyle)
 
 	svgOut := svg.NewSVG()
 	svgOut.AddFeatureCollection(gs)
 
+	projection, err := svg.ProjectionByName(config.Cfg.SvgProjection)
+	if err != nil {
+		log.ExitWithError(err)
+	}
+
 	got := svgOut.Draw(
 		float64(config.Cfg.SvgWidth),
 		float64(config.Cfg.SvgHeight),
 		svg.WithAttribute("xmlns", "http://www.w3.org/2000/svg"),
+		svg.WithProjection(projection),
+		svg.WithScaleBar(config.Cfg.SvgScaleBar),
+		svg.WithNorthArrow(config.Cfg.SvgNorthArrow),
 		svg.UseProperties([]string{"style"}),
 		svg.WithPadding(svg.Padding{
 			Top:    float64(config.Cfg.SvgPadding),
@@ -169,3 +182,103 @@ func (s *Set) ExportSvg() string {
 	)
 	return got
 }
+
+func (s *Set) toKml() *kml.KML {
+
+	k := kml.NewKML("Tracks")
+
+	colors := 0
+	for utils.GetDarkPastelColor(colors) != "" {
+		k.AddLineStyle(fmt.Sprintf("track-%d", colors), kml.Color(utils.GetDarkPastelColor(colors), 1), 3)
+		colors++
+	}
+
+	gs := s.ToGeoJson(nil)
+
+	for _, feature := range gs.Features {
+		if feature.Geometry.Type != geojson.GeometryLineString {
+			continue
+		}
+
+		i := feature.Properties["id"].(int)
+		t := s.tracks[i]
+
+		name := t.Meta.TrackTitle
+		if len(name) == 0 {
+			name = utils.GetBasename(t.FilePath)
+		}
+
+		var description string
+		if len(t.Meta.TrackUrl) > 0 {
+			url := html.EscapeString(t.Meta.TrackUrl)
+			description = fmt.Sprintf(`<a href="%s">%s</a>`, url, url)
+		}
+
+		k.AddFeature(feature, kml.Placemark{
+			Name:        name,
+			Description: description,
+			StyleId:     fmt.Sprintf("track-%d", i%colors),
+		})
+	}
+
+	return k
+}
+
+func (s *Set) ExportKml() []byte {
+
+	content, err := s.toKml().Bytes()
+	if err != nil {
+		log.ExitWithError(err)
+	}
+
+	return content
+}
+
+func (s *Set) ExportKmz() []byte {
+
+	content, err := s.toKml().Kmz()
+	if err != nil {
+		log.ExitWithError(err)
+	}
+
+	return content
+}
+
+func (s *Set) ExportPng() []byte {
+
+	r := raster.NewRaster()
+	r.AddFeatureCollection(s.ToGeoJson(setSvgStyle))
+
+	projection, err := svg.ProjectionByName(config.Cfg.SvgProjection)
+	if err != nil {
+		log.ExitWithError(err)
+	}
+
+	img := r.Draw(
+		float64(config.Cfg.PngWidth),
+		float64(config.Cfg.PngHeight),
+		raster.WithDpi(float64(config.Cfg.PngDpi)),
+		raster.WithProjection(projection),
+		raster.WithStyle(func(feature *geojson.Feature) raster.Style {
+			style, _ := feature.Properties["style"].(string)
+			result := raster.ParseStyle(style)
+			if feature.Geometry.Type == geojson.GeometryLineString {
+				result.StrokeWidth = config.Cfg.PngLineWidth
+			}
+			return result
+		}),
+		raster.WithPadding(raster.Padding{
+			Top:    float64(config.Cfg.PngPadding),
+			Right:  float64(config.Cfg.PngPadding),
+			Bottom: float64(config.Cfg.PngPadding),
+			Left:   float64(config.Cfg.PngPadding),
+		}),
+	)
+
+	content, err := raster.EncodePng(img, float64(config.Cfg.PngDpi))
+	if err != nil {
+		log.ExitWithError(err)
+	}
+
+	return content
+}
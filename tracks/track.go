@@ -74,6 +74,15 @@ func NewTrack(filePath string) *Track {
 	return &t
 }
 
+// track built from [lat, lng] positions (e.g. in tests)
+func NewTrackFromPoints(points [][2]float64) *Track {
+	t := &Track{}
+	for _, p := range points {
+		t.Points = append(t.Points, gpx.GPXPoint{Point: gpx.Point{Latitude: p[0], Longitude: p[1]}})
+	}
+	return t
+}
+
 func (t *Track) ReadPoints() {
 
 	var err error
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"math"
+
+	"github.com/golang/geo/s2"
+)
+
+// mean radius of the earth
+const EARTH_RADIUS_METERS = 6371e3
+
+// HaversineDistance returns distance in meters of two positions given by
+// latitude and longitude in degrees
+func HaversineDistance(lat1, lng1, lat2, lng2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	deltaPhi := (lat2 - lat1) * math.Pi / 180
+	deltaLambda := (lng2 - lng1) * math.Pi / 180
+
+	a := math.Sin(deltaPhi/2)*math.Sin(deltaPhi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*math.Sin(deltaLambda/2)*math.Sin(deltaLambda/2)
+
+	return 2 * EARTH_RADIUS_METERS * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+func coordToPoint(c []float64) s2.Point {
+	return s2.PointFromLatLng(s2.LatLngFromDegrees(c[1], c[0]))
+}
+
+// DistanceFromSegment returns distance in meters of point p from the line
+// segment a-b, points are [lng, lat] coordinates
+func DistanceFromSegment(p, a, b []float64) float64 {
+	return float64(s2.DistanceFromSegment(coordToPoint(p), coordToPoint(a), coordToPoint(b))) * EARTH_RADIUS_METERS
+}
+
+// FarthestFromSegment returns index of the point between i and j
+// (exclusive) most distant from the segment path[i]-path[j] and the distance,
+// index is -1 if there is no point in between
+func FarthestFromSegment(path [][]float64, i, j int) (int, float64) {
+	farthest := -1
+	maxDistance := 0.0
+	for k := i + 1; k < j; k++ {
+		d := DistanceFromSegment(path[k], path[i], path[j])
+		if farthest < 0 || d > maxDistance {
+			farthest = k
+			maxDistance = d
+		}
+	}
+	return farthest, maxDistance
+}
+
+// DouglasPeucker simplifies path given by [lng, lat] coordinates, points
+// closer than tolerance (meters) to the simplified line are dropped, returns
+// mask of kept points (end points are always kept)
+func DouglasPeucker(path [][]float64, tolerance float64) []bool {
+
+	keep := make([]bool, len(path))
+	if len(path) == 0 {
+		return keep
+	}
+	keep[0] = true
+	keep[len(path)-1] = true
+
+	// explicit stack of ranges instead of recursion (paths could be long)
+	stack := [][2]int{{0, len(path) - 1}}
+	for len(stack) > 0 {
+		r := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		farthest, distance := FarthestFromSegment(path, r[0], r[1])
+		if farthest < 0 || distance <= tolerance {
+			continue
+		}
+
+		keep[farthest] = true
+		stack = append(stack, [2]int{r[0], farthest}, [2]int{farthest, r[1]})
+	}
+
+	return keep
+}